@@ -124,3 +124,53 @@ func Test_applyAllowLists(t *testing.T) {
 		})
 	}
 }
+
+func TestPropsAllowList(t *testing.T) {
+	allowLists, err := parseAllowListFile("props.whitelist")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The properties that soong_ui --verify-reproducible changes between its
+	// builds with BUILD_DATETIME, BUILD_USERNAME and BUILD_HOSTNAME.
+	propsA := bytesToZipArtifactFile("system/build.prop", []byte(`
+ro.build.date=Thu Jan  1 00:00:00 UTC 2021
+ro.build.date.utc=1609459200
+ro.build.user=reproducible-a
+ro.build.host=reproducible-a.example.com
+ro.system.build.date=Thu Jan  1 00:00:00 UTC 2021
+ro.build.version.sdk=30
+`))
+	propsB := bytesToZipArtifactFile("system/build.prop", []byte(`
+ro.build.date=Fri Jan  2 01:00:00 UTC 2021
+ro.build.date.utc=1609549200
+ro.build.user=reproducible-b
+ro.build.host=reproducible-b.example.com
+ro.system.build.date=Fri Jan  2 01:00:00 UTC 2021
+ro.build.version.sdk=30
+`))
+	propsC := bytesToZipArtifactFile("system/build.prop", []byte(`
+ro.build.date=Thu Jan  1 00:00:00 UTC 2021
+ro.build.date.utc=1609459200
+ro.build.user=reproducible-a
+ro.build.host=reproducible-a.example.com
+ro.system.build.date=Thu Jan  1 00:00:00 UTC 2021
+ro.build.version.sdk=31
+`))
+
+	got, err := applyAllowLists(zipDiff{modified: [][2]*ZipArtifactFile{{propsA, propsB}}}, allowLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.modified) != 0 {
+		t.Errorf("expected only perturbed properties to be ignored, got %v", got.modified)
+	}
+
+	got, err = applyAllowLists(zipDiff{modified: [][2]*ZipArtifactFile{{propsA, propsC}}}, allowLists)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.modified) != 1 {
+		t.Errorf("expected other properties to be compared, got %v", got.modified)
+	}
+}
@@ -16,7 +16,9 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"sort"
 )
 
 // compareTargetFiles takes two ZipArtifacts and compares the files they contain by examining
@@ -42,6 +44,11 @@ func compareTargetFiles(priZip, refZip ZipArtifact, artifact string, allowLists
 		return zipDiff{}, err
 	}
 
+	// diffTargetFilesLists requires the file lists to be sorted, which zip
+	// files created from directories may not be.
+	sortZipArtifactFiles(priZipFiles)
+	sortZipArtifactFiles(refZipFiles)
+
 	// Compare the file lists from both builds
 	diff := diffTargetFilesLists(refZipFiles, priZipFiles)
 
@@ -97,6 +104,40 @@ func (d *zipDiff) String() string {
 	return buf.String()
 }
 
+// jsonZipDiff is the list of files that differ between two zip files, as
+// printed with -json.
+type jsonZipDiff struct {
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+	Added    []string `json:"added"`
+}
+
+// JSON returns the names of the files that differ between two zip files as
+// JSON.
+func (d *zipDiff) JSON() ([]byte, error) {
+	ret := jsonZipDiff{
+		Modified: []string{},
+		Removed:  []string{},
+		Added:    []string{},
+	}
+	for _, f := range d.modified {
+		ret.Modified = append(ret.Modified, f[0].Name)
+	}
+	for _, f := range d.onlyInA {
+		ret.Removed = append(ret.Removed, f.Name)
+	}
+	for _, f := range d.onlyInB {
+		ret.Added = append(ret.Added, f.Name)
+	}
+	return json.MarshalIndent(ret, "", "  ")
+}
+
+func sortZipArtifactFiles(files []*ZipArtifactFile) {
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+}
+
 func diffTargetFilesLists(a, b []*ZipArtifactFile) zipDiff {
 	i := 0
 	j := 0
@@ -129,3 +129,46 @@ func TestDiffTargetFilesLists(t *testing.T) {
 		})
 	}
 }
+
+func TestZipDiffJSON(t *testing.T) {
+	zipArtifactFile := func(name string) *ZipArtifactFile {
+		return &ZipArtifactFile{File: &zip.File{FileHeader: zip.FileHeader{Name: name}}}
+	}
+	diff := zipDiff{
+		modified: [][2]*ZipArtifactFile{{zipArtifactFile("system/build.prop"), zipArtifactFile("system/build.prop")}},
+		onlyInA:  []*ZipArtifactFile{zipArtifactFile("system/removed")},
+	}
+
+	data, err := diff.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+  "modified": [
+    "system/build.prop"
+  ],
+  "removed": [
+    "system/removed"
+  ],
+  "added": []
+}`
+	if string(data) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, data)
+	}
+}
+
+func TestSortZipArtifactFiles(t *testing.T) {
+	var files []*ZipArtifactFile
+	for _, name := range []string{"a/b", "a.txt", "A"} {
+		files = append(files, &ZipArtifactFile{File: &zip.File{FileHeader: zip.FileHeader{Name: name}}})
+	}
+	sortZipArtifactFiles(files)
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	if want := []string{"A", "a.txt", "a/b"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("want %q, got %q", want, names)
+	}
+}
@@ -26,6 +26,9 @@ var (
 	allowListFiles = newMultiString("allowlist_file", "files containing allowlist definitions")
 
 	filters = newMultiString("filter", "filter patterns to apply to files in target-files.zip before comparing")
+
+	jsonOutput = flag.Bool("json", false, "print the names of the differing files as JSON")
+	artifact   = flag.String("artifact", targetFilesPattern, "the kind of zip files to compare, anything other than the default compares every file")
 )
 
 func newMultiString(name, usage string) *multiString {
@@ -67,13 +70,22 @@ func main() {
 	}
 	defer refZip.Close()
 
-	diff, err := compareTargetFiles(priZip, refZip, targetFilesPattern, allowLists, *filters)
+	diff, err := compareTargetFiles(priZip, refZip, *artifact, allowLists, *filters)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error comparing zip files: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Print(diff.String())
+	if *jsonOutput {
+		data, err := diff.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(diff.String())
+	}
 
 	if len(diff.modified) > 0 || len(diff.onlyInA) > 0 || len(diff.onlyInB) > 0 {
 		fmt.Fprintln(os.Stderr, "differences found")
@@ -23,6 +23,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -50,6 +51,8 @@ var onlySoong = flag.Bool("only-soong", false, "Only run product config and Soon
 
 var buildVariant = flag.String("variant", "eng", "build variant to use")
 
+var shareOut = flag.Bool("share-out", false, "build the products of each job in one output directory, reusing host tools and Soong intermediates where the configuration is identical (not with --keep)")
+
 var shardCount = flag.Int("shard-count", 1, "split the products into multiple shards (to spread the build onto multiple machines, etc)")
 var shard = flag.Int("shard", 1, "1-indexed shard to execute")
 
@@ -176,6 +179,12 @@ func main() {
 
 	flag.Parse()
 
+	// The artifacts of a product are archived from its output directory,
+	// which would include those of every product built before it.
+	if *shareOut && *keepArtifacts {
+		log.Fatalln("--share-out can't be used with --keep, the archives would mix the artifacts of several products")
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -186,8 +195,8 @@ func main() {
 	defer stat.Finish()
 	stat.AddOutput(output)
 
-	var failures failureCount
-	stat.AddOutput(&failures)
+	failures := newFailureCount()
+	stat.AddOutput(failures)
 
 	build.SetupSignals(log, cancel, func() {
 		trace.Close()
@@ -337,8 +346,18 @@ func main() {
 		}
 	}()
 
+	var sharedOutDirs []string
 	var wg sync.WaitGroup
 	for i := 0; i < jobs; i++ {
+		// With --share-out, each job builds its products one after the other
+		// in the same output directory, like a developer lunching different
+		// products in one tree. Only what depends on the configuration of
+		// the product is rebuilt.
+		sharedOutDir := ""
+		if *shareOut {
+			sharedOutDir = filepath.Join(config.OutDir(), fmt.Sprintf("shared-%d", i))
+			sharedOutDirs = append(sharedOutDirs, sharedOutDir)
+		}
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
@@ -348,13 +367,25 @@ func main() {
 					if product == "" {
 						return
 					}
-					buildProduct(mpCtx, product)
+					buildProduct(mpCtx, product, sharedOutDir)
 				}
 			}
 		}()
 	}
 	wg.Wait()
 
+	if !*incremental {
+		for _, dir := range sharedOutDirs {
+			os.RemoveAll(dir)
+		}
+	}
+
+	summary := failures.summary()
+	fmt.Fprint(output, summary)
+	if err := ioutil.WriteFile(filepath.Join(logsDir, "summary.txt"), []byte(summary), 0666); err != nil {
+		log.Println("Failed to write summary:", err)
+	}
+
 	if *alternateResultDir {
 		args := zip.ZipArgs{
 			FileArgs: []zip.FileArg{
@@ -371,19 +402,24 @@ func main() {
 
 	s.Finish()
 
-	if failures == 1 {
+	if failures.count == 1 {
 		log.Fatal("1 failure")
-	} else if failures > 1 {
-		log.Fatalf("%d failures", failures)
+	} else if failures.count > 1 {
+		log.Fatalf("%d failures", failures.count)
 	} else {
 		fmt.Fprintln(output, "Success")
 	}
 }
 
-func buildProduct(mpctx *mpContext, product string) {
+// buildProduct runs product config, Soong and Kati for a product, in
+// sharedOutDir if it isn't empty or in an output directory of its own.
+func buildProduct(mpctx *mpContext, product string, sharedOutDir string) {
 	var stdLog string
 
 	outDir := filepath.Join(mpctx.Config.OutDir(), product)
+	if sharedOutDir != "" {
+		outDir = sharedOutDir
+	}
 	logsDir := filepath.Join(mpctx.LogsDir, product)
 
 	if err := os.MkdirAll(outDir, 0777); err != nil {
@@ -455,7 +491,7 @@ func buildProduct(mpctx *mpContext, product string) {
 				log.Fatalf("Error zipping artifacts: %v", err)
 			}
 		}
-		if !*incremental {
+		if !*incremental && sharedOutDir == "" {
 			os.RemoveAll(outDir)
 		}
 	}()
@@ -488,19 +524,53 @@ func buildProduct(mpctx *mpContext, product string) {
 	})
 }
 
-type failureCount int
+// productResult is the outcome of building one product.
+type productResult struct {
+	product  string
+	duration time.Duration
+	err      error
+}
+
+// failureCount is a StatusOutput that counts the failures of the build, and
+// records how long each product took to build and whether it failed.
+type failureCount struct {
+	count int
+
+	started map[*status.Action]time.Time
+	results []productResult
+
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
+}
+
+func newFailureCount() *failureCount {
+	return &failureCount{
+		started: make(map[*status.Action]time.Time),
+		now:     time.Now,
+	}
+}
 
-func (f *failureCount) StartAction(action *status.Action, counts status.Counts) {}
+func (f *failureCount) StartAction(action *status.Action, counts status.Counts) {
+	f.started[action] = f.now()
+}
 
 func (f *failureCount) FinishAction(result status.ActionResult, counts status.Counts) {
 	if result.Error != nil {
-		*f += 1
+		f.count += 1
+	}
+	if start, ok := f.started[result.Action]; ok {
+		delete(f.started, result.Action)
+		f.results = append(f.results, productResult{
+			product:  result.Action.Description,
+			duration: f.now().Sub(start),
+			err:      result.Error,
+		})
 	}
 }
 
 func (f *failureCount) Message(level status.MsgLevel, message string) {
 	if level >= status.ErrorLvl {
-		*f += 1
+		f.count += 1
 	}
 }
 
@@ -511,6 +581,38 @@ func (f *failureCount) Write(p []byte) (int, error) {
 	return len(p), nil
 }
 
+// summary returns a table of the time each product took to build and
+// whether it failed, sorted by product.
+func (f *failureCount) summary() string {
+	results := append([]productResult(nil), f.results...)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].product < results[j].product
+	})
+
+	width := len("PRODUCT")
+	for _, r := range results {
+		if len(r.product) > width {
+			width = len(r.product)
+		}
+	}
+
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%-*s  %10s  %s\n", width, "PRODUCT", "TIME", "RESULT")
+	var total time.Duration
+	failed := 0
+	for _, r := range results {
+		result := "ok"
+		if r.err != nil {
+			result = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(&buf, "%-*s  %10s  %s\n", width, r.product, r.duration.Round(time.Second), result)
+		total += r.duration
+	}
+	fmt.Fprintf(&buf, "%d products, %d failed, %s total\n", len(results), failed, total.Round(time.Second))
+	return buf.String()
+}
+
 func splitList(list []string, shardCount int) (ret [][]string) {
 	each := len(list) / shardCount
 	extra := len(list) % shardCount
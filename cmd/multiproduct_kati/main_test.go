@@ -15,9 +15,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
+
+	"android/soong/ui/status"
 )
 
 func TestSplitList(t *testing.T) {
@@ -91,3 +96,33 @@ func TestSplitList(t *testing.T) {
 		})
 	}
 }
+
+func TestFailureCountSummary(t *testing.T) {
+	f := newFailureCount()
+	now := time.Unix(0, 0)
+	f.now = func() time.Time { return now }
+
+	arm := &status.Action{Description: "aosp_arm"}
+	x86 := &status.Action{Description: "aosp_x86_64"}
+	f.StartAction(x86, status.Counts{})
+	f.StartAction(arm, status.Counts{})
+	now = now.Add(90 * time.Second)
+	f.FinishAction(status.ActionResult{Action: arm, Error: errors.New("failed")}, status.Counts{})
+	now = now.Add(30*time.Second + 400*time.Millisecond)
+	f.FinishAction(status.ActionResult{Action: x86}, status.Counts{})
+
+	if f.count != 1 {
+		t.Errorf("want 1 failure, got %d", f.count)
+	}
+
+	want := strings.Join([]string{
+		"PRODUCT            TIME  RESULT",
+		"aosp_arm          1m30s  FAILED",
+		"aosp_x86_64        2m0s  ok",
+		"2 products, 1 failed, 3m30s total",
+		"",
+	}, "\n")
+	if g := f.summary(); g != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, g)
+	}
+}
@@ -56,6 +56,7 @@ func main() {
 	exitCode, err := Main(os.Stdout, os.Stderr, interposer, os.Args, mainOpts{
 		sendLog:       paths.SendLog,
 		config:        paths.GetConfig,
+		allowlisted:   paths.Allowlisted,
 		lookupParents: lookupParents,
 	})
 	if err != nil {
@@ -69,11 +70,13 @@ var usage = fmt.Errorf(`To use the PATH interposer:
  * Set up a directory of symlinks to the PATH interposer, and use that in PATH
 
 If a tool isn't in the allowed list, a log will be posted to the unix domain
-socket at <interposer>_log.`)
+socket at <interposer>_log, and tools that are disallowed fail. If
+<interposer>_report_all exists, every use of a tool is posted.`)
 
 type mainOpts struct {
 	sendLog       func(logSocket string, entry *paths.LogEntry, done chan interface{})
 	config        func(name string) paths.PathConfig
+	allowlisted   func(tool, phase string, module paths.LogModule) bool
 	lookupParents func() []paths.LogProcess
 }
 
@@ -107,22 +110,35 @@ func Main(stdout, stderr io.Writer, interposer string, args []string, opts mainO
 		return 1, fmt.Errorf("Failed to set PATH env: %v", err)
 	}
 
-	if config := opts.config(base); config.Log || config.Error {
+	// Only the uses of logged or disallowed tools are posted, unless every use
+	// is reported, as looking up the parents and waiting for the log slows
+	// down every tool.
+	config := opts.config(base)
+	_, err = os.Stat(interposer + "_report_all")
+	reportAll := err == nil
+	if config.Log || config.Error || reportAll {
 		var procs []paths.LogProcess
 		if opts.lookupParents != nil {
 			procs = opts.lookupParents()
 		}
 
+		phase := paths.PhaseOf(procs)
+		module := paths.ModuleOf(args, procs)
+		allowlisted := config.Error && opts.allowlisted != nil && opts.allowlisted(base, phase, module)
+
 		if opts.sendLog != nil {
 			waitForLog := make(chan interface{})
 			opts.sendLog(interposer+"_log", &paths.LogEntry{
-				Basename: base,
-				Args:     args,
-				Parents:  procs,
+				Basename:    base,
+				Args:        args,
+				Parents:     procs,
+				Phase:       phase,
+				Module:      module,
+				Allowlisted: allowlisted,
 			}, waitForLog)
 			defer func() { <-waitForLog }()
 		}
-		if config.Error {
+		if config.Error && !allowlisted {
 			return 1, fmt.Errorf("%q is not allowed to be used. See https://android.googlesource.com/platform/build/+/master/Changes.md#PATH_Tools for more information.", base)
 		}
 	}
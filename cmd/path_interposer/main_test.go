@@ -69,7 +69,7 @@ func TestInterposer(t *testing.T) {
 				Log:   false,
 				Error: false,
 			}
-		} else if name == "path_interposer_test_not_allowed" {
+		} else if name == "path_interposer_test_not_allowed" || name == "sh" {
 			return paths.PathConfig{
 				Log:   false,
 				Error: true,
@@ -85,9 +85,11 @@ func TestInterposer(t *testing.T) {
 		name string
 		args []string
 
-		exitCode int
-		err      error
-		logEntry string
+		exitCode    int
+		err         error
+		reportAll   bool
+		logEntry    string
+		allowlisted bool
 	}{
 		{
 			name: "direct call",
@@ -111,6 +113,13 @@ func TestInterposer(t *testing.T) {
 			name: "relative true",
 			args: []string{"true"},
 		},
+		{
+			name: "reported true",
+			args: []string{"true"},
+
+			reportAll: true,
+			logEntry:  "true",
+		},
 		{
 			name: "exit code",
 			args: []string{"bash", "-c", "exit 42"},
@@ -141,10 +150,25 @@ func TestInterposer(t *testing.T) {
 			err:      fmt.Errorf(`"path_interposer_test_not_allowed" is not allowed to be used. See https://android.googlesource.com/platform/build/+/master/Changes.md#PATH_Tools for more information.`),
 			logEntry: "path_interposer_test_not_allowed",
 		},
+		{
+			name: "allowlisted",
+			args: []string{"sh", "-c", "exit 3"},
+
+			exitCode:    3,
+			logEntry:    "sh",
+			allowlisted: true,
+		},
 	}
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
+			if testCase.reportAll {
+				if err := ioutil.WriteFile(interposer+"_report_all", nil, 0666); err != nil {
+					t.Fatal(err)
+				}
+				defer os.Remove(interposer + "_report_all")
+			}
+
 			logged := false
 			logFunc := func(logSocket string, entry *paths.LogEntry, done chan interface{}) {
 				defer close(done)
@@ -153,11 +177,17 @@ func TestInterposer(t *testing.T) {
 				if entry.Basename != testCase.logEntry {
 					t.Errorf("unexpected log entry:\nwant: %q\n got: %q", testCase.logEntry, entry.Basename)
 				}
+				if entry.Allowlisted != testCase.allowlisted {
+					t.Errorf("expected allowlisted %t, got %t", testCase.allowlisted, entry.Allowlisted)
+				}
 			}
 
 			exitCode, err := Main(ioutil.Discard, ioutil.Discard, interposer, testCase.args, mainOpts{
 				sendLog: logFunc,
 				config:  logConfig,
+				allowlisted: func(tool, phase string, module paths.LogModule) bool {
+					return tool == "sh"
+				},
 			})
 
 			errstr := func(err error) string {
@@ -97,6 +97,56 @@ var commands []command = []command{
 		config:      buildActionConfig,
 		stdio:       stdio,
 		run:         runMake,
+	}, {
+		flag:         "--gc",
+		description:  "remove intermediates that are no longer reachable from the current build",
+		simpleOutput: true,
+		logsPrefix:   "gc-",
+		config:       dumpVarConfig,
+		stdio:        stdio,
+		run:          garbageCollect,
+	}, {
+		flag:         "--disk-usage",
+		description:  "report the disk usage of the out directory by module and partition",
+		simpleOutput: true,
+		logsPrefix:   "disk_usage-",
+		config:       dumpVarConfig,
+		stdio:        customStdio,
+		run:          diskUsage,
+	}, {
+		flag:         "--dump-effective-config",
+		description:  "print the build settings and the build profile or environment each one came from",
+		simpleOutput: true,
+		logsPrefix:   "dumpconfig-",
+		config: func(ctx build.Context, args ...string) build.Config {
+			return build.NewConfig(ctx, args...)
+		},
+		stdio: customStdio,
+		run:   dumpEffectiveConfig,
+	}, {
+		flag:         "--doctor",
+		description:  "check the build environment for common problems and suggest fixes",
+		simpleOutput: true,
+		logsPrefix:   "doctor-",
+		config:       dumpVarConfig,
+		stdio:        stdio,
+		run:          doctor,
+	}, {
+		flag:         "--verify-reproducible",
+		description:  "build twice in different output directories and report the installed and intermediate files that differ",
+		simpleOutput: true,
+		logsPrefix:   "reproducible-",
+		config:       dumpVarConfig,
+		stdio:        stdio,
+		run:          verifyReproducible,
+	}, {
+		flag:         "--finder-daemon",
+		description:  "keep the list of source files up to date in the background for the next builds",
+		simpleOutput: true,
+		logsPrefix:   "finder_daemon-",
+		config:       dumpVarConfig,
+		stdio:        stdio,
+		run:          finderDaemon,
 	},
 }
 
@@ -165,6 +215,23 @@ func main() {
 	output := terminal.NewStatusOutput(c.stdio().Stdout(), os.Getenv("NINJA_STATUS"), c.simpleOutput,
 		build.OsEnvironment().IsEnvTrue("ANDROID_QUIET_BUILD"))
 
+	// Write the status as one JSON object per line for tools that wrap the
+	// build if requested. SOONG_UI_JSON_STATUS is "stdout", "stderr" or a file
+	// descriptor number; writing to stdout replaces the terminal output.
+	var jsonOutput status.StatusOutput
+	if dest, ok := os.LookupEnv("SOONG_UI_JSON_STATUS"); ok && dest != "" && !c.simpleOutput {
+		w, err := terminal.JSONStatusWriter(c.stdio(), dest)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error setting up JSON status: %s.\n", err)
+			os.Exit(1)
+		}
+		jsonOutput = terminal.NewJSONStatusOutput(w, os.Getenv("NINJA_STATUS"),
+			build.OsEnvironment().IsEnvTrue("ANDROID_QUIET_BUILD"))
+		if dest == "stdout" {
+			output, jsonOutput = jsonOutput, nil
+		}
+	}
+
 	// Attach a new logger instance to the terminal output.
 	log := logger.New(output)
 	defer log.Cleanup()
@@ -187,8 +254,15 @@ func main() {
 	defer stat.Finish()
 	// Hook up the terminal output and tracer to Status.
 	stat.AddOutput(output)
+	stat.AddOutput(jsonOutput)
 	stat.AddOutput(trace.StatusTracer())
 
+	// Serve the live build status over HTTP if requested. Skipped for the
+	// dumpvars commands, which may run alongside a build.
+	if addr, ok := build.OsEnvironment().Get("SOONG_UI_STATUS_SERVER"); ok && addr != "" && !c.simpleOutput {
+		stat.AddOutput(status.NewStatusServer(log, addr))
+	}
+
 	// Set up a cleanup procedure in case the normal termination process doesn't work.
 	build.SetupSignals(log, cancel, func() {
 		trace.Close()
@@ -223,6 +297,7 @@ func main() {
 
 	// Common list of metric file definition.
 	buildErrorFile := filepath.Join(logsDir, c.logsPrefix+"build_error")
+	buildProgressFile := filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")
 	rbeMetricsFile := filepath.Join(logsDir, c.logsPrefix+"rbe_metrics.pb")
 	soongMetricsFile := filepath.Join(logsDir, c.logsPrefix+"soong_metrics")
 
@@ -230,12 +305,41 @@ func main() {
 
 	os.MkdirAll(logsDir, 0777)
 	log.SetOutput(filepath.Join(logsDir, c.logsPrefix+"soong.log"))
+	// The Perfetto trace needs to be set up before the JSON trace to include
+	// the events from before this point.
+	if build.OsEnvironment().IsEnvTrue("SOONG_UI_PERFETTO_TRACE") {
+		trace.SetPerfettoOutput(filepath.Join(logsDir, c.logsPrefix+"build.perfetto-trace"))
+	}
 	trace.SetOutput(filepath.Join(logsDir, c.logsPrefix+"build.trace"))
+	if build.OsEnvironment().IsEnvTrue("SOONG_UI_COLLAPSE_WARNINGS") {
+		if err := terminal.CollapseWarnings(output, filepath.Join(logsDir, c.logsPrefix+"warnings.txt")); err != nil {
+			log.Println("Failed to collapse warnings:", err)
+		}
+	}
 	stat.AddOutput(status.NewVerboseLog(log, filepath.Join(logsDir, c.logsPrefix+"verbose.log")))
 	stat.AddOutput(status.NewErrorLog(log, filepath.Join(logsDir, c.logsPrefix+"error.log")))
 	stat.AddOutput(status.NewProtoErrorLog(log, buildErrorFile))
-	stat.AddOutput(status.NewCriticalPath(log))
-	stat.AddOutput(status.NewBuildProgressLog(log, filepath.Join(logsDir, c.logsPrefix+"build_progress.pb")))
+	stat.AddOutput(status.NewCriticalPath(log, filepath.Join(config.ProductOut(), "module-info.json"), buildProgressFile))
+	stat.AddOutput(status.NewBuildProgressLog(log, buildProgressFile))
+	stat.AddOutput(status.NewResourceUsageLog(log,
+		filepath.Join(logsDir, c.logsPrefix+"resource_usage.txt"),
+		filepath.Join(logsDir, c.logsPrefix+"resource_usage.json")))
+	// The build report reads the files written by the outputs above and the
+	// soong metrics, so it needs to be added last. The metrics are dumped by a
+	// deferred call that runs before stat.Finish.
+	if !c.simpleOutput {
+		stat.AddOutput(status.NewBuildReport(log, filepath.Join(logsDir, c.logsPrefix+"build_report.html"),
+			soongMetricsFile, buildProgressFile, buildErrorFile))
+	}
+
+	// Compare action durations against previous builds in the same out
+	// directory. The report is deferred so that it runs before stat.Finish
+	// while the terminal output is still active.
+	if !c.simpleOutput {
+		history := status.NewActionHistory(log, filepath.Join(config.OutDir(), ".action_history.json"))
+		stat.AddOutput(history)
+		defer history.Report(stat.StartTool(), filepath.Join(logsDir, c.logsPrefix+"build_time_regressions.txt"))
+	}
 
 	buildCtx.Verbosef("Detected %.3v GB total RAM", float32(config.TotalRAM())/(1024*1024*1024))
 	buildCtx.Verbosef("Parallelism (local/remote/highmem): %v/%v/%v",
@@ -253,9 +357,11 @@ func main() {
 			soongMetricsFile,         // high level metrics related to this build system.
 			config.BazelMetricsDir(), // directory that contains a set of bazel metrics.
 		}
+		// metrics of the remote execution backends other than RBE.
+		files = append(files, build.RemoteMetricsFiles(config, logsDir, c.logsPrefix)...)
 		defer build.UploadMetrics(buildCtx, config, c.simpleOutput, buildStarted, files...)
 		defer met.Dump(soongMetricsFile)
-		defer build.DumpRBEMetrics(buildCtx, config, rbeMetricsFile)
+		defer build.DumpRBEMetrics(buildCtx, config, logsDir, c.logsPrefix)
 	}
 
 	// Read the time at the starting point.
@@ -424,6 +530,101 @@ func stdio() terminal.StdioInterface {
 	return terminal.StdioImpl{}
 }
 
+func garbageCollect(ctx build.Context, config build.Config, args []string, _ string) {
+	flags := flag.NewFlagSet("gc", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(ctx.Writer, "usage: %s --gc [--dry-run]\n\n", os.Args[0])
+		fmt.Fprintln(ctx.Writer, "In gc mode, remove the directories under the intermediates trees that")
+		fmt.Fprintln(ctx.Writer, "don't contain any output of the current product's last build. Run a")
+		fmt.Fprintln(ctx.Writer, "build first, as intermediates of other products are removed too.")
+		fmt.Fprintln(ctx.Writer, "")
+		flags.PrintDefaults()
+	}
+	dryRun := flags.Bool("dry-run", false, "List the directories that would be removed without removing them")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	build.GarbageCollect(ctx, config, *dryRun)
+}
+
+func diskUsage(ctx build.Context, config build.Config, args []string, _ string) {
+	flags := flag.NewFlagSet("disk-usage", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(ctx.Writer, "usage: %s --disk-usage [--previous=<report.json>] [--top=N]\n\n", os.Args[0])
+		fmt.Fprintln(ctx.Writer, "In disk usage mode, attribute the files in the out directory to the modules")
+		fmt.Fprintln(ctx.Writer, "that own them, their module classes and install partitions, and print the")
+		fmt.Fprintln(ctx.Writer, "report to stdout. The report is also written to the logs directory as text")
+		fmt.Fprintln(ctx.Writer, "and JSON, and compared with the previous JSON report.")
+		fmt.Fprintln(ctx.Writer, "")
+		flags.PrintDefaults()
+	}
+	previous := flags.String("previous", "", "JSON report to compare with, defaults to the last report in the logs directory")
+	top := flags.Int("top", 50, "Number of modules and directories to list, or 0 to list all of them")
+	flags.Parse(args)
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	fmt.Print(build.DiskUsageReport(ctx, config, *previous, *top))
+}
+
+func dumpEffectiveConfig(ctx build.Context, config build.Config, _ []string, _ string) {
+	fmt.Print(build.DumpEffectiveConfig(config))
+}
+
+func doctor(ctx build.Context, config build.Config, args []string, _ string) {
+	if len(args) != 0 {
+		fmt.Fprintf(ctx.Writer, "usage: %s --doctor\n", os.Args[0])
+		os.Exit(1)
+	}
+	build.Doctor(ctx, config)
+}
+
+// allowlistFiles collects the values of a repeated --allowlist-file flag.
+type allowlistFiles []string
+
+func (a *allowlistFiles) String() string {
+	return strings.Join(*a, ",")
+}
+
+func (a *allowlistFiles) Set(s string) error {
+	*a = append(*a, s)
+	return nil
+}
+
+func verifyReproducible(ctx build.Context, config build.Config, args []string, _ string) {
+	flags := flag.NewFlagSet("verify-reproducible", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(ctx.Writer, "usage: %s --verify-reproducible [--allowlist-file=<file>] [<target> ...]\n\n", os.Args[0])
+		fmt.Fprintln(ctx.Writer, "Build the targets, droid by default, twice in different output")
+		fmt.Fprintln(ctx.Writer, "directories with a different build date, user and host, and report")
+		fmt.Fprintln(ctx.Writer, "the installed and intermediate files that differ and the first actions")
+		fmt.Fprintln(ctx.Writer, "that produced different outputs for each of them.")
+		fmt.Fprintln(ctx.Writer, "")
+		flags.PrintDefaults()
+	}
+	var allowlists allowlistFiles
+	flags.Var(&allowlists, "allowlist-file", "a diff_target_files allowlist of known differences, in addition to the default ones")
+	flags.SetOutput(ctx.Writer)
+	flags.Parse(args)
+
+	build.VerifyReproducible(ctx, config, flags.Args(), allowlists)
+}
+
+func finderDaemon(ctx build.Context, config build.Config, args []string, _ string) {
+	if len(args) != 0 {
+		fmt.Fprintf(ctx.Writer, "usage: %s --finder-daemon\n", os.Args[0])
+		os.Exit(1)
+	}
+	build.RunFinderDaemon(ctx, config)
+}
+
 // dumpvar and dumpvars use stdout to output variable values, so use stderr instead of stdout when
 // reporting events to keep stdout clean from noise.
 func customStdio() terminal.StdioInterface {
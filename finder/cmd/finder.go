@@ -22,9 +22,11 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"runtime/pprof"
 	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"android/soong/finder"
@@ -36,12 +38,16 @@ var (
 	excludeDirs     string
 	filenamesToFind string
 	pruneFiles      string
+	includePatterns patternList
+	excludePatterns patternList
 
 	// other configuration
 	cpuprofile    string
 	verbose       bool
 	dbPath        string
 	numIterations int
+	socketPath    string
+	serve         bool
 )
 
 func init() {
@@ -57,13 +63,29 @@ func init() {
 	flag.StringVar(&pruneFiles, "prune-files", "",
 		"filenames that if discovered will exclude their entire directory "+
 			"(including sibling files and directories)")
+	flag.Var(&includePatterns.globs, "glob",
+		"only list the files whose paths relative to a <searchDirectory> match this glob, "+
+			"where ** matches any number of directories (may be repeated)")
+	flag.Var(&includePatterns.regexps, "regex",
+		"only list the files whose paths relative to a <searchDirectory> match this "+
+			"regular expression (may be repeated)")
+	flag.Var(&excludePatterns.globs, "exclude-glob",
+		"don't list the files or search the directories whose paths relative to a "+
+			"<searchDirectory> match this glob (may be repeated)")
+	flag.Var(&excludePatterns.regexps, "exclude-regex",
+		"don't list the files or search the directories whose paths relative to a "+
+			"<searchDirectory> match this regular expression (may be repeated)")
 	flag.IntVar(&numIterations, "count", 1,
 		"number of times to run. This is intended for use with --cpuprofile"+
 			" , to increase profile accuracy")
+	flag.StringVar(&socketPath, "daemon", "",
+		"filepath of the unix socket of a finder daemon to answer the query (optional)")
+	flag.BoolVar(&serve, "serve", false,
+		"run a finder daemon on the socket given by --daemon until interrupted")
 }
 
 var usage = func() {
-	fmt.Printf("usage: finder -name <fileName> --db <dbPath> <searchDirectory> [<searchDirectory>...]\n")
+	fmt.Printf("usage: finder -name <fileName> --db <dbPath> [--glob <pattern>] [--regex <pattern>] [--daemon <socketPath> [--serve]] <searchDirectory> [<searchDirectory>...]\n")
 	flag.PrintDefaults()
 }
 
@@ -79,6 +101,47 @@ func stringToList(input string) []string {
 	return strings.Split(input, ",")
 }
 
+// a stringList is a flag that may be given more than once
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// a patternList holds the globs and regular expressions given by flags
+type patternList struct {
+	globs   stringList
+	regexps stringList
+}
+
+func (l patternList) empty() bool {
+	return len(l.globs) == 0 && len(l.regexps) == 0
+}
+
+func (l patternList) patterns() ([]finder.Pattern, error) {
+	var patterns []finder.Pattern
+	for _, glob := range l.globs {
+		pattern, err := finder.NewGlob(glob)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	for _, expr := range l.regexps {
+		pattern, err := finder.NewRegexp(expr)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
 func run() error {
 	startTime := time.Now()
 	flag.Parse()
@@ -127,6 +190,13 @@ func run() error {
 		usage()
 		return errors.New("Param 'db' must be nonempty")
 	}
+	if serve {
+		if socketPath == "" {
+			usage()
+			return errors.New("Param 'daemon' must be nonempty with 'serve'")
+		}
+		return runDaemon(params, logger)
+	}
 
 	matches := []string{}
 	for i := 0; i < numIterations; i++ {
@@ -147,10 +217,60 @@ func run() error {
 }
 
 func runFind(params finder.CacheParams, logger *log.Logger) (paths []string, err error) {
-	service, err := finder.New(params, fs.OsFs, logger, dbPath)
+	var service *finder.Finder
+	if socketPath != "" {
+		service, err = finder.NewWithDaemon(params, fs.OsFs, logger, dbPath, socketPath)
+	} else {
+		service, err = finder.New(params, fs.OsFs, logger, dbPath)
+	}
 	if err != nil {
 		return []string{}, err
 	}
 	defer service.Shutdown()
-	return service.FindAll(), nil
+	if includePatterns.empty() && excludePatterns.empty() {
+		return service.FindAll(), service.Err()
+	}
+
+	include, err := includePatterns.patterns()
+	if err != nil {
+		return []string{}, err
+	}
+	exclude, err := excludePatterns.patterns()
+	if err != nil {
+		return []string{}, err
+	}
+	patterns := finder.Patterns{Include: include, Exclude: exclude}
+	for _, rootPath := range params.RootDirs {
+		paths = append(paths, service.FindPatterns(rootPath, patterns)...)
+	}
+	return paths, service.Err()
+}
+
+func runDaemon(params finder.CacheParams, logger *log.Logger) error {
+	service, err := finder.New(params, fs.OsFs, logger, dbPath)
+	if err != nil {
+		return err
+	}
+	daemon, err := finder.NewDaemon(service, socketPath)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	errs := make(chan error, 1)
+	go func() {
+		errs <- daemon.Serve()
+	}()
+	logger.Printf("Finder daemon listening on %v\n", socketPath)
+
+	select {
+	case err = <-errs:
+	case <-signals:
+	}
+	// Close writes the cache, so wait for it before exiting
+	if closeErr := daemon.Close(); err == nil {
+		err = closeErr
+	}
+	return err
 }
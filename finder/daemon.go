@@ -0,0 +1,457 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"android/soong/finder/fs"
+)
+
+// This file provides a finder daemon, a long-lived process that keeps the
+// node tree of a Finder up to date by watching the filesystem for changes,
+// and answers the queries of other processes over a unix socket.
+// Revalidating the on-disk cache means calling Stat on every directory in it,
+// which takes tens of seconds for a full tree when the page cache is cold.
+// A Finder created with NewWithDaemon instead asks the daemon, and only falls
+// back to the on-disk cache if there is no daemon with the same CacheParams
+// or the daemon stops answering.
+
+// The protocol is a line of JSON for each daemonRequest, answered by a line of
+// JSON for each daemonResponse. The first request of each connection must be
+// a daemonHello.
+const (
+	daemonHello            = "Hello"
+	daemonFindAt           = "FindAt"
+	daemonFindNamedAt      = "FindNamedAt"
+	daemonFindFirstNamedAt = "FindFirstNamedAt"
+)
+
+// How long a client waits for the daemon to answer a query before falling
+// back to the on-disk cache.
+const daemonTimeout = time.Minute
+
+// How often the daemon applies the filesystem changes when it isn't queried,
+// so that the kernel's queue of changes doesn't overflow.
+const daemonUpdateInterval = time.Second
+
+type daemonRequest struct {
+	Op string
+
+	// The cache config of the client, for daemonHello. It must be the same
+	// as the daemon's.
+	Config string `json:",omitempty"`
+
+	Root string `json:",omitempty"`
+	Name string `json:",omitempty"`
+}
+
+type daemonResponse struct {
+	Files []string `json:",omitempty"`
+	Error string   `json:",omitempty"`
+}
+
+// a watcher keeps the node tree of a Finder up to date with the filesystem
+type watcher interface {
+	// update applies the changes to the filesystem since the last call
+	update() error
+	close() error
+}
+
+// A Daemon answers the queries of Finders created with NewWithDaemon with the
+// node tree of its own Finder.
+type Daemon struct {
+	finder   *Finder
+	watcher  watcher
+	listener net.Listener
+
+	// held while applying filesystem changes
+	updateLock sync.Mutex
+	done       chan bool
+
+	// the open connections, which are closed by Close
+	connsLock sync.Mutex
+	conns     map[net.Conn]bool
+}
+
+// NewDaemon creates a Daemon that answers queries with the node tree of f
+// on the unix socket at socketPath, once Serve is called. f must not be used
+// by anything else afterwards.
+func NewDaemon(f *Finder, socketPath string) (*Daemon, error) {
+	// every directory is watched, so the whole db needs to be loaded
+	f.lock()
+	f.loadSections("/", true)
+	f.unlock()
+
+	w, err := newWatcher(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// remove the socket of a daemon that didn't exit cleanly
+	if conn, err := net.Dial("unix", socketPath); err == nil {
+		conn.Close()
+		w.close()
+		return nil, fmt.Errorf("another finder daemon is listening on %v", socketPath)
+	}
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		w.close()
+		return nil, err
+	}
+	return newDaemon(f, w, listener), nil
+}
+
+func newDaemon(f *Finder, w watcher, listener net.Listener) *Daemon {
+	return &Daemon{
+		finder:   f,
+		watcher:  w,
+		listener: listener,
+		done:     make(chan bool),
+		conns:    make(map[net.Conn]bool),
+	}
+}
+
+// Serve answers queries until Close is called
+func (d *Daemon) Serve() error {
+	go func() {
+		ticker := time.NewTicker(daemonUpdateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.done:
+				return
+			case <-ticker.C:
+				d.update()
+			}
+		}
+	}()
+
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			select {
+			case <-d.done:
+				return nil
+			default:
+				return err
+			}
+		}
+		d.connsLock.Lock()
+		d.conns[conn] = true
+		d.connsLock.Unlock()
+		go d.serveConn(conn)
+	}
+}
+
+// Close stops answering queries, and saves the node tree to the on-disk cache
+// if it changed
+func (d *Daemon) Close() error {
+	close(d.done)
+	err := d.listener.Close()
+	d.connsLock.Lock()
+	for conn := range d.conns {
+		conn.Close()
+	}
+	d.connsLock.Unlock()
+
+	d.updateLock.Lock()
+	d.watcher.close()
+	d.updateLock.Unlock()
+
+	d.finder.goDumpDb()
+	d.finder.WaitForDbDump()
+	return err
+}
+
+func (d *Daemon) update() error {
+	d.updateLock.Lock()
+	defer d.updateLock.Unlock()
+	err := d.watcher.update()
+	if err != nil {
+		d.finder.verbosef("Failed to apply filesystem changes: %v\n", err)
+	}
+	return err
+}
+
+func (d *Daemon) serveConn(conn net.Conn) {
+	defer func() {
+		d.connsLock.Lock()
+		delete(d.conns, conn)
+		d.connsLock.Unlock()
+		conn.Close()
+	}()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+
+	greeted := false
+	for {
+		var request daemonRequest
+		if err := decoder.Decode(&request); err != nil {
+			return
+		}
+		var response daemonResponse
+		if !greeted && request.Op != daemonHello {
+			response.Error = "expected " + daemonHello
+		} else {
+			response = d.answer(request)
+		}
+		if err := encoder.Encode(response); err != nil || response.Error != "" {
+			return
+		}
+		greeted = true
+	}
+}
+
+func (d *Daemon) answer(request daemonRequest) daemonResponse {
+	if request.Op == daemonHello {
+		config, err := d.finder.cacheMetadata.Config.Dump()
+		if err != nil {
+			return daemonResponse{Error: err.Error()}
+		}
+		if request.Config != string(config) {
+			return daemonResponse{Error: fmt.Sprintf("the daemon's params are %s", config)}
+		}
+		return daemonResponse{}
+	}
+
+	// make sure that the changes made before the query are seen
+	if err := d.update(); err != nil {
+		return daemonResponse{Error: err.Error()}
+	}
+
+	var files []string
+	switch request.Op {
+	case daemonFindAt:
+		files = d.finder.FindAt(request.Root)
+	case daemonFindNamedAt:
+		files = d.finder.FindNamedAt(request.Root, request.Name)
+	case daemonFindFirstNamedAt:
+		files = d.finder.FindFirstNamedAt(request.Root, request.Name)
+	default:
+		return daemonResponse{Error: fmt.Sprintf("unknown query %q", request.Op)}
+	}
+	return daemonResponse{Files: files}
+}
+
+// a daemonClient is the connection of a Finder to a Daemon
+type daemonClient struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+}
+
+func dialDaemon(socketPath string, config []byte) (*daemonClient, error) {
+	conn, err := net.DialTimeout("unix", socketPath, daemonTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &daemonClient{
+		conn:    conn,
+		encoder: json.NewEncoder(conn),
+		decoder: json.NewDecoder(conn),
+	}
+	if _, err := c.query(daemonRequest{Op: daemonHello, Config: string(config)}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *daemonClient) query(request daemonRequest) ([]string, error) {
+	c.conn.SetDeadline(time.Now().Add(daemonTimeout))
+	if err := c.encoder.Encode(request); err != nil {
+		return nil, err
+	}
+	var response daemonResponse
+	if err := c.decoder.Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Error != "" {
+		return nil, errors.New(response.Error)
+	}
+	return response.Files, nil
+}
+
+func (c *daemonClient) close() {
+	c.conn.Close()
+}
+
+// NewWithDaemon is like New, but the queries are answered by the finder
+// daemon listening on the unix socket at socketPath, if there is one with the
+// same cacheParams. Otherwise, or if the daemon stops answering, the Finder
+// uses the on-disk cache like one created by New.
+func NewWithDaemon(cacheParams CacheParams, filesystem fs.FileSystem,
+	logger Logger, dbPath string, socketPath string) (f *Finder, err error) {
+	f = newUnloaded(cacheParams, filesystem, logger, dbPath, defaultNumThreads)
+
+	config, err := f.cacheMetadata.Config.Dump()
+	if err != nil {
+		return nil, err
+	}
+	f.daemon, err = dialDaemon(socketPath, config)
+	if err == nil {
+		f.verbosef("Using finder daemon at %v\n", socketPath)
+		return f, nil
+	}
+	f.verbosef("Not using finder daemon at %v: %v\n", socketPath, err)
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// findWithDaemon asks the daemon, if any, for the results of a query. If the
+// daemon fails to answer, it switches to the on-disk cache and returns false.
+// If the cache fails to load, like New would, this and later queries have no
+// results, and the error is returned by Err.
+func (f *Finder) findWithDaemon(op string, rootPath string, fileName string) ([]string, bool) {
+	f.lock()
+	defer f.unlock()
+
+	if f.loadErr != nil {
+		return []string{}, true
+	}
+	if f.daemon == nil {
+		return nil, false
+	}
+
+	results, err := f.daemon.query(daemonRequest{Op: op, Root: rootPath, Name: fileName})
+	if err == nil {
+		return results, true
+	}
+
+	f.verbosef("Finder daemon failed, using the cache instead: %v\n", err)
+	f.daemon.close()
+	f.daemon = nil
+	if err := f.load(); err != nil {
+		// the filesystem may have changed since the daemon last answered,
+		// like a root directory being removed
+		f.verbosef("Failed to fall back from finder daemon: %v\n", err)
+		f.loadErr = fmt.Errorf("failed to fall back from finder daemon: %v", err)
+		return []string{}, true
+	}
+	return nil, false
+}
+
+// Err returns the error loading the on-disk cache after the daemon stopped
+// answering, if any. The queries since then had no results, so callers of
+// NewWithDaemon should check it after their queries.
+func (f *Finder) Err() error {
+	f.lock()
+	defer f.unlock()
+	return f.loadErr
+}
+
+// pathMapOfFiles returns a node tree that contains the given files, so that
+// they can be filtered by a WalkFunc. Relative paths are relative to
+// workingDir.
+func pathMapOfFiles(files []string, workingDir string) *pathMap {
+	root := newPathMap("/")
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(workingDir, file)
+		}
+		dir, name := filepath.Split(file)
+		node := root.GetNode(filepath.Clean(dir), true)
+		node.FileNames = append(node.FileNames, name)
+	}
+	root.UpdateNumDescendentsRecursive()
+	return root
+}
+
+// invalidate updates the node tree after the contents of the given
+// directories changed, by calling Stat on them and ReadDir on the ones that
+// changed, and recursively on any new subdirectories. It returns the
+// directories that aren't in the node tree, which are ignored.
+func (f *Finder) invalidate(dirs []string) (unknown []string) {
+	// the node tree can't change while it is being written to the cache
+	f.WaitForDbDump()
+
+	f.lock()
+	defer f.unlock()
+
+	// look up every node before changing any, because listDirSync can
+	// replace the children of a node
+	var invalidated []*pathMap
+	for _, dir := range dirs {
+		node := f.nodes.GetNode(dir, false)
+		if node == nil {
+			unknown = append(unknown, dir)
+			continue
+		}
+		invalidated = append(invalidated, node)
+	}
+
+	f.threadPool = newThreadPool(f.numDbLoadingThreads)
+	for _, node := range invalidated {
+		f.statDirAsync(node)
+	}
+	f.threadPool.Wait()
+	f.threadPool = nil
+
+	f.updateNumDescendents(invalidated)
+
+	// the errors have been logged; unlike when the Finder is created there is
+	// no caller to report them to
+	if err := f.getErr(); err != nil {
+		f.verbosef("%v\n", err)
+	}
+	f.fsErrs = nil
+
+	return unknown
+}
+
+// knownDirs returns the existing directories in the node tree under the given
+// directories, including themselves
+func (f *Finder) knownDirs(dirs []string) []string {
+	f.lock()
+	defer f.unlock()
+
+	var results []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		node := f.nodes.GetNode(dir, false)
+		if node == nil {
+			continue
+		}
+		nodes := []*pathMap{node}
+		for len(nodes) > 0 {
+			current := nodes[0]
+			nodes = nodes[1:]
+			if seen[current.path] {
+				continue
+			}
+			seen[current.path] = true
+			if current.ModTime != 0 {
+				results = append(results, current.path)
+			}
+			for _, child := range current.children {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return results
+}
@@ -0,0 +1,321 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+// a fakeWatcher reports the directories that the test says changed
+type fakeWatcher struct {
+	finder *Finder
+
+	lock    sync.Mutex
+	changed []string
+}
+
+func (w *fakeWatcher) setChanged(dirs ...string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.changed = append(w.changed, dirs...)
+}
+
+func (w *fakeWatcher) update() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if len(w.changed) > 0 {
+		w.finder.invalidate(w.changed)
+		w.changed = nil
+	}
+	return nil
+}
+
+func (w *fakeWatcher) close() error {
+	return nil
+}
+
+// startDaemon starts a daemon for f with a fakeWatcher, and returns the path of
+// its socket
+func startDaemon(t *testing.T, f *Finder) (*Daemon, *fakeWatcher, string) {
+	// the MockFs can't be written to by the test and the dump at once
+	f.WaitForDbDump()
+
+	socketPath := filepath.Join(t.TempDir(), "finder.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &fakeWatcher{finder: f}
+	d := newDaemon(f, w, listener)
+	go d.Serve()
+	return d, w, socketPath
+}
+
+func newDaemonClient(t *testing.T, filesystem *fs.MockFs, cacheParams CacheParams, socketPath string) *Finder {
+	if cacheParams.WorkingDirectory == "" {
+		cacheParams.WorkingDirectory = "/cwd"
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	f, err := NewWithDaemon(cacheParams, filesystem, logger, "/finder/finder-db", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestInvalidate(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/1/findme.txt", filesystem)
+	fs.Create(t, "/tmp/b/findme.txt", filesystem)
+	fs.Create(t, "/tmp/b/2/findme.txt", filesystem)
+	fs.Create(t, "/tmp/c/findme.txt", filesystem)
+
+	finder := newFinder(
+		t,
+		filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		},
+	)
+	defer finder.Shutdown()
+	finder.WaitForDbDump()
+
+	// modify the filesystem
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/a/new.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme2/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme2/3/findme.txt", filesystem)
+	fs.Move(t, "/tmp/b", "/tmp/d", filesystem)
+	fs.Delete(t, "/tmp/c/findme.txt", filesystem)
+	filesystem.ClearMetrics()
+
+	unknown := finder.invalidate([]string{"/tmp", "/tmp/a", "/tmp/c", "/tmp/unknown"})
+
+	fs.AssertSameResponse(t, unknown, []string{"/tmp/unknown"})
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"),
+		[]string{
+			"/tmp/a/1/findme.txt",
+			"/tmp/a/findme.txt",
+			"/tmp/a/findme2/3/findme.txt",
+			"/tmp/a/findme2/findme.txt",
+			"/tmp/d/2/findme.txt",
+			"/tmp/d/findme.txt",
+			"/tmp/findme.txt",
+		})
+	// only the invalidated directories and the new ones are checked
+	fs.AssertSameStatCalls(t, filesystem.StatCalls,
+		[]string{"/tmp", "/tmp/a", "/tmp/a/findme2", "/tmp/a/findme2/3", "/tmp/c", "/tmp/d", "/tmp/d/2"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls,
+		[]string{"/tmp", "/tmp/a", "/tmp/a/findme2", "/tmp/a/findme2/3", "/tmp/c", "/tmp/d", "/tmp/d/2"})
+	fs.AssertSameResponse(t, finder.knownDirs([]string{"/tmp/a", "/tmp/d"}),
+		[]string{"/tmp/a", "/tmp/a/1", "/tmp/a/findme2", "/tmp/a/findme2/3", "/tmp/d", "/tmp/d/2"})
+}
+
+func TestInvalidateUnchanged(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+
+	finder := newFinder(
+		t,
+		filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		},
+	)
+	defer finder.Shutdown()
+	filesystem.ClearMetrics()
+
+	finder.invalidate([]string{"/tmp", "/tmp/a"})
+
+	fs.AssertSameResponse(t, finder.FindNamedAt("/tmp", "findme.txt"), []string{"/tmp/a/findme.txt"})
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{"/tmp", "/tmp/a"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+}
+
+func TestDaemon(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/1/findme.txt", filesystem)
+	fs.Create(t, "/tmp/b/ignoreme.txt", filesystem)
+	cacheParams := CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt", "ignoreme.txt"},
+	}
+
+	d, w, socketPath := startDaemon(t, newFinder(t, filesystem, cacheParams))
+	defer d.Close()
+
+	filesystem.ClearMetrics()
+	client := newDaemonClient(t, filesystem, cacheParams, socketPath)
+	defer client.Shutdown()
+
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/1/findme.txt", "/tmp/a/findme.txt", "/tmp/findme.txt"})
+	fs.AssertSameResponse(t, client.FindFirstNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/findme.txt"})
+	fs.AssertSameResponse(t, client.FindMatching("/tmp/a",
+		func(entries DirEntries) (dirs []string, files []string) {
+			// don't descend into subdirectories
+			return nil, entries.FileNames
+		}),
+		[]string{"/tmp/a/findme.txt"})
+	fs.AssertSameResponse(t, client.FindAll(),
+		[]string{"/tmp/a/1/findme.txt", "/tmp/a/findme.txt", "/tmp/b/ignoreme.txt", "/tmp/findme.txt"})
+	// the client doesn't look at the filesystem
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+
+	// changes are seen by the next query
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/b/findme.txt", filesystem)
+	w.setChanged("/tmp/b")
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp/b", "findme.txt"), []string{"/tmp/b/findme.txt"})
+}
+
+func TestDaemonRelativePaths(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/cwd/a/findme.txt", filesystem)
+	fs.Create(t, "/cwd/a/b/findme.txt", filesystem)
+	cacheParams := CacheParams{
+		RootDirs:     []string{"."},
+		IncludeFiles: []string{"findme.txt"},
+	}
+
+	d, _, socketPath := startDaemon(t, newFinder(t, filesystem, cacheParams))
+	defer d.Close()
+	client := newDaemonClient(t, filesystem, cacheParams, socketPath)
+	defer client.Shutdown()
+
+	fs.AssertSameResponse(t, client.FindNamedAt("a", "findme.txt"), []string{"a/b/findme.txt", "a/findme.txt"})
+	fs.AssertSameResponse(t, client.FindMatching(".",
+		func(entries DirEntries) (dirs []string, files []string) {
+			return entries.DirNames, entries.FileNames
+		}),
+		[]string{"a/b/findme.txt", "a/findme.txt"})
+}
+
+func TestDaemonDifferentParams(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme2.txt", filesystem)
+
+	d, _, socketPath := startDaemon(t, newFinder(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	}))
+	defer d.Close()
+
+	client := newDaemonClient(t, filesystem, CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt", "findme2.txt"},
+	}, socketPath)
+	defer client.Shutdown()
+
+	if client.daemon != nil {
+		t.Errorf("want a client with different params to not use the daemon")
+	}
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme2.txt"), []string{"/tmp/a/findme2.txt"})
+}
+
+func TestDaemonFallback(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	cacheParams := CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	}
+
+	// no daemon
+	client := newDaemonClient(t, filesystem, cacheParams, filepath.Join(t.TempDir(), "finder.sock"))
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"), []string{"/tmp/a/findme.txt", "/tmp/findme.txt"})
+	client.Shutdown()
+
+	// the daemon exits while it is used
+	d, _, socketPath := startDaemon(t, newFinder(t, filesystem, cacheParams))
+	client = newDaemonClient(t, filesystem, cacheParams, socketPath)
+	defer client.Shutdown()
+	if client.daemon == nil {
+		t.Fatalf("want the client to use the daemon")
+	}
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"), []string{"/tmp/a/findme.txt", "/tmp/findme.txt"})
+
+	d.Close()
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/b/findme.txt", filesystem)
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"),
+		[]string{"/tmp/a/findme.txt", "/tmp/b/findme.txt", "/tmp/findme.txt"})
+	if client.daemon != nil {
+		t.Errorf("want the client to stop using the daemon")
+	}
+	if err := client.Err(); err != nil {
+		t.Errorf("want no error, got %v", err)
+	}
+}
+
+func TestDaemonFallbackError(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	cacheParams := CacheParams{
+		RootDirs:     []string{"/tmp"},
+		IncludeFiles: []string{"findme.txt"},
+	}
+
+	d, _, socketPath := startDaemon(t, newFinder(t, filesystem, cacheParams))
+	client := newDaemonClient(t, filesystem, cacheParams, socketPath)
+	defer client.Shutdown()
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"), []string{"/tmp/a/findme.txt", "/tmp/findme.txt"})
+
+	// the daemon exits after a root directory was removed, so the cache
+	// can't be loaded like New couldn't create a Finder
+	d.Close()
+	fs.RemoveAll(t, "/tmp", filesystem)
+	fs.AssertSameResponse(t, client.FindNamedAt("/tmp", "findme.txt"), []string{})
+	if err := client.Err(); err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("want the error loading the cache, got %v", err)
+	}
+	fs.AssertSameResponse(t, client.FindFirstNamedAt("/tmp", "findme.txt"), []string{})
+	fs.AssertSameResponse(t, client.FindAll(), []string{})
+}
+
+func TestPathMapOfFiles(t *testing.T) {
+	root := pathMapOfFiles([]string{"/tmp/a/x.txt", "b/y.txt", "/tmp/a/c/z.txt"}, "/cwd")
+
+	node := root.GetNode("/tmp/a", false)
+	if node == nil {
+		t.Fatal("want a node for /tmp/a")
+	}
+	fs.AssertSameResponse(t, node.FileNames, []string{"x.txt"})
+	if root.GetNode("/cwd/b", false) == nil {
+		t.Errorf("want relative paths to be in the working directory")
+	}
+	if root.approximateNumDescendents != 6 {
+		t.Errorf("want 6 nodes, got %v", root.approximateNumDescendents)
+	}
+}
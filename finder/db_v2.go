@@ -0,0 +1,639 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This file provides the version 2 format of the cache db, a compact binary
+// format that is divided into sections that can be loaded and rewritten
+// independently of each other.
+//
+// After the same two header lines as version 1 (the version string and the
+// cache config), the db contains an index and then the data of each section:
+//
+//	uvarint number of sections
+//	for each section:
+//		uvarint length of the path of its root directory, the path
+//		uvarint offset of its data after the index
+//		uvarint length of its data
+//		uint32  crc32 of its data (little-endian)
+//	the data of each section, in the order of the index
+//
+// A section contains a directory and all of its descendants that aren't in
+// other sections, which always come after it in the index. Its data is:
+//
+//	uvarint number of strings, then each as a uvarint length and the bytes
+//	uvarint number of directories, then each:
+//		uvarint index of its parent directory (except for the first directory,
+//		        which is the root of the section)
+//		uvarint index of its name in the strings (except for the first one)
+//		varint  modification time, 0 if the directory didn't exist
+//		uvarint inode number
+//		uvarint device number
+//		uvarint number of files, then the index of each name in the strings
+//
+// Each section can be decoded in place from the bytes of the db, so only the
+// index needs to be read to start using it. The Finder only loads (and calls
+// Stat on the directories of) the sections that are needed by a query, and
+// when it saves the db it copies the bytes of the sections that didn't change
+// instead of encoding them again.
+
+// A section is split off from its parent when it has between
+// defaultMinDbSectionDirs and defaultMaxDbSectionDirs directories. Smaller
+// subtrees stay in their parent's section, and larger ones are split further.
+const (
+	defaultMinDbSectionDirs = 512
+	defaultMaxDbSectionDirs = 8192
+)
+
+// a dbSection is a section of a version 2 db
+type dbSection struct {
+	// the path of the root directory of the section
+	root string
+	data []byte
+	crc  uint32
+
+	// whether the directories of the section have been added to the node tree
+	loaded bool
+	// set if any of the directories of the section changed since it was read
+	modified int32
+}
+
+func (s *dbSection) setModified() {
+	atomic.StoreInt32(&s.modified, 1)
+}
+
+func (s *dbSection) wasModified() bool {
+	return atomic.LoadInt32(&s.modified) > 0
+}
+
+// isSectionRoot tells whether node is the root directory of a section of the db
+func (node *pathMap) isSectionRoot() bool {
+	return node.section != nil && node.section.root == node.path
+}
+
+// isUnloaded tells whether node is the root directory of a section of the db
+// that hasn't been loaded yet
+func (node *pathMap) isUnloaded() bool {
+	return node.isSectionRoot() && !node.section.loaded
+}
+
+// startFromDbV2 reads the index of a version 2 db from reader, and loads the
+// sections that contain the root directories of the cache. The other sections
+// are loaded by loadSections when they are needed.
+func (f *Finder) startFromDbV2(reader *bufio.Reader) error {
+	startTime := time.Now()
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	sections, err := decodeDbIndex(data)
+	if err != nil {
+		return fmt.Errorf("Failed to read the index of the database: %v", err)
+	}
+	f.verbosef("Read the index of %v sections in %v\n", len(sections), time.Since(startTime))
+
+	// add an unloaded node for the root of each section; the parent section
+	// of each one comes before it, so the directories between them belong
+	// to the parent section
+	mainTree := newPathMap("/")
+	for _, section := range sections {
+		node := mainTree.GetNode(section.root, true)
+		node.section = section
+	}
+	f.nodes = *mainTree
+
+	for _, path := range f.cacheMetadata.Config.RootDirs {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, path)
+		}
+		f.loadSections(filepath.Clean(path), false)
+	}
+	f.verbosef("Loaded the database sections of the root directories in %v\n", time.Since(startTime))
+	return nil
+}
+
+func decodeDbIndex(data []byte) ([]*dbSection, error) {
+	d := dbDecoder{data: data}
+	count := d.uvarint()
+	if count == 0 || count > uint64(len(data)) {
+		return nil, errors.New("invalid number of sections")
+	}
+	sections := make([]*dbSection, count)
+	type extent struct{ offset, length uint64 }
+	extents := make([]extent, count)
+	for i := range sections {
+		root := d.bytes()
+		extents[i] = extent{d.uvarint(), d.uvarint()}
+		sections[i] = &dbSection{root: string(root), crc: d.uint32()}
+		if d.err != nil {
+			return nil, d.err
+		}
+		if !filepath.IsAbs(sections[i].root) {
+			return nil, fmt.Errorf("invalid section root %q", sections[i].root)
+		}
+	}
+	if sections[0].root != "/" {
+		return nil, errors.New("the first section isn't the filesystem root")
+	}
+
+	// the sections must fill the rest of the db exactly, so that truncated
+	// or appended data is noticed without loading every section
+	body := data[d.offset:]
+	next := uint64(0)
+	for i, e := range extents {
+		if e.offset != next || e.length > uint64(len(body))-next {
+			return nil, fmt.Errorf("section %v is out of bounds", i)
+		}
+		sections[i].data = body[e.offset : e.offset+e.length]
+		next += e.length
+	}
+	if next != uint64(len(body)) {
+		return nil, fmt.Errorf("%v unexpected bytes after the last section", uint64(len(body))-next)
+	}
+	return sections, nil
+}
+
+// loadSections loads the unloaded sections of the db that contain path, and
+// if subtree is set, the ones below it as well. Their directories are checked
+// for changes like when the Finder is created.
+// loadSections must be called with the Finder locked, and doesn't report the
+// filesystem errors that it encounters.
+func (f *Finder) loadSections(path string, subtree bool) {
+	for {
+		// sections are loaded in rounds, so that a section is only loaded
+		// once the sections above it are, and ReadDir is only called once
+		// every section in the round has been added to the node tree
+		nodes := f.unloadedSections(path, subtree)
+		if len(nodes) == 0 {
+			return
+		}
+		startTime := time.Now()
+		// the node tree can't change while it is being written to the db
+		f.WaitForDbDump()
+
+		f.threadPool = newThreadPool(f.numDbLoadingThreads)
+		dirsToWalk := make([][]*pathMap, len(nodes))
+		errs := make([]error, len(nodes))
+		for i := range nodes {
+			i := i
+			f.threadPool.Run(func() {
+				dirsToWalk[i], errs[i] = f.loadSection(nodes[i])
+			})
+		}
+		f.threadPool.Wait()
+
+		for i, node := range nodes {
+			if errs[i] != nil {
+				// scan the directories of the section again instead
+				f.verbosef("Failed to load database section %v: %v\n", node.path, errs[i])
+				node.mapNode = mapNode{}
+				node.children = make(map[string]*pathMap)
+				node.section = nil
+				f.setModified()
+				f.statDirAsync(node)
+			} else {
+				f.listDirsAsync(dirsToWalk[i])
+			}
+		}
+		f.threadPool.Wait()
+		f.threadPool = nil
+
+		f.updateNumDescendents(nodes)
+		f.verbosef("Loaded %v database sections in %v\n", len(nodes), time.Since(startTime))
+	}
+}
+
+// unloadedSections returns the unloaded sections that loadSections needs to
+// load next: the topmost unloaded section that contains path, or if there is
+// none and subtree is set, the topmost ones below path.
+func (f *Finder) unloadedSections(path string, subtree bool) []*pathMap {
+	node := &f.nodes
+	if node.isUnloaded() {
+		return []*pathMap{node}
+	}
+	for _, name := range splitPath(path) {
+		child, ok := node.children[name]
+		if !ok {
+			return nil
+		}
+		if child.isUnloaded() {
+			return []*pathMap{child}
+		}
+		node = child
+	}
+	if !subtree {
+		return nil
+	}
+
+	var unloaded []*pathMap
+	nodes := []*pathMap{node}
+	for len(nodes) > 0 {
+		current := nodes[0]
+		nodes = nodes[1:]
+		for _, child := range current.children {
+			if child.isUnloaded() {
+				unloaded = append(unloaded, child)
+			} else {
+				nodes = append(nodes, child)
+			}
+		}
+	}
+	return unloaded
+}
+
+// splitPath returns the names of the directories in path, which must be clean
+// and absolute
+func splitPath(path string) []string {
+	var names []string
+	for path != "/" && path != "." && path != "" {
+		names = append(names, filepath.Base(path))
+		path = filepath.Dir(path)
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return names
+}
+
+// loadSection adds the directories of the section of node to the node tree,
+// and returns the ones that changed since the section was written
+func (f *Finder) loadSection(node *pathMap) (dirsToWalk []*pathMap, err error) {
+	section := node.section
+	if crc32.ChecksumIEEE(section.data) != section.crc {
+		return nil, errors.New("checksum mismatch")
+	}
+	dirs, err := decodeDbSection(section.data)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*pathMap, len(dirs))
+	nodes[0] = node
+	for i := 1; i < len(dirs); i++ {
+		parent := nodes[dirs[i].parent]
+		child, found := parent.children[dirs[i].name]
+		if !found {
+			child = parent.newChild(dirs[i].name)
+		}
+		child.section = section
+		nodes[i] = child
+	}
+
+	for i, dir := range dirs {
+		if dir.ModTime == 0 {
+			// the directory didn't exist when the db was written, which
+			// only happens for directories that were never scanned
+			nodes[i].mapNode = mapNode{}
+			continue
+		}
+		updated := f.statDirSync(nodes[i].path)
+		nodes[i].mapNode = mapNode{statResponse: updated}
+		if !f.isInfoUpToDate(dir.statResponse, updated) && updated.ModTime != 0 {
+			f.setModified()
+			section.setModified()
+			dirsToWalk = append(dirsToWalk, nodes[i])
+		} else {
+			nodes[i].FileNames = dir.FileNames
+		}
+	}
+	section.loaded = true
+	return dirsToWalk, nil
+}
+
+// a dbDir is a directory in a section of a version 2 db
+type dbDir struct {
+	mapNode
+
+	parent int
+	name   string
+}
+
+func decodeDbSection(data []byte) ([]dbDir, error) {
+	d := dbDecoder{data: data}
+
+	numStrings := d.uvarint()
+	if numStrings > uint64(len(data)) {
+		return nil, errors.New("invalid number of strings")
+	}
+	names := make([]string, numStrings)
+	for i := range names {
+		names[i] = string(d.bytes())
+	}
+	name := func() string {
+		i := d.uvarint()
+		if i >= uint64(len(names)) {
+			d.fail(fmt.Errorf("invalid string %v", i))
+			return ""
+		}
+		return names[i]
+	}
+
+	numDirs := d.uvarint()
+	if numDirs == 0 || numDirs > uint64(len(data)) {
+		return nil, errors.New("invalid number of directories")
+	}
+	dirs := make([]dbDir, numDirs)
+	for i := range dirs {
+		if i > 0 {
+			parent := d.uvarint()
+			if parent >= uint64(i) {
+				return nil, fmt.Errorf("directory %v has invalid parent %v", i, parent)
+			}
+			dirs[i].parent = int(parent)
+			dirs[i].name = name()
+		}
+		dirs[i].ModTime = d.varint()
+		dirs[i].Inode = d.uvarint()
+		dirs[i].Device = d.uvarint()
+		numFiles := d.uvarint()
+		if numFiles > uint64(len(data)) {
+			return nil, errors.New("invalid number of files")
+		}
+		dirs[i].FileNames = make([]string, numFiles)
+		for j := range dirs[i].FileNames {
+			dirs[i].FileNames[j] = name()
+		}
+		if d.err != nil {
+			return nil, d.err
+		}
+	}
+	if d.offset != len(data) {
+		return nil, errors.New("unexpected bytes after the last directory")
+	}
+	return dirs, nil
+}
+
+// a dbDecoder reads the values of a version 2 db, and remembers the first
+// error
+type dbDecoder struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (d *dbDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+	d.offset = len(d.data)
+}
+
+func (d *dbDecoder) uvarint() uint64 {
+	value, n := binary.Uvarint(d.data[d.offset:])
+	if n <= 0 {
+		d.fail(errors.New("invalid or truncated number"))
+		return 0
+	}
+	d.offset += n
+	return value
+}
+
+func (d *dbDecoder) varint() int64 {
+	value, n := binary.Varint(d.data[d.offset:])
+	if n <= 0 {
+		d.fail(errors.New("invalid or truncated number"))
+		return 0
+	}
+	d.offset += n
+	return value
+}
+
+func (d *dbDecoder) uint32() uint32 {
+	if len(d.data)-d.offset < 4 {
+		d.fail(errors.New("truncated number"))
+		return 0
+	}
+	value := binary.LittleEndian.Uint32(d.data[d.offset:])
+	d.offset += 4
+	return value
+}
+
+func (d *dbDecoder) bytes() []byte {
+	length := d.uvarint()
+	if length > uint64(len(d.data)-d.offset) {
+		d.fail(errors.New("truncated string"))
+		return nil
+	}
+	value := d.data[d.offset : d.offset+int(length)]
+	d.offset += int(length)
+	return value
+}
+
+// a dbEncoder writes the values of a version 2 db
+type dbEncoder struct {
+	data    []byte
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *dbEncoder) uvarint(value uint64) {
+	n := binary.PutUvarint(e.scratch[:], value)
+	e.data = append(e.data, e.scratch[:n]...)
+}
+
+func (e *dbEncoder) varint(value int64) {
+	n := binary.PutVarint(e.scratch[:], value)
+	e.data = append(e.data, e.scratch[:n]...)
+}
+
+func (e *dbEncoder) uint32(value uint32) {
+	binary.LittleEndian.PutUint32(e.scratch[:], value)
+	e.data = append(e.data, e.scratch[:4]...)
+}
+
+func (e *dbEncoder) bytes(value string) {
+	e.uvarint(uint64(len(value)))
+	e.data = append(e.data, value...)
+}
+
+// dbSectionRoots divides the node tree into the sections to write to the db,
+// and returns the root directory of each one, parents first. The sections that
+// were read from the db and didn't change are kept as they are.
+func (f *Finder) dbSectionRoots() []*pathMap {
+	// the number of directories in each subtree that will be encoded again
+	sizes := make(map[*pathMap]int)
+	var countDirs func(node *pathMap) int
+	countDirs = func(node *pathMap) int {
+		count := 1
+		for _, child := range node.children {
+			childCount := countDirs(child)
+			if !f.isReusable(child) {
+				count += childCount
+			}
+		}
+		sizes[node] = count
+		return count
+	}
+	countDirs(&f.nodes)
+
+	var roots []*pathMap
+	var walk func(root *pathMap, node *pathMap)
+	walk = func(root *pathMap, node *pathMap) {
+		for _, name := range sortedChildNames(node) {
+			child := node.children[name]
+			split := false
+			if f.isReusable(root) {
+				// only the directories of an unmodified section stay in
+				// it, including the ones that are scanned again after
+				// failing to load their own section
+				split = child.section != root.section
+			} else {
+				split = f.isReusable(child) ||
+					(sizes[child] >= f.minDbSectionDirs && sizes[child] <= f.maxDbSectionDirs)
+			}
+			if split {
+				roots = append(roots, child)
+				walk(child, child)
+			} else {
+				walk(root, child)
+			}
+		}
+	}
+	roots = append(roots, &f.nodes)
+	walk(&f.nodes, &f.nodes)
+	return roots
+}
+
+// isReusable tells whether node is the root of a section of the db that can be
+// written again without encoding it
+func (f *Finder) isReusable(node *pathMap) bool {
+	return node.isSectionRoot() && !node.section.wasModified()
+}
+
+func sortedChildNames(node *pathMap) []string {
+	names := make([]string, 0, len(node.children))
+	for name := range node.children {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// encodeDbSection encodes the section with the given root directory, which
+// contains its descendants that aren't the roots of other sections
+func encodeDbSection(root *pathMap, roots map[*pathMap]bool) []byte {
+	var names []string
+	nameIndexes := make(map[string]uint64)
+	intern := func(name string) uint64 {
+		index, ok := nameIndexes[name]
+		if !ok {
+			index = uint64(len(names))
+			nameIndexes[name] = index
+			names = append(names, name)
+		}
+		return index
+	}
+
+	var dirs dbEncoder
+	numDirs := 0
+	var encodeDir func(node *pathMap, parent int, name string)
+	encodeDir = func(node *pathMap, parent int, name string) {
+		index := numDirs
+		numDirs++
+		if index > 0 {
+			dirs.uvarint(uint64(parent))
+			dirs.uvarint(intern(name))
+		}
+		dirs.varint(node.ModTime)
+		dirs.uvarint(node.Inode)
+		dirs.uvarint(node.Device)
+		dirs.uvarint(uint64(len(node.FileNames)))
+		for _, file := range node.FileNames {
+			dirs.uvarint(intern(file))
+		}
+		for _, childName := range sortedChildNames(node) {
+			child := node.children[childName]
+			if !roots[child] {
+				encodeDir(child, index, childName)
+			}
+		}
+	}
+	encodeDir(root, 0, "")
+
+	var e dbEncoder
+	e.uvarint(uint64(len(names)))
+	for _, name := range names {
+		e.bytes(name)
+	}
+	e.uvarint(uint64(numDirs))
+	e.data = append(e.data, dirs.data...)
+	return e.data
+}
+
+// serializeDbV2 converts the cache database into the version 2 format
+func (f *Finder) serializeDbV2() ([]byte, error) {
+	startTime := time.Now()
+	roots := f.dbSectionRoots()
+	isRoot := make(map[*pathMap]bool, len(roots))
+	for _, root := range roots {
+		isRoot[root] = true
+	}
+
+	// encode the modified sections in parallel
+	sections := make([]*dbSection, len(roots))
+	numEncoded := 0
+	wg := sync.WaitGroup{}
+	for i, root := range roots {
+		if f.isReusable(root) {
+			sections[i] = root.section
+			continue
+		}
+		numEncoded++
+		wg.Add(1)
+		go func(i int, root *pathMap) {
+			data := encodeDbSection(root, isRoot)
+			sections[i] = &dbSection{root: root.path, data: data, crc: crc32.ChecksumIEEE(data)}
+			wg.Done()
+		}(i, root)
+	}
+	wg.Wait()
+	f.verbosef("Encoded %v of %v database sections in %v\n", numEncoded, len(roots), time.Since(startTime))
+
+	header := []byte(f.cacheMetadata.Version)
+	header = append(header, lineSeparator)
+	configDump, err := f.cacheMetadata.Config.Dump()
+	if err != nil {
+		return nil, err
+	}
+	header = append(header, configDump...)
+	header = append(header, lineSeparator)
+
+	e := dbEncoder{data: header}
+	e.uvarint(uint64(len(sections)))
+	offset := 0
+	for _, section := range sections {
+		e.bytes(section.root)
+		e.uvarint(uint64(offset))
+		e.uvarint(uint64(len(section.data)))
+		e.uint32(section.crc)
+		offset += len(section.data)
+	}
+	for _, section := range sections {
+		e.data = append(e.data, section.data...)
+	}
+	return e.data, nil
+}
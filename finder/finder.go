@@ -77,13 +77,20 @@ import (
 //    and scanning the filesystem) is complete.
 //    Tests indicate that it only takes about 10% as long to search the in-memory cache as to
 //    generate it, making this not a huge loss in performance.
+//    The exception is the sections of a version 2 db that aren't needed by the
+//    root directories, which are loaded by the first query that needs them (see db_v2.go)
+//    while the Finder is locked.
 // 4. The parsing of the db and the initial setup of the pathMap tree must complete before
 //      beginning to call listDirSync (because listDirSync can create new entries in the pathMap)
 
 // see cmd/finder.go or finder_test.go for usage examples
 
 // Update versionString whenever making a backwards-incompatible change to the cache file format
-const versionString = "Android finder version 1"
+const versionString = "Android finder version 2"
+
+// versionStringV1 is the version of the previous, JSON cache file format, which is still read
+// so that an existing cache is migrated instead of being regenerated
+const versionStringV1 = "Android finder version 1"
 
 // a CacheParams specifies which files and directories the user wishes be scanned and
 // potentially added to the cache
@@ -149,6 +156,8 @@ type Finder struct {
 	cacheMetadata       cacheMetadata
 	logger              Logger
 	filesystem          fs.FileSystem
+	minDbSectionDirs    int
+	maxDbSectionDirs    int
 
 	// temporary state
 	threadPool        *threadPool
@@ -160,6 +169,12 @@ type Finder struct {
 	// non-temporary state
 	modifiedFlag int32
 	nodes        pathMap
+
+	// If set, queries are answered by a finder daemon, see daemon.go.
+	daemon *daemonClient
+	// If set, the cache failed to load after the daemon stopped answering,
+	// and queries return no results.
+	loadErr error
 }
 
 var defaultNumThreads = runtime.NumCPU() * 2
@@ -173,6 +188,16 @@ func New(cacheParams CacheParams, filesystem fs.FileSystem,
 // newImpl is like New but accepts more params
 func newImpl(cacheParams CacheParams, filesystem fs.FileSystem,
 	logger Logger, dbPath string, numThreads int) (f *Finder, err error) {
+	f = newUnloaded(cacheParams, filesystem, logger, dbPath, numThreads)
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// newUnloaded creates a Finder that hasn't read the cache or the filesystem yet
+func newUnloaded(cacheParams CacheParams, filesystem fs.FileSystem,
+	logger Logger, dbPath string, numThreads int) *Finder {
 	numDbLoadingThreads := numThreads
 	numSearchingThreads := numThreads
 
@@ -184,39 +209,44 @@ func newImpl(cacheParams CacheParams, filesystem fs.FileSystem,
 		},
 	}
 
-	f = &Finder{
+	return &Finder{
 		numDbLoadingThreads: numDbLoadingThreads,
 		numSearchingThreads: numSearchingThreads,
 		cacheMetadata:       metadata,
 		logger:              logger,
 		filesystem:          filesystem,
+		minDbSectionDirs:    defaultMinDbSectionDirs,
+		maxDbSectionDirs:    defaultMaxDbSectionDirs,
 
 		nodes:  *newPathMap("/"),
 		DbPath: dbPath,
 
 		shutdownWaitgroup: sync.WaitGroup{},
 	}
+}
 
+// load populates the in-memory cache from the cache db and the filesystem
+func (f *Finder) load() error {
 	f.loadFromFilesystem()
 
 	// check for any filesystem errors
-	err = f.getErr()
+	err := f.getErr()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// confirm that every path mentioned in the CacheConfig exists
-	for _, path := range cacheParams.RootDirs {
+	for _, path := range f.cacheMetadata.Config.RootDirs {
 		if !filepath.IsAbs(path) {
 			path = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, path)
 		}
 		node := f.nodes.GetNode(filepath.Clean(path), false)
 		if node == nil || node.ModTime == 0 {
-			return nil, fmt.Errorf("path %v was specified to be included in the cache but does not exist\n", path)
+			return fmt.Errorf("path %v was specified to be included in the cache but does not exist\n", path)
 		}
 	}
 
-	return f, nil
+	return nil
 }
 
 // FindNamed searches for every cached file
@@ -241,6 +271,9 @@ func (f *Finder) FindNamed(fileName string) []string {
 // The reason a caller might use FindNamedAt instead of FindNamed is if they want
 // to limit their search to a subset of the cache
 func (f *Finder) FindNamedAt(rootPath string, fileName string) []string {
+	if results, ok := f.findWithDaemon(daemonFindNamedAt, rootPath, fileName); ok {
+		return results
+	}
 	filter := func(entries DirEntries) (dirNames []string, fileNames []string) {
 		matches := []string{}
 		for _, foundName := range entries.FileNames {
@@ -263,6 +296,9 @@ func (f *Finder) FindFirstNamed(fileName string) []string {
 // FindFirstNamedAt searches for every file named <fileName>
 // Whenever it finds a match, it stops search subdirectories
 func (f *Finder) FindFirstNamedAt(rootPath string, fileName string) []string {
+	if results, ok := f.findWithDaemon(daemonFindFirstNamedAt, rootPath, fileName); ok {
+		return results
+	}
 	filter := func(entries DirEntries) (dirNames []string, fileNames []string) {
 		matches := []string{}
 		for _, foundName := range entries.FileNames {
@@ -289,6 +325,10 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 	var isRel bool
 	workingDir := f.cacheMetadata.Config.WorkingDirectory
 
+	// the daemon can't run the filter, so it lists every file under
+	// rootPath and the filter is run on those
+	daemonResults, useDaemon := f.findWithDaemon(daemonFindAt, rootPath, "")
+
 	isRel = !filepath.IsAbs(rootPath)
 	if isRel {
 		rootPath = filepath.Join(workingDir, rootPath)
@@ -301,7 +341,19 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 	f.lock()
 	defer f.unlock()
 
-	node := f.nodes.GetNode(rootPath, false)
+	nodes := &f.nodes
+	if useDaemon {
+		nodes = pathMapOfFiles(daemonResults, workingDir)
+	} else {
+		f.loadSections(rootPath, true)
+		// there is no caller to report these errors to once the Finder is created
+		if err := f.getErr(); err != nil {
+			f.verbosef("%v\n", err)
+		}
+		f.fsErrs = nil
+	}
+
+	node := nodes.GetNode(rootPath, false)
 	if node == nil {
 		f.verbosef("No data for path %v ; apparently not included in cache params: %v\n",
 			rootPath, f.cacheMetadata.Config.CacheParams)
@@ -326,8 +378,17 @@ func (f *Finder) FindMatching(rootPath string, filter WalkFunc) []string {
 }
 
 // Shutdown declares that the finder is no longer needed and waits for its cleanup to complete
-// Currently, that only entails waiting for the database dump to complete.
+// Currently, that only entails waiting for the database dump to complete, and dumping it again
+// if the queries found changes in sections of the database that weren't loaded before.
 func (f *Finder) Shutdown() {
+	f.lock()
+	if f.daemon != nil {
+		f.daemon.close()
+		f.daemon = nil
+	}
+	f.WaitForDbDump()
+	f.goDumpDb()
+	f.unlock()
 	f.WaitForDbDump()
 }
 
@@ -338,8 +399,9 @@ func (f *Finder) WaitForDbDump() {
 
 // End of public api
 
+// goDumpDb starts to save the cache database to disk, if it changed since it was last saved
 func (f *Finder) goDumpDb() {
-	if f.wasModified() {
+	if f.clearModified() {
 		f.shutdownWaitgroup.Add(1)
 		go func() {
 			err := f.dumpDb()
@@ -476,6 +538,9 @@ type pathMap struct {
 
 	// number of descendent nodes, including self
 	approximateNumDescendents int
+
+	// the section of a version 2 db that the directory was read from, if any
+	section *dbSection
 }
 
 func newPathMap(path string) *pathMap {
@@ -523,6 +588,7 @@ func (m *pathMap) GetNode(path string, createIfNotFound bool) *pathMap {
 func (m *pathMap) newChild(name string) (child *pathMap) {
 	path := joinCleanPaths(m.path, name)
 	newChild := newPathMap(path)
+	newChild.section = m.section
 	m.children[name] = newChild
 
 	return m.children[name]
@@ -544,6 +610,28 @@ func (m *pathMap) UpdateNumDescendentsRecursive() {
 	m.UpdateNumDescendents()
 }
 
+// updateNumDescendents updates the number of descendents of the given nodes after their subtrees
+// changed, and of their ancestors. The shape of the tree is used to divide the searches between
+// threads.
+func (f *Finder) updateNumDescendents(nodes []*pathMap) {
+	for _, node := range nodes {
+		node.UpdateNumDescendentsRecursive()
+	}
+	for _, node := range nodes {
+		if node.path == "/" {
+			continue
+		}
+		for dir := filepath.Dir(node.path); ; dir = filepath.Dir(dir) {
+			if ancestor := f.nodes.GetNode(dir, false); ancestor != nil {
+				ancestor.UpdateNumDescendents()
+			}
+			if dir == "/" {
+				break
+			}
+		}
+	}
+}
+
 func (m *pathMap) MergeIn(other *pathMap) {
 	for key, theirs := range other.children {
 		ours, found := m.children[key]
@@ -747,27 +835,28 @@ func (f *Finder) readLine(reader *bufio.Reader) ([]byte, error) {
 	return reader.ReadBytes(lineSeparator)
 }
 
-// validateCacheHeader reads the cache header from cacheReader and tells whether the cache is compatible with this Finder
-func (f *Finder) validateCacheHeader(cacheReader *bufio.Reader) bool {
+// validateCacheHeader reads the cache header from cacheReader and tells whether the cache is compatible with this Finder,
+// and which version of the cache file format it is in
+func (f *Finder) validateCacheHeader(cacheReader *bufio.Reader) (version string, ok bool) {
 	cacheVersionBytes, err := f.readLine(cacheReader)
 	if err != nil {
 		f.verbosef("Failed to read database header; database is invalid\n")
-		return false
+		return "", false
 	}
 	if len(cacheVersionBytes) > 0 && cacheVersionBytes[len(cacheVersionBytes)-1] == lineSeparator {
 		cacheVersionBytes = cacheVersionBytes[:len(cacheVersionBytes)-1]
 	}
 	cacheVersionString := string(cacheVersionBytes)
 	currentVersion := f.cacheMetadata.Version
-	if cacheVersionString != currentVersion {
+	if cacheVersionString != currentVersion && cacheVersionString != versionStringV1 {
 		f.verbosef("Version changed from %q to %q, database is not applicable\n", cacheVersionString, currentVersion)
-		return false
+		return "", false
 	}
 
 	cacheParamBytes, err := f.readLine(cacheReader)
 	if err != nil {
 		f.verbosef("Failed to read database search params; database is invalid\n")
-		return false
+		return "", false
 	}
 
 	if len(cacheParamBytes) > 0 && cacheParamBytes[len(cacheParamBytes)-1] == lineSeparator {
@@ -782,9 +871,9 @@ func (f *Finder) validateCacheHeader(cacheReader *bufio.Reader) bool {
 	currentParamString := string(currentParamBytes)
 	if cacheParamString != currentParamString {
 		f.verbosef("Params changed from %q to %q, database is not applicable\n", cacheParamString, currentParamString)
-		return false
+		return "", false
 	}
-	return true
+	return cacheVersionString, true
 }
 
 // loadBytes compares the cache info in <data> to the state of the filesystem
@@ -848,10 +937,16 @@ func (f *Finder) startFromExternalCache() (err error) {
 		return errors.New("No data to load from database\n")
 	}
 	bufferedReader := bufio.NewReader(reader)
-	if !f.validateCacheHeader(bufferedReader) {
+	version, ok := f.validateCacheHeader(bufferedReader)
+	if !ok {
 		return errors.New("Cache header does not match")
 	}
 	f.verbosef("Database header matches, will attempt to use database %v\n", f.DbPath)
+	if version != versionStringV1 {
+		return f.startFromDbV2(bufferedReader)
+	}
+	// write the database again in the current format
+	f.setModified()
 
 	// read the file and spawn threads to process it
 	nodesToWalk := [][]*pathMap{}
@@ -1054,8 +1149,9 @@ func (f *Finder) isInfoUpToDate(old statResponse, new statResponse) (equal bool)
 	return true
 }
 
-func (f *Finder) wasModified() bool {
-	return atomic.LoadInt32(&f.modifiedFlag) > 0
+// clearModified clears the modified flag and returns whether it was set
+func (f *Finder) clearModified() bool {
+	return atomic.SwapInt32(&f.modifiedFlag, 0) > 0
 }
 
 func (f *Finder) setModified() {
@@ -1085,8 +1181,9 @@ func (f *Finder) sortedDirEntries() []dirFullInfo {
 	return nodes
 }
 
-// serializeDb converts the cache database into a form to save to disk
-func (f *Finder) serializeDb() ([]byte, error) {
+// serializeDbV1 converts the cache database into the version 1 format, which is only read now.
+// It is kept to test the migration from version 1 and to compare the formats in benchmarks.
+func (f *Finder) serializeDbV1() ([]byte, error) {
 	// sort dir entries
 	var entryList = f.sortedDirEntries()
 
@@ -1096,7 +1193,7 @@ func (f *Finder) serializeDb() ([]byte, error) {
 
 	// generate header
 	header := []byte{}
-	header = append(header, []byte(f.cacheMetadata.Version)...)
+	header = append(header, []byte(versionStringV1)...)
 	header = append(header, lineSeparator)
 	configDump, err := f.cacheMetadata.Config.Dump()
 	if err != nil {
@@ -1157,7 +1254,7 @@ func (f *Finder) dumpDb() error {
 
 	tempPath := f.DbPath + ".tmp"
 
-	bytes, err := f.serializeDb()
+	bytes, err := f.serializeDbV2()
 	if err != nil {
 		return err
 	}
@@ -1263,6 +1360,9 @@ func (f *Finder) statDirAsync(dir *pathMap) {
 					FileNames:    []string{},
 				}
 				f.setModified()
+				if node.section != nil {
+					node.section.setModified()
+				}
 				if node.statResponse.ModTime != 0 {
 					// modification time was updated, so re-scan for
 					// child directories
@@ -1466,6 +1566,7 @@ func (f *Finder) listDirSync(dir *pathMap) {
 func (f *Finder) listMatches(node *pathMap,
 	filter WalkFunc) (subDirs []*pathMap, filePaths []string) {
 	entries := DirEntries{
+		Path:      node.path,
 		FileNames: node.FileNames,
 	}
 	entries.DirNames = make([]string, 0, len(node.children))
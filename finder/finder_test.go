@@ -15,6 +15,7 @@
 package finder
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -497,6 +498,178 @@ func TestFindFirst(t *testing.T) {
 	)
 }
 
+func newPatternsFinder(t *testing.T, filesystem *fs.MockFs) *Finder {
+	fs.Create(t, "/tmp/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/Android.mk", filesystem)
+	fs.Create(t, "/tmp/a/b/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/b/c/Android.mk", filesystem)
+	fs.Create(t, "/tmp/out/Android.bp", filesystem)
+	fs.Create(t, "/tmp/out/a/Android.bp", filesystem)
+	fs.Create(t, "/tmp/a/b/README", filesystem)
+
+	finder := newFinder(
+		t,
+		filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"Android.bp", "Android.mk"},
+		},
+	)
+	// queries must only use the cache
+	finder.WaitForDbDump()
+	filesystem.ClearMetrics()
+	return finder
+}
+
+func TestFindGlob(t *testing.T) {
+	filesystem := newFs()
+	finder := newPatternsFinder(t, filesystem)
+	defer finder.Shutdown()
+
+	foundPaths, err := finder.FindGlob("/tmp", "**/Android.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/Android.bp",
+			"/tmp/a/Android.bp",
+			"/tmp/a/b/Android.bp",
+			"/tmp/out/Android.bp",
+			"/tmp/out/a/Android.bp"})
+
+	foundPaths, err = finder.FindGlob("/tmp", "*/*/Android.*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/a/b/Android.bp",
+			"/tmp/out/a/Android.bp"})
+
+	// the glob is relative to the root of the query
+	foundPaths, err = finder.FindGlob("/tmp/a", "*/Android.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths, []string{"/tmp/a/b/Android.bp"})
+
+	// files that aren't in the cache aren't found
+	foundPaths, err = finder.FindGlob("/tmp", "**/README")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths, []string{})
+
+	if _, err := finder.FindGlob("/tmp", "/tmp/**"); err == nil {
+		t.Errorf("expected an error for an absolute glob")
+	}
+
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+}
+
+func TestFindGlobRelativeRoot(t *testing.T) {
+	filesystem := newFs()
+	fs.Create(t, "/cwd/Android.bp", filesystem)
+	fs.Create(t, "/cwd/a/Android.bp", filesystem)
+	fs.Create(t, "/cwd/a/b/Android.bp", filesystem)
+
+	finder := newFinder(
+		t,
+		filesystem,
+		CacheParams{
+			RootDirs:     []string{"."},
+			IncludeFiles: []string{"Android.bp"},
+		},
+	)
+	defer finder.Shutdown()
+
+	foundPaths, err := finder.FindGlob("a", "**/Android.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths, []string{"a/Android.bp", "a/b/Android.bp"})
+
+	foundPaths, err = finder.FindGlob(".", "*/Android.bp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths, []string{"a/Android.bp"})
+}
+
+func TestFindRegexp(t *testing.T) {
+	filesystem := newFs()
+	finder := newPatternsFinder(t, filesystem)
+	defer finder.Shutdown()
+
+	foundPaths, err := finder.FindRegexp("/tmp", `a/(.*/)?Android\.mk`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/a/Android.mk",
+			"/tmp/a/b/c/Android.mk"})
+
+	if _, err := finder.FindRegexp("/tmp", "a(b"); err == nil {
+		t.Errorf("expected an error for an invalid regular expression")
+	}
+
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+}
+
+func TestFindPatterns(t *testing.T) {
+	filesystem := newFs()
+	finder := newPatternsFinder(t, filesystem)
+	defer finder.Shutdown()
+
+	mustGlob := func(pattern string) Pattern {
+		glob, err := NewGlob(pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return glob
+	}
+	mustRegexp := func(expr string) Pattern {
+		re, err := NewRegexp(expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return re
+	}
+
+	// excluded directories aren't searched
+	foundPaths := finder.FindPatterns("/tmp", Patterns{
+		Include: []Pattern{mustGlob("**/Android.bp")},
+		Exclude: []Pattern{mustGlob("out")},
+	})
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/Android.bp",
+			"/tmp/a/Android.bp",
+			"/tmp/a/b/Android.bp"})
+
+	// without include patterns, every cached file is included
+	foundPaths = finder.FindPatterns("/tmp", Patterns{
+		Exclude: []Pattern{mustGlob("out"), mustRegexp(`.*/b/.*\.bp`)},
+	})
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/Android.bp",
+			"/tmp/a/Android.bp",
+			"/tmp/a/Android.mk",
+			"/tmp/a/b/c/Android.mk"})
+
+	// a file is included if it matches any include pattern
+	foundPaths = finder.FindPatterns("/tmp", Patterns{
+		Include: []Pattern{mustGlob("Android.bp"), mustRegexp(`.*/c/.*`)},
+	})
+	fs.AssertSameResponse(t, foundPaths,
+		[]string{"/tmp/Android.bp",
+			"/tmp/a/b/c/Android.mk"})
+
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+}
+
 func TestConcurrentFindSameDirectory(t *testing.T) {
 
 	testWithNumThreads := func(t *testing.T, numThreads int) {
@@ -1586,3 +1759,352 @@ func TestCacheEntryPathUnexpectedError(t *testing.T) {
 		t.Fatal("Failed to detect unexpected filesystem error")
 	}
 }
+
+// newFinderWithDbSections is like newFinder, but the db that it writes is divided into sections
+// of between minDirs and maxDirs directories
+func newFinderWithDbSections(t *testing.T, filesystem *fs.MockFs, cacheParams CacheParams,
+	minDirs int, maxDirs int) *Finder {
+	filesystem.MkDirs("/finder")
+	if cacheParams.WorkingDirectory == "" {
+		cacheParams.WorkingDirectory = "/cwd"
+	}
+	f := newUnloaded(cacheParams, filesystem, log.New(ioutil.Discard, "", 0), "/finder/finder-db", 2)
+	f.minDbSectionDirs = minDirs
+	f.maxDbSectionDirs = maxDirs
+	if err := f.load(); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+// readDbSections returns the header lines and the sections of a version 2 db. The data of the
+// sections is part of the returned bytes of the db.
+func readDbSections(t *testing.T, path string, filesystem *fs.MockFs) (db []byte, sections map[string]*dbSection) {
+	db = []byte(fs.Read(t, path, filesystem))
+	lines := bytes.SplitN(db, []byte{lineSeparator}, 3)
+	if len(lines) != 3 {
+		t.Fatalf("db has no header")
+	}
+	if string(lines[0]) != versionString {
+		t.Fatalf("expected db version %q, got %q", versionString, lines[0])
+	}
+	list, err := decodeDbIndex(lines[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections = make(map[string]*dbSection)
+	for _, section := range list {
+		sections[section.root] = section
+	}
+	return db, sections
+}
+
+func createDbSectionsTree(t *testing.T, filesystem *fs.MockFs) {
+	fs.Create(t, "/tmp/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/1/findme.txt", filesystem)
+	fs.Create(t, "/tmp/a/2/findme.txt", filesystem)
+	fs.Create(t, "/tmp/b/findme.txt", filesystem)
+	fs.Create(t, "/tmp/b/1/findme.txt", filesystem)
+	fs.Create(t, "/tmp/c/findme.txt", filesystem)
+}
+
+func TestDbSections(t *testing.T) {
+	filesystem := newFs()
+	createDbSectionsTree(t, filesystem)
+	finder := newFinderWithDbSections(t, filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		}, 2, 3)
+	finder.Shutdown()
+
+	_, sections := readDbSections(t, finder.DbPath, filesystem)
+	roots := []string{}
+	for root := range sections {
+		roots = append(roots, root)
+	}
+	// /tmp has too many directories and /tmp/c too few to have their own sections
+	fs.AssertSameResponse(t, roots, []string{"/", "/tmp/a", "/tmp/b"})
+}
+
+func TestDbSectionsLoadedLazily(t *testing.T) {
+	filesystem := newFs()
+	createDbSectionsTree(t, filesystem)
+	finder := newFinderWithDbSections(t, filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		}, 2, 3)
+	finder.Shutdown()
+	filesystem.ClearMetrics()
+
+	// only the section of the root directory is loaded at first
+	finder2 := finderWithSameParams(t, finder)
+	defer finder2.Shutdown()
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{"/tmp", "/tmp/c"})
+
+	filesystem.ClearMetrics()
+	fs.AssertSameResponse(t, finder2.FindNamedAt("/tmp/a", "findme.txt"),
+		[]string{"/tmp/a/1/findme.txt", "/tmp/a/2/findme.txt", "/tmp/a/findme.txt"})
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{"/tmp/a", "/tmp/a/1", "/tmp/a/2"})
+
+	filesystem.ClearMetrics()
+	fs.AssertSameResponse(t, finder2.FindNamedAt("/tmp", "findme.txt"),
+		[]string{
+			"/tmp/a/1/findme.txt",
+			"/tmp/a/2/findme.txt",
+			"/tmp/a/findme.txt",
+			"/tmp/b/1/findme.txt",
+			"/tmp/b/findme.txt",
+			"/tmp/c/findme.txt",
+			"/tmp/findme.txt",
+		})
+	fs.AssertSameStatCalls(t, filesystem.StatCalls, []string{"/tmp/b", "/tmp/b/1"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+}
+
+func TestDbSectionsRewrittenIncrementally(t *testing.T) {
+	filesystem := newFs()
+	createDbSectionsTree(t, filesystem)
+	finder := newFinderWithDbSections(t, filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		}, 2, 3)
+	finder.Shutdown()
+	_, sections := readDbSections(t, finder.DbPath, filesystem)
+
+	// modify the filesystem
+	filesystem.Clock.Tick()
+	fs.Create(t, "/tmp/b/2/findme.txt", filesystem)
+	filesystem.ClearMetrics()
+
+	// only load the section that changed
+	finder2 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder2.FindNamedAt("/tmp/b", "findme.txt"),
+		[]string{"/tmp/b/1/findme.txt", "/tmp/b/2/findme.txt", "/tmp/b/findme.txt"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{"/tmp/b", "/tmp/b/2"})
+	finder2.Shutdown()
+
+	// the sections that didn't change were copied
+	_, sections2 := readDbSections(t, finder.DbPath, filesystem)
+	for _, root := range []string{"/", "/tmp/a"} {
+		if sections2[root] == nil || !bytes.Equal(sections[root].data, sections2[root].data) {
+			t.Errorf("section %v was rewritten", root)
+		}
+	}
+	if sections2["/tmp/b"] == nil || bytes.Equal(sections["/tmp/b"].data, sections2["/tmp/b"].data) {
+		t.Errorf("section /tmp/b wasn't rewritten")
+	}
+
+	// the rewritten db is up to date
+	filesystem.ClearMetrics()
+	finder3 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder3.FindAll(),
+		[]string{
+			"/tmp/a/1/findme.txt",
+			"/tmp/a/2/findme.txt",
+			"/tmp/a/findme.txt",
+			"/tmp/b/1/findme.txt",
+			"/tmp/b/2/findme.txt",
+			"/tmp/b/findme.txt",
+			"/tmp/c/findme.txt",
+			"/tmp/findme.txt",
+		})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+	finder3.Shutdown()
+}
+
+func TestCorruptedDbSection(t *testing.T) {
+	filesystem := newFs()
+	createDbSectionsTree(t, filesystem)
+	finder := newFinderWithDbSections(t, filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		}, 2, 3)
+	finder.Shutdown()
+
+	// corrupt the data of a section without changing its length
+	db, sections := readDbSections(t, finder.DbPath, filesystem)
+	data := sections["/tmp/a"].data
+	data[len(data)-1] ^= 0xff
+	filesystem.WriteFile(finder.DbPath, db, 0777)
+	filesystem.ClearMetrics()
+
+	// the directories of the section are scanned again
+	finder2 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder2.FindNamedAt("/tmp/a", "findme.txt"),
+		[]string{"/tmp/a/1/findme.txt", "/tmp/a/2/findme.txt", "/tmp/a/findme.txt"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{"/tmp/a", "/tmp/a/1", "/tmp/a/2"})
+	finder2.Shutdown()
+
+	filesystem.ClearMetrics()
+	finder3 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder3.FindNamedAt("/tmp/a", "findme.txt"),
+		[]string{"/tmp/a/1/findme.txt", "/tmp/a/2/findme.txt", "/tmp/a/findme.txt"})
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+	finder3.Shutdown()
+}
+
+func TestMigrationFromDbV1(t *testing.T) {
+	filesystem := newFs()
+	createDbSectionsTree(t, filesystem)
+	finder := newFinder(t, filesystem,
+		CacheParams{
+			RootDirs:     []string{"/tmp"},
+			IncludeFiles: []string{"findme.txt"},
+		})
+	foundPaths := finder.FindAll()
+	finder.Shutdown()
+
+	// replace the db with a version 1 db
+	db, err := finder.serializeDbV1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filesystem.WriteFile(finder.DbPath, db, 0777)
+	filesystem.ClearMetrics()
+
+	// the version 1 db is used
+	finder2 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder2.FindAll(), foundPaths)
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+	finder2.Shutdown()
+
+	// and rewritten as a version 2 db
+	readDbSections(t, finder.DbPath, filesystem)
+	filesystem.ClearMetrics()
+	finder3 := finderWithSameParams(t, finder)
+	fs.AssertSameResponse(t, finder3.FindAll(), foundPaths)
+	fs.AssertSameReadDirCalls(t, filesystem.ReadDirCalls, []string{})
+	finder3.Shutdown()
+}
+
+// newBenchmarkFs returns a filesystem with 1111 directories under /src, each containing a
+// findme.txt and an ignoreme.txt
+func newBenchmarkFs(b *testing.B) *fs.MockFs {
+	filesystem := newFs()
+	filesystem.MkDirs("/finder")
+	dirs := []string{"/src"}
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			for k := 0; k < 10; k++ {
+				dirs = append(dirs, fmt.Sprintf("/src/%v/%v/%v", i, j, k))
+			}
+			dirs = append(dirs, fmt.Sprintf("/src/%v/%v", i, j))
+		}
+		dirs = append(dirs, fmt.Sprintf("/src/%v", i))
+	}
+	for _, dir := range dirs {
+		for _, name := range []string{"findme.txt", "ignoreme.txt"} {
+			if err := filesystem.MkDirs(dir); err != nil {
+				b.Fatal(err)
+			}
+			if err := filesystem.WriteFile(filepath.Join(dir, name), nil, 0666); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return filesystem
+}
+
+// newBenchmarkFinder creates a Finder for the files of newBenchmarkFs, which writes a db with a
+// section for each directory under /src
+func newBenchmarkFinder(b *testing.B, filesystem *fs.MockFs) *Finder {
+	f := newUnloaded(
+		CacheParams{
+			WorkingDirectory: "/cwd",
+			RootDirs:         []string{"/src"},
+			IncludeFiles:     []string{"findme.txt"},
+		},
+		filesystem, log.New(ioutil.Discard, "", 0), "/finder/finder-db", defaultNumThreads)
+	f.minDbSectionDirs = 100
+	f.maxDbSectionDirs = 200
+	if err := f.load(); err != nil {
+		b.Fatal(err)
+	}
+	return f
+}
+
+func benchmarkLoadDb(b *testing.B, rootPath string, serialize func(f *Finder) ([]byte, error)) {
+	filesystem := newBenchmarkFs(b)
+	f := newBenchmarkFinder(b, filesystem)
+	f.Shutdown()
+	db, err := serialize(f)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		filesystem.WriteFile(f.DbPath, db, 0777)
+		b.StartTimer()
+
+		f := newBenchmarkFinder(b, filesystem)
+		f.FindNamedAt(rootPath, "findme.txt")
+
+		b.StopTimer()
+		f.Shutdown()
+		b.StartTimer()
+	}
+}
+
+func BenchmarkLoadDbV1(b *testing.B) {
+	benchmarkLoadDb(b, "/src", (*Finder).serializeDbV1)
+}
+
+func BenchmarkLoadDbV2(b *testing.B) {
+	benchmarkLoadDb(b, "/src", (*Finder).serializeDbV2)
+}
+
+func BenchmarkLoadDbV2Subtree(b *testing.B) {
+	benchmarkLoadDb(b, "/src/0", (*Finder).serializeDbV2)
+}
+
+func BenchmarkSerializeDbV1(b *testing.B) {
+	f := newBenchmarkFinder(b, newBenchmarkFs(b))
+	f.Shutdown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.serializeDbV1(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeDbV2(b *testing.B) {
+	f := newBenchmarkFinder(b, newBenchmarkFs(b))
+	f.Shutdown()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.serializeDbV2(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSerializeDbV2Incremental(b *testing.B) {
+	filesystem := newBenchmarkFs(b)
+	f := newBenchmarkFinder(b, filesystem)
+	f.Shutdown()
+
+	// change one of the sections
+	filesystem.Clock.Tick()
+	filesystem.WriteFile("/src/0/findme2.txt", nil, 0666)
+	f = newBenchmarkFinder(b, filesystem)
+	f.FindAll()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := f.serializeDbV2(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	f.Shutdown()
+}
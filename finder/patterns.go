@@ -0,0 +1,232 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// This file provides queries that select the files under a directory by
+// matching their paths against globs and regular expressions. Like the other
+// queries, they only consider the files in the cache, so only the files whose
+// names are in CacheParams.IncludeFiles or CacheParams.IncludeSuffixes.
+
+// A Pattern matches the paths of files relative to the root directory of a
+// query, which use '/' as the separator.
+type Pattern interface {
+	// Match tells whether the path matches the pattern
+	Match(path string) bool
+
+	// mayMatchUnder tells whether the paths of any files under the directory
+	// could match the pattern, so that other directories aren't searched
+	mayMatchUnder(dir string) bool
+
+	String() string
+}
+
+// NewGlob returns a Pattern for a glob, whose '/'-separated elements are
+// matched against the elements of the path with path.Match, except that an
+// element "**" matches any number of elements, including none. For example,
+// "**/Android.bp" matches every Android.bp, and "device/*/*/BoardConfig.mk"
+// only matches two directories below device.
+func NewGlob(pattern string) (Pattern, error) {
+	if pattern == "" || strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("invalid glob %q: must be a relative path", pattern)
+	}
+	elements := strings.Split(pattern, "/")
+	for _, element := range elements {
+		if _, err := path.Match(element, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+		}
+	}
+	return glob{pattern, elements}, nil
+}
+
+type glob struct {
+	pattern  string
+	elements []string
+}
+
+func (g glob) Match(path string) bool {
+	return matchGlob(g.elements, strings.Split(path, "/"))
+}
+
+func (g glob) mayMatchUnder(dir string) bool {
+	return globMayMatchUnder(g.elements, strings.Split(dir, "/"))
+}
+
+func (g glob) String() string {
+	return g.pattern
+}
+
+func matchGlob(pattern []string, elements []string) bool {
+	if len(pattern) == 0 {
+		return len(elements) == 0
+	}
+	if pattern[0] == "**" {
+		return matchGlob(pattern[1:], elements) ||
+			(len(elements) > 0 && matchGlob(pattern, elements[1:]))
+	}
+	if len(elements) == 0 {
+		return false
+	}
+	matched, _ := path.Match(pattern[0], elements[0])
+	return matched && matchGlob(pattern[1:], elements[1:])
+}
+
+// globMayMatchUnder tells whether pattern could match a path below the
+// directory with the given elements
+func globMayMatchUnder(pattern []string, dir []string) bool {
+	if len(dir) == 0 {
+		return len(pattern) > 0
+	}
+	if len(pattern) == 0 {
+		return false
+	}
+	if pattern[0] == "**" {
+		return globMayMatchUnder(pattern[1:], dir) || globMayMatchUnder(pattern, dir[1:])
+	}
+	matched, _ := path.Match(pattern[0], dir[0])
+	return matched && globMayMatchUnder(pattern[1:], dir[1:])
+}
+
+// NewRegexp returns a Pattern for a regular expression, which must match the
+// whole path.
+func NewRegexp(expr string) (Pattern, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression %q: %v", expr, err)
+	}
+	return regexpPattern{expr, re}, nil
+}
+
+type regexpPattern struct {
+	expr string
+	re   *regexp.Regexp
+}
+
+func (r regexpPattern) Match(path string) bool {
+	return r.re.MatchString(path)
+}
+
+func (r regexpPattern) mayMatchUnder(dir string) bool {
+	return true
+}
+
+func (r regexpPattern) String() string {
+	return r.expr
+}
+
+// Patterns select the files whose paths match any of the Include patterns, or
+// every file if there are none, and none of the Exclude patterns. The
+// directories whose paths match any of the Exclude patterns aren't searched.
+type Patterns struct {
+	Include []Pattern
+	Exclude []Pattern
+}
+
+func (p Patterns) includes(path string) bool {
+	if len(p.Include) == 0 {
+		return true
+	}
+	return matchAny(p.Include, path)
+}
+
+func (p Patterns) searches(dir string) bool {
+	if matchAny(p.Exclude, dir) {
+		return false
+	}
+	if len(p.Include) == 0 {
+		return true
+	}
+	for _, pattern := range p.Include {
+		if pattern.mayMatchUnder(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []Pattern, path string) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindGlob searches under <rootPath> for every file whose path relative to
+// <rootPath> matches the glob <pattern> (see NewGlob)
+func (f *Finder) FindGlob(rootPath string, pattern string) ([]string, error) {
+	glob, err := NewGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return f.FindPatterns(rootPath, Patterns{Include: []Pattern{glob}}), nil
+}
+
+// FindRegexp searches under <rootPath> for every file whose path relative to
+// <rootPath> matches the regular expression <expr> (see NewRegexp)
+func (f *Finder) FindRegexp(rootPath string, expr string) ([]string, error) {
+	re, err := NewRegexp(expr)
+	if err != nil {
+		return nil, err
+	}
+	return f.FindPatterns(rootPath, Patterns{Include: []Pattern{re}}), nil
+}
+
+// FindPatterns searches under <rootPath> for every file selected by <patterns>
+// by its path relative to <rootPath>
+func (f *Finder) FindPatterns(rootPath string, patterns Patterns) []string {
+	root := rootPath
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(f.cacheMetadata.Config.WorkingDirectory, root)
+	}
+	root = filepath.Clean(root)
+
+	filter := func(entries DirEntries) (dirNames []string, fileNames []string) {
+		dir := relativePath(root, entries.Path)
+		for _, name := range entries.DirNames {
+			if patterns.searches(joinCleanPaths(dir, name)) {
+				dirNames = append(dirNames, name)
+			}
+		}
+		for _, name := range entries.FileNames {
+			path := joinCleanPaths(dir, name)
+			if patterns.includes(path) && !matchAny(patterns.Exclude, path) {
+				fileNames = append(fileNames, name)
+			}
+		}
+		return dirNames, fileNames
+	}
+	return f.FindMatching(rootPath, filter)
+}
+
+// relativePath returns the path of the directory <path> relative to its
+// ancestor <root>, both clean and absolute, or "" if they are the same
+func relativePath(root string, path string) string {
+	if path == root {
+		return ""
+	}
+	if root == "/" {
+		return path[1:]
+	}
+	return path[len(root)+1:]
+}
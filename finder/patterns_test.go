@@ -0,0 +1,89 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"path"
+	"testing"
+)
+
+func TestGlob(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		path    string
+		match   bool
+		// whether mayMatchUnder is true for the directory of path
+		mayMatchUnderDir bool
+	}{
+		{"Android.bp", "Android.bp", true, false},
+		{"Android.bp", "a/Android.bp", false, false},
+		{"**/Android.bp", "Android.bp", true, false},
+		{"**/Android.bp", "a/b/Android.bp", true, true},
+		{"**/Android.bp", "a/b/Android.mk", false, true},
+		{"a/**", "a/b/c.txt", true, true},
+		{"a/**", "b/c.txt", false, false},
+		{"a/**/c.txt", "a/c.txt", true, true},
+		{"a/**/c.txt", "a/b/b/c.txt", true, true},
+		{"*/*/*.mk", "device/google/board.mk", true, true},
+		{"*/*/*.mk", "device/board.mk", false, true},
+		{"*/*/*.mk", "device/google/x/board.mk", false, false},
+		{"device/**/AndroidProducts.mk", "vendor/x/AndroidProducts.mk", false, false},
+		{"a/[bc]/?.txt", "a/c/d.txt", true, true},
+	}
+	for _, testCase := range testCases {
+		glob, err := NewGlob(testCase.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if match := glob.Match(testCase.path); match != testCase.match {
+			t.Errorf("%q.Match(%q): expected %v, got %v", testCase.pattern, testCase.path, testCase.match, match)
+		}
+		dir := path.Dir(testCase.path)
+		if dir == "." {
+			continue
+		}
+		if may := glob.mayMatchUnder(dir); may != testCase.mayMatchUnderDir {
+			t.Errorf("%q.mayMatchUnder(%q): expected %v, got %v", testCase.pattern, dir, testCase.mayMatchUnderDir, may)
+		}
+	}
+}
+
+func TestInvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"", "/a/b", "a/[b"} {
+		if _, err := NewGlob(pattern); err == nil {
+			t.Errorf("expected an error for glob %q", pattern)
+		}
+	}
+	if _, err := NewRegexp("a(b"); err == nil {
+		t.Errorf("expected an error for an invalid regular expression")
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	re, err := NewRegexp(`.*\.(mk|bp)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, match := range map[string]bool{
+		"Android.bp":      true,
+		"a/b/Android.mk":  true,
+		"a/b/Android.bpx": false,
+		"x.bp/OWNERS":     false,
+	} {
+		if re.Match(path) != match {
+			t.Errorf("%q.Match(%q): expected %v", re, path, match)
+		}
+	}
+}
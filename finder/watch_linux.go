@@ -0,0 +1,160 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+// The changes to a directory that can change the node tree: entries being
+// added, removed or renamed, and changes to its own permissions. Changes to
+// the contents of files don't matter.
+const inotifyMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM |
+	syscall.IN_MOVED_TO | syscall.IN_ATTRIB | syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF |
+	syscall.IN_ONLYDIR | syscall.IN_DONT_FOLLOW
+
+// an inotifyWatcher watches every directory in the node tree of a Finder with
+// inotify
+type inotifyWatcher struct {
+	finder *Finder
+	fd     int
+
+	// the path of each watch descriptor, and the reverse
+	paths   map[int32]string
+	watches map[string]int32
+
+	buf [64 * 1024]byte
+}
+
+func newWatcher(f *Finder) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %v", err)
+	}
+	w := &inotifyWatcher{
+		finder:  f,
+		fd:      fd,
+		paths:   make(map[int32]string),
+		watches: make(map[string]int32),
+	}
+	if err := w.watch(f.knownDirs([]string{"/"})); err != nil {
+		w.close()
+		return nil, err
+	}
+	f.verbosef("Watching %v directories\n", len(w.watches))
+	return w, nil
+}
+
+// watch adds watches for the directories that aren't watched yet
+func (w *inotifyWatcher) watch(dirs []string) error {
+	for _, dir := range dirs {
+		if _, ok := w.watches[dir]; ok {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, inotifyMask)
+		if err == syscall.ENOSPC {
+			return fmt.Errorf("too many directories to watch, raise fs.inotify.max_user_watches above %v",
+				len(w.watches))
+		} else if err != nil {
+			// the directory was removed or became unreadable since it
+			// was listed, which its parent's watch will report
+			continue
+		}
+		wd32 := int32(wd)
+		// a directory that moved keeps its watch descriptor
+		if old, ok := w.paths[wd32]; ok {
+			delete(w.watches, old)
+		}
+		w.paths[wd32] = dir
+		w.watches[dir] = wd32
+	}
+	return nil
+}
+
+func (w *inotifyWatcher) forget(wd int32) {
+	if path, ok := w.paths[wd]; ok {
+		delete(w.watches, path)
+		delete(w.paths, wd)
+	}
+}
+
+func (w *inotifyWatcher) update() error {
+	changed := make(map[string]bool)
+	overflowed := false
+	for {
+		n, err := syscall.Read(w.fd, w.buf[:])
+		if err == syscall.EAGAIN || n == 0 {
+			break
+		} else if err == syscall.EINTR {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("reading inotify events: %v", err)
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&w.buf[offset]))
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				overflowed = true
+				continue
+			}
+			path, ok := w.paths[event.Wd]
+			if !ok {
+				continue
+			}
+			changed[path] = true
+			if event.Mask&syscall.IN_MOVE_SELF != 0 {
+				// the path of the directory is stale, it will be
+				// watched again at its new path if it is still in the
+				// tree
+				syscall.InotifyRmWatch(w.fd, uint32(event.Wd))
+				w.forget(event.Wd)
+			} else if event.Mask&syscall.IN_IGNORED != 0 {
+				w.forget(event.Wd)
+			}
+		}
+	}
+
+	var dirs []string
+	if overflowed {
+		// some changes were lost, check every directory
+		w.finder.verbosef("Too many filesystem changes, checking every directory\n")
+		dirs = w.finder.knownDirs([]string{"/"})
+	} else {
+		for dir := range changed {
+			dirs = append(dirs, dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return nil
+	}
+
+	for _, dir := range w.finder.invalidate(dirs) {
+		// a watch of a directory that moved out of the tree along with
+		// its parent
+		if wd, ok := w.watches[dir]; ok {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			w.forget(wd)
+		}
+	}
+	return w.watch(w.finder.knownDirs(dirs))
+}
+
+func (w *inotifyWatcher) close() error {
+	return syscall.Close(w.fd)
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package finder
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+func TestInotifyWatcher(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(path string) {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("src/findme.txt")
+	write("src/a/findme.txt")
+	write("src/b/c/findme.txt")
+
+	cacheParams := CacheParams{
+		WorkingDirectory: dir,
+		RootDirs:         []string{"src"},
+		IncludeFiles:     []string{"findme.txt"},
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	f, err := newImpl(cacheParams, fs.OsFs, logger, filepath.Join(dir, "finder.db"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Shutdown()
+
+	w, err := newWatcher(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.close()
+
+	check := func(want ...string) {
+		t.Helper()
+		if err := w.update(); err != nil {
+			t.Fatal(err)
+		}
+		fs.AssertSameResponse(t, f.FindNamedAt("src", "findme.txt"), want)
+	}
+	check("src/a/findme.txt", "src/b/c/findme.txt", "src/findme.txt")
+
+	write("src/a/new/findme.txt")
+	check("src/a/findme.txt", "src/a/new/findme.txt", "src/b/c/findme.txt", "src/findme.txt")
+
+	if err := os.Rename(filepath.Join(dir, "src/b"), filepath.Join(dir, "src/d")); err != nil {
+		t.Fatal(err)
+	}
+	check("src/a/findme.txt", "src/a/new/findme.txt", "src/d/c/findme.txt", "src/findme.txt")
+
+	// the moved directories are still watched at their new paths
+	write("src/d/c/e/findme.txt")
+	check("src/a/findme.txt", "src/a/new/findme.txt", "src/d/c/e/findme.txt", "src/d/c/findme.txt", "src/findme.txt")
+
+	if err := os.RemoveAll(filepath.Join(dir, "src/a")); err != nil {
+		t.Fatal(err)
+	}
+	check("src/d/c/e/findme.txt", "src/d/c/findme.txt", "src/findme.txt")
+}
@@ -74,6 +74,16 @@ func createCombinedBuildNinjaFile(ctx Context, config Config) {
 		}
 	}
 
+	// Ninja reloads the combined ninja file when any of the included files
+	// change.
+	explainInputs := newRebuildStepInputs()
+	explainInputs.addFiles(config.SoongNinjaFile())
+	if !config.SkipKatiNinja() && config.HasKatiSuffix() {
+		explainInputs.Values["kati suffix"] = config.KatiSuffix()
+		explainInputs.addFiles(config.KatiBuildNinjaFile(), config.KatiPackageNinjaFile())
+	}
+	finishExplainStep := config.explainer.beginStep("combined ninja", explainInputs, "", "")
+
 	file, err := os.Create(config.CombinedNinjaFile())
 	if err != nil {
 		ctx.Fatalln("Failed to create combined ninja file:", err)
@@ -83,6 +93,8 @@ func createCombinedBuildNinjaFile(ctx Context, config Config) {
 	if err := combinedBuildNinjaTemplate.Execute(file, config); err != nil {
 		ctx.Fatalln("Failed to write combined ninja file:", err)
 	}
+
+	finishExplainStep()
 }
 
 // These are bitmasks which can be used to check whether various flags are set e.g. whether to use Bazel.
@@ -105,40 +117,59 @@ const (
 	RunAllWithBazel = RunProductConfig | RunSoong | RunKati | RunKatiNinja | RunBazel
 )
 
-// checkProblematicFiles fails the build if existing Android.mk or CleanSpec.mk files are found at the root of the tree.
-func checkProblematicFiles(ctx Context) {
-	files := []string{"Android.mk", "CleanSpec.mk"}
-	for _, file := range files {
+// problematicFiles returns the files at the root of the tree that need to be
+// removed to build.
+func problematicFiles() []string {
+	var ret []string
+	for _, file := range []string{"Android.mk", "CleanSpec.mk"} {
 		if _, err := os.Stat(file); !os.IsNotExist(err) {
-			absolute := absPath(ctx, file)
-			ctx.Printf("Found %s in tree root. This file needs to be removed to build.\n", file)
-			ctx.Fatalf("    rm %s\n", absolute)
+			ret = append(ret, file)
 		}
 	}
+	return ret
 }
 
-// checkCaseSensitivity issues a warning if a case-insensitive file system is being used.
-func checkCaseSensitivity(ctx Context, config Config) {
-	outDir := config.OutDir()
-	lowerCase := filepath.Join(outDir, "casecheck.txt")
-	upperCase := filepath.Join(outDir, "CaseCheck.txt")
+// checkProblematicFiles fails the build if existing Android.mk or CleanSpec.mk files are found at the root of the tree.
+func checkProblematicFiles(ctx Context) {
+	for _, file := range problematicFiles() {
+		absolute := absPath(ctx, file)
+		ctx.Printf("Found %s in tree root. This file needs to be removed to build.\n", file)
+		ctx.Fatalf("    rm %s\n", absolute)
+	}
+}
+
+// isCaseSensitive returns whether the file system containing dir is
+// case-sensitive.
+func isCaseSensitive(dir string) (bool, error) {
+	lowerCase := filepath.Join(dir, "casecheck.txt")
+	upperCase := filepath.Join(dir, "CaseCheck.txt")
 	lowerData := "a"
 	upperData := "B"
 
 	if err := ioutil.WriteFile(lowerCase, []byte(lowerData), 0666); err != nil { // a+rw
-		ctx.Fatalln("Failed to check case sensitivity:", err)
+		return false, err
 	}
 
 	if err := ioutil.WriteFile(upperCase, []byte(upperData), 0666); err != nil { // a+rw
-		ctx.Fatalln("Failed to check case sensitivity:", err)
+		return false, err
 	}
 
 	res, err := ioutil.ReadFile(lowerCase)
+	if err != nil {
+		return false, err
+	}
+
+	return string(res) == lowerData, nil
+}
+
+// checkCaseSensitivity issues a warning if a case-insensitive file system is being used.
+func checkCaseSensitivity(ctx Context, config Config) {
+	caseSensitive, err := isCaseSensitive(config.OutDir())
 	if err != nil {
 		ctx.Fatalln("Failed to check case sensitivity:", err)
 	}
 
-	if string(res) != lowerData {
+	if !caseSensitive {
 		ctx.Println("************************************************************")
 		ctx.Println("You are building on a case-insensitive filesystem.")
 		ctx.Println("Please move your source tree to a case-sensitive filesystem.")
@@ -215,6 +246,9 @@ func Build(ctx Context, config Config) {
 
 	SetupPath(ctx, config)
 
+	config.explainer = newRebuildExplainer(ctx, config)
+	defer config.explainer.finish(ctx, config)
+
 	what := RunAll
 	if config.UseBazel() {
 		what = RunAllWithBazel
@@ -239,13 +273,7 @@ func Build(ctx Context, config Config) {
 		what = what &^ RunNinja
 	}
 
-	if config.StartGoma() {
-		startGoma(ctx, config)
-	}
-
-	if config.StartRBE() {
-		startRBE(ctx, config)
-	}
+	startRemoteBackends(ctx, config)
 
 	if what&RunProductConfig != 0 {
 		runMakeProductConfig(ctx, config)
@@ -296,6 +324,10 @@ func Build(ctx Context, config Config) {
 
 	distGzipFile(ctx, config, config.CombinedNinjaFile())
 
+	if config.AuditEnv() {
+		auditEnvironment(ctx, config)
+	}
+
 	if what&RunBuildTests != 0 {
 		testForDanglingRules(ctx, config)
 	}
@@ -51,6 +51,8 @@ type configImpl struct {
 	skipKatiNinja  bool
 	skipNinja      bool
 	skipSoongTests bool
+	explainRebuild bool
+	auditEnv       bool
 
 	// From the product config
 	katiArgs        []string
@@ -76,6 +78,15 @@ type configImpl struct {
 
 	// Set by multiproduct_kati
 	emptyNinjaFile bool
+
+	// Records the inputs of the regeneration steps, set by Build.
+	explainer *rebuildExplainer
+
+	// The value and source of each build setting, see profile.go.
+	settingSources map[string]settingSource
+
+	// The sandbox profile of each phase, see sandbox_profile.go.
+	sandboxProfiles map[string]SandboxProfile
 }
 
 const srcDirFileCheck = "build/soong/root.bp"
@@ -133,6 +144,21 @@ func NewConfig(ctx Context, args ...string) Config {
 
 	ret.totalRAM = detectTotalRAM(ctx)
 
+	// Fill in the settings that aren't in the environment from the build
+	// profiles before anything reads them.
+	profiles, required := buildProfileFiles(ret.environ)
+	if sources, err := loadBuildProfiles(ret.environ, profiles, required); err != nil {
+		ctx.Fatalln("Failed to load the build profiles:", err)
+	} else {
+		ret.settingSources = sources
+	}
+
+	if sandboxProfiles, err := loadSandboxProfiles(ret.environ); err != nil {
+		ctx.Fatalln("Failed to load the sandbox profiles:", err)
+	} else {
+		ret.sandboxProfiles = sandboxProfiles
+	}
+
 	ret.parseArgs(ctx, args)
 
 	// Make sure OUT_DIR is set appropriately
@@ -292,8 +318,8 @@ func NewConfig(ctx Context, args ...string) Config {
 
 	ret.environ.Set("BUILD_DATETIME_FILE", buildDateTimeFile)
 
-	if ret.UseRBE() {
-		for k, v := range getRBEVars(ctx, Config{ret}) {
+	for _, backend := range enabledRemoteBackends(Config{ret}) {
+		for k, v := range backend.Env(ctx, Config{ret}) {
 			ret.environ.Set(k, v)
 		}
 	}
@@ -581,6 +607,10 @@ func (c *configImpl) parseArgs(ctx Context, args []string) {
 			c.skipKatiNinja = true
 		} else if arg == "--skip-soong-tests" {
 			c.skipSoongTests = true
+		} else if arg == "--explain-rebuild" {
+			c.explainRebuild = true
+		} else if arg == "--audit-env" {
+			c.auditEnv = true
 		} else if len(arg) > 0 && arg[0] == '-' {
 			parseArgNum := func(def int) int {
 				if len(arg) > 2 {
@@ -806,6 +836,18 @@ func (c *configImpl) SetSkipNinja(v bool) {
 	c.skipNinja = v
 }
 
+// ExplainRebuild returns true if the reasons why the regeneration steps reran
+// and ninja rebuilt outputs should be reported.
+func (c *configImpl) ExplainRebuild() bool {
+	return c.explainRebuild
+}
+
+// AuditEnv returns true if the environment variables read by the build should
+// be audited, failing the build if ninja actions read untracked variables.
+func (c *configImpl) AuditEnv() bool {
+	return c.auditEnv
+}
+
 func (c *configImpl) SkipConfig() bool {
 	return c.skipConfig
 }
@@ -870,6 +912,27 @@ func (c *configImpl) HighmemParallel() int {
 	return parallel
 }
 
+// MemoryThrottleThreshold returns the available memory below which new heavy
+// actions are held back while ninja runs, or 0 if they are never held back.
+// Actions are only held back if NINJA_MIN_AVAILABLE_MEMORY_MB is set.
+func (c *configImpl) MemoryThrottleThreshold() uint64 {
+	if mb, ok := c.environ.GetInt("NINJA_MIN_AVAILABLE_MEMORY_MB"); ok && mb > 0 {
+		return uint64(mb) * 1024 * 1024
+	}
+	return 0
+}
+
+// SandboxProfile returns the sandbox profile selected for a phase of the
+// build.
+func (c *configImpl) SandboxProfile(phase string) SandboxProfile {
+	if profile, ok := c.sandboxProfiles[phase]; ok {
+		return profile
+	}
+	profile := builtinSandboxProfiles[defaultSandboxProfile]
+	profile.Name = defaultSandboxProfile
+	return profile
+}
+
 func (c *configImpl) TotalRAM() uint64 {
 	return c.totalRAM
 }
@@ -1022,8 +1085,10 @@ func (c *configImpl) rbeAuth() (string, string) {
 	return "RBE_use_application_default_credentials", "true"
 }
 
+// UseRemoteBuild returns whether a remote execution backend that runs actions
+// in the remote pools is enabled.
 func (c *configImpl) UseRemoteBuild() bool {
-	return c.UseGoma() || c.UseRBE()
+	return c.remoteBackendParallel() > 0
 }
 
 // RemoteParallel controls how many remote jobs (i.e., commands which contain
@@ -1036,7 +1101,19 @@ func (c *configImpl) RemoteParallel() int {
 	if i, ok := c.environ.GetInt("NINJA_REMOTE_NUM_JOBS"); ok {
 		return i
 	}
-	return 500
+	return c.remoteBackendParallel()
+}
+
+// remoteBackendParallel returns the largest number of remote jobs requested by
+// the enabled remote execution backends.
+func (c *configImpl) remoteBackendParallel() int {
+	parallel := 0
+	for _, backend := range enabledRemoteBackends(Config{c}) {
+		if p := backend.Parallel(Config{c}); p > parallel {
+			parallel = p
+		}
+	}
+	return parallel
 }
 
 func (c *configImpl) SetKatiArgs(args []string) {
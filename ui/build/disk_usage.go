@@ -0,0 +1,320 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/ui/metrics"
+	"android/soong/ui/status"
+)
+
+const (
+	diskUsageReportFile = "disk_usage.txt"
+	diskUsageJsonFile   = "disk_usage.json"
+
+	notInstalled  = "(not installed)"
+	unknownModule = "(unknown)"
+)
+
+// outDirUsage is the number of bytes used by the files in the output directory,
+// attributed to the modules that own them, their module classes and the
+// partitions they are installed to. Files that aren't owned by a known module
+// are attributed to their directory instead.
+type outDirUsage struct {
+	OutDir       string           `json:"out_dir"`
+	Total        int64            `json:"total"`
+	Partitions   map[string]int64 `json:"partitions"`
+	ModuleTypes  map[string]int64 `json:"module_types"`
+	Modules      map[string]int64 `json:"modules"`
+	Unattributed map[string]int64 `json:"unattributed"`
+}
+
+// moduleInfo is the part of module-info.json used to attribute files.
+type moduleInfo struct {
+	// The module that installs each installed file.
+	installed map[string]string
+	// The Make class of each module, for example SHARED_LIBRARIES.
+	classes map[string]string
+}
+
+// DiskUsageReport measures the output directory and writes a text and a JSON
+// report to the logs directory. The report is compared with previousFile, a
+// JSON report written by an earlier run, or with the last JSON report in the
+// logs directory if previousFile is empty. At most top modules and
+// directories are listed. It returns the text report.
+func DiskUsageReport(ctx Context, config Config, previousFile string, top int) string {
+	ctx.BeginTrace(metrics.RunSetupTool, "disk usage")
+	defer ctx.EndTrace()
+
+	// Find out/target/product/<DEVICE> without running the full product
+	// config.
+	vars, err := DumpMakeVars(ctx, config, nil, []string{"TARGET_DEVICE"})
+	if err != nil {
+		ctx.Fatalln("Error dumping make vars:", err)
+	}
+	config.SetTargetDevice(vars["TARGET_DEVICE"])
+
+	moduleInfoFile := filepath.Join(config.ProductOut(), "module-info.json")
+	info, err := readModuleInfo(moduleInfoFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			ctx.Fatalln("Failed to read module-info.json:", err)
+		}
+		ctx.Printf("%s doesn't exist, only intermediates will be attributed to modules", moduleInfoFile)
+		info = &moduleInfo{}
+	}
+
+	usage, err := measureDiskUsage(config.OutDir(), config.ProductOut(), config.hostOutRoot(), info)
+	if err != nil {
+		ctx.Fatalln("Failed to measure the output directory:", err)
+	}
+
+	jsonFile := filepath.Join(config.LogsDir(), diskUsageJsonFile)
+	if previousFile == "" {
+		previousFile = jsonFile
+	}
+	var previous *outDirUsage
+	if data, err := ioutil.ReadFile(previousFile); err == nil {
+		previous = &outDirUsage{}
+		if err := json.Unmarshal(data, previous); err != nil {
+			ctx.Fatalf("Failed to parse the previous report %s: %v", previousFile, err)
+		}
+	} else if !os.IsNotExist(err) || previousFile != jsonFile {
+		ctx.Fatalln("Failed to read the previous report:", err)
+	}
+
+	report := formatDiskUsage(usage, previous, previousFile, top)
+
+	if data, err := json.MarshalIndent(usage, "", "  "); err != nil {
+		ctx.Fatalln("Failed to marshal the disk usage:", err)
+	} else if err := ioutil.WriteFile(jsonFile, data, 0666); err != nil {
+		ctx.Fatalln("Failed to write the disk usage:", err)
+	}
+	reportFile := filepath.Join(config.LogsDir(), diskUsageReportFile)
+	if err := ioutil.WriteFile(reportFile, []byte(report), 0666); err != nil {
+		ctx.Fatalln("Failed to write the disk usage report:", err)
+	}
+	ctx.Verbosef("Wrote the disk usage report to %s and %s", reportFile, jsonFile)
+
+	return report
+}
+
+// readModuleInfo reads the installed files and the classes of the modules from
+// module-info.json.
+func readModuleInfo(file string) (*moduleInfo, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var modules map[string]struct {
+		Class     []string `json:"class"`
+		Installed []string `json:"installed"`
+	}
+	if err := json.Unmarshal(data, &modules); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+
+	ret := &moduleInfo{
+		installed: make(map[string]string),
+		classes:   make(map[string]string),
+	}
+	for name, module := range modules {
+		if len(module.Class) > 0 {
+			ret.classes[name] = module.Class[0]
+		}
+		for _, installed := range module.Installed {
+			ret.installed[installed] = name
+		}
+	}
+	return ret, nil
+}
+
+// measureDiskUsage walks outDir and attributes the size of every file in it.
+// Symlinks aren't followed.
+func measureDiskUsage(outDir, productOut, hostOutRoot string, info *moduleInfo) (*outDirUsage, error) {
+	usage := &outDirUsage{
+		OutDir:       outDir,
+		Partitions:   make(map[string]int64),
+		ModuleTypes:  make(map[string]int64),
+		Modules:      make(map[string]int64),
+		Unattributed: make(map[string]int64),
+	}
+
+	err := filepath.Walk(outDir, func(path string, fi os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			// The file was removed while walking the directory.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		size := fi.Size()
+		usage.Total += size
+
+		module, installed := info.installed[path]
+		if !installed {
+			module = status.OutputModule(path, nil)
+		}
+
+		partition := notInstalled
+		if installed {
+			partition = installPartition(path, productOut, hostOutRoot)
+		}
+		usage.Partitions[partition] += size
+
+		if module == "" {
+			usage.Unattributed[unattributedDir(path, outDir)] += size
+			return nil
+		}
+		usage.Modules[module] += size
+		usage.ModuleTypes[info.moduleClass(module)] += size
+		return nil
+	})
+	return usage, err
+}
+
+// moduleClass returns the Make class of a module. Soong modules are found by
+// the name part of their "//dir:name" labels.
+func (info *moduleInfo) moduleClass(module string) string {
+	if strings.HasPrefix(module, "//") {
+		module = module[strings.LastIndex(module, ":")+1:]
+	}
+	if class, ok := info.classes[module]; ok {
+		return class
+	}
+	return unknownModule
+}
+
+// installPartition returns the partition that an installed file is installed
+// to, for example "system" or "vendor". Host files are in the "host"
+// partition.
+func installPartition(path, productOut, hostOutRoot string) string {
+	if rel, err := filepath.Rel(hostOutRoot, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return "host"
+	}
+	if rel, err := filepath.Rel(productOut, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return strings.SplitN(rel, string(filepath.Separator), 2)[0]
+	}
+	return notInstalled
+}
+
+// unattributedDir returns the directory that files without an owner are
+// attributed to: their directory in outDir, up to three levels deep.
+func unattributedDir(path, outDir string) string {
+	rel, err := filepath.Rel(outDir, filepath.Dir(path))
+	if err != nil {
+		return filepath.Dir(path)
+	}
+	parts := strings.SplitN(rel, string(filepath.Separator), 4)
+	if len(parts) > 3 {
+		parts = parts[:3]
+	}
+	return filepath.Join(parts...)
+}
+
+// formatDiskUsage returns the text report of usage, compared with previous if
+// it isn't nil.
+func formatDiskUsage(usage, previous *outDirUsage, previousFile string, top int) string {
+	sb := &strings.Builder{}
+
+	fmt.Fprintf(sb, "Disk usage of %s: %s", usage.OutDir, humanizeBytes(usage.Total))
+	if previous != nil {
+		fmt.Fprintf(sb, " (%s since %s)", humanizeDelta(usage.Total-previous.Total), previousFile)
+	}
+	fmt.Fprintln(sb)
+
+	section := func(title, column string, cur, prev map[string]int64, top int) {
+		keys := sortedBySize(cur)
+		if top > 0 && len(keys) > top {
+			title = fmt.Sprintf("%s (largest %d of %d)", title, top, len(keys))
+			keys = keys[:top]
+		}
+		fmt.Fprintf(sb, "\n%s:\n", title)
+		if previous != nil {
+			fmt.Fprintf(sb, "%10s %11s  %s\n", "SIZE", "CHANGE", column)
+		} else {
+			fmt.Fprintf(sb, "%10s  %s\n", "SIZE", column)
+		}
+		for _, key := range keys {
+			if previous != nil {
+				fmt.Fprintf(sb, "%10s %11s  %s\n", humanizeBytes(cur[key]), humanizeDelta(cur[key]-prev[key]), key)
+			} else {
+				fmt.Fprintf(sb, "%10s  %s\n", humanizeBytes(cur[key]), key)
+			}
+		}
+	}
+
+	var prev outDirUsage
+	if previous != nil {
+		prev = *previous
+	}
+	section("By install partition", "PARTITION", usage.Partitions, prev.Partitions, 0)
+	section("By module class", "CLASS", usage.ModuleTypes, prev.ModuleTypes, 0)
+	section("By module", "MODULE", usage.Modules, prev.Modules, top)
+	section("Not attributed to a module", "DIRECTORY", usage.Unattributed, prev.Unattributed, top)
+
+	if previous != nil {
+		growth := make(map[string]int64)
+		for module, size := range usage.Modules {
+			if delta := size - prev.Modules[module]; delta > 0 {
+				growth[module] = delta
+			}
+		}
+		keys := sortedBySize(growth)
+		if top > 0 && len(keys) > top {
+			keys = keys[:top]
+		}
+		fmt.Fprintf(sb, "\nLargest growth by module:\n")
+		fmt.Fprintf(sb, "%11s %10s  %s\n", "CHANGE", "SIZE", "MODULE")
+		for _, key := range keys {
+			fmt.Fprintf(sb, "%11s %10s  %s\n", humanizeDelta(growth[key]), humanizeBytes(usage.Modules[key]), key)
+		}
+	}
+
+	return sb.String()
+}
+
+// sortedBySize returns the keys of sizes, largest first.
+func sortedBySize(sizes map[string]int64) []string {
+	keys := make([]string, 0, len(sizes))
+	for key := range sizes {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// humanizeDelta formats a change in size with its sign.
+func humanizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + humanizeBytes(-delta)
+	}
+	return "+" + humanizeBytes(delta)
+}
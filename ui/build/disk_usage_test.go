@@ -0,0 +1,150 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMeasureDiskUsage(t *testing.T) {
+	outDir := filepath.Join(t.TempDir(), "out")
+	productOut := filepath.Join(outDir, "target", "product", "generic")
+	hostOutRoot := filepath.Join(outDir, "host")
+
+	files := map[string]int{
+		"soong/.intermediates/bionic/libc/libc/android_arm64_armv8-a_shared/libc.so": 100,
+		"soong/.intermediates/bionic/libc/libc/android_arm64_armv8-a_static/libc.a":  200,
+		"soong/.intermediates/foo/libsoongonly/android_arm64_armv8-a/out.txt":        5,
+		"target/product/generic/obj/APPS/Foo_intermediates/Foo.apk":                  300,
+		"target/product/generic/system/lib64/libc.so":                                100,
+		"target/product/generic/vendor/app/Foo/Foo.apk":                              300,
+		"target/product/generic/symbols/system/lib64/libc.so":                        400,
+		"host/linux-x86/bin/aapt2":                                                   50,
+		"soong/build.ninja":                                                          20,
+	}
+	for file, size := range files {
+		path := filepath.Join(outDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := &moduleInfo{
+		installed: map[string]string{
+			filepath.Join(productOut, "system/lib64/libc.so"):   "libc",
+			filepath.Join(productOut, "vendor/app/Foo/Foo.apk"): "Foo",
+			filepath.Join(outDir, "host/linux-x86/bin/aapt2"):   "aapt2",
+		},
+		classes: map[string]string{
+			"libc":  "SHARED_LIBRARIES",
+			"Foo":   "APPS",
+			"aapt2": "EXECUTABLES",
+		},
+	}
+
+	got, err := measureDiskUsage(outDir, productOut, hostOutRoot, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &outDirUsage{
+		OutDir: outDir,
+		Total:  1475,
+		Partitions: map[string]int64{
+			"system":     100,
+			"vendor":     300,
+			"host":       50,
+			notInstalled: 1025,
+		},
+		ModuleTypes: map[string]int64{
+			"SHARED_LIBRARIES": 400,
+			"APPS":             600,
+			"EXECUTABLES":      50,
+			unknownModule:      5,
+		},
+		Modules: map[string]int64{
+			"//bionic/libc:libc": 300,
+			"libc":               100,
+			"Foo":                600,
+			"aapt2":              50,
+			"//foo:libsoongonly": 5,
+		},
+		Unattributed: map[string]int64{
+			"target/product/generic": 400,
+			"soong":                  20,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestFormatDiskUsage(t *testing.T) {
+	previous := &outDirUsage{
+		OutDir:       "out",
+		Total:        3072,
+		Partitions:   map[string]int64{"system": 3072},
+		ModuleTypes:  map[string]int64{"SHARED_LIBRARIES": 3072},
+		Modules:      map[string]int64{"libc": 2048, "libm": 1024},
+		Unattributed: map[string]int64{},
+	}
+	usage := &outDirUsage{
+		OutDir:       "out",
+		Total:        5120,
+		Partitions:   map[string]int64{"system": 4096, notInstalled: 1024},
+		ModuleTypes:  map[string]int64{"SHARED_LIBRARIES": 4096},
+		Modules:      map[string]int64{"libc": 3072, "libm": 512, "libdl": 512},
+		Unattributed: map[string]int64{"soong": 1024},
+	}
+
+	got := formatDiskUsage(usage, previous, "old.json", 2)
+	want := strings.Join([]string{
+		"Disk usage of out: 5.0 KiB (+2.0 KiB since old.json)",
+		"",
+		"By install partition:",
+		"      SIZE      CHANGE  PARTITION",
+		"   4.0 KiB    +1.0 KiB  system",
+		"   1.0 KiB    +1.0 KiB  (not installed)",
+		"",
+		"By module class:",
+		"      SIZE      CHANGE  CLASS",
+		"   4.0 KiB    +1.0 KiB  SHARED_LIBRARIES",
+		"",
+		"By module (largest 2 of 3):",
+		"      SIZE      CHANGE  MODULE",
+		"   3.0 KiB    +1.0 KiB  libc",
+		"     512 B      +512 B  libdl",
+		"",
+		"Not attributed to a module:",
+		"      SIZE      CHANGE  DIRECTORY",
+		"   1.0 KiB    +1.0 KiB  soong",
+		"",
+		"Largest growth by module:",
+		"     CHANGE       SIZE  MODULE",
+		"   +1.0 KiB    3.0 KiB  libc",
+		"     +512 B      512 B  libdl",
+		"",
+	}, "\n")
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
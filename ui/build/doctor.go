@@ -0,0 +1,273 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"android/soong/ui/metrics"
+)
+
+type doctorStatus int
+
+const (
+	doctorPass doctorStatus = iota
+	doctorWarn
+	doctorFail
+)
+
+func (s doctorStatus) String() string {
+	switch s {
+	case doctorPass:
+		return "PASS"
+	case doctorWarn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// doctorResult is the outcome of a check of the build environment, with a
+// hint on how to fix it if it didn't pass.
+type doctorResult struct {
+	status  doctorStatus
+	details string
+	hint    string
+}
+
+type doctorCheck struct {
+	name string
+	run  func(ctx Context, config Config) doctorResult
+}
+
+// The checks run by Doctor, in order.
+var doctorChecks = []doctorCheck{
+	{"source tree", checkSourceTreeDoctor},
+	{"files in tree root", checkProblematicFilesDoctor},
+	{"case sensitivity", checkCaseSensitivityDoctor},
+	{"RAM", checkRAMDoctor},
+	{"open files limit", checkOpenFilesDoctor},
+	{"free disk space", checkDiskSpaceDoctor},
+	{"out dir filesystem", checkFilesystemDoctor},
+	{"inotify watches", checkInotifyDoctor},
+	{"build lock", checkBuildLockDoctor},
+	{"sandbox", checkSandboxDoctor},
+}
+
+const (
+	// The free space in the output directory below which builds are likely
+	// to run out of disk.
+	minFreeDiskSpace  = 20 << 30
+	warnFreeDiskSpace = 100 << 30
+
+	// The inotify watches needed to watch the source tree.
+	minInotifyWatches = 524288
+)
+
+// Doctor runs checks of the build environment, prints a table of the results
+// with hints on how to fix the problems that were found, and fails if any of
+// the checks failed.
+func Doctor(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.RunSetupTool, "doctor")
+	defer ctx.EndTrace()
+
+	results := make([]doctorResult, len(doctorChecks))
+	failed := 0
+	for i, check := range doctorChecks {
+		results[i] = check.run(ctx, config)
+		if results[i].status == doctorFail {
+			failed++
+		}
+	}
+
+	fmt.Fprint(ctx.Writer, formatDoctorResults(doctorChecks, results))
+
+	if failed > 0 {
+		ctx.Fatalf("%d of %d checks failed", failed, len(doctorChecks))
+	}
+}
+
+// formatDoctorResults returns a table of the results of the checks.
+func formatDoctorResults(checks []doctorCheck, results []doctorResult) string {
+	width := 0
+	for _, check := range checks {
+		if len(check.name) > width {
+			width = len(check.name)
+		}
+	}
+
+	sb := &strings.Builder{}
+	for i, check := range checks {
+		r := results[i]
+		fmt.Fprintf(sb, "%-4s  %-*s  %s\n", r.status, width, check.name, r.details)
+		if r.status != doctorPass && r.hint != "" {
+			fmt.Fprintf(sb, "%-4s  %-*s  hint: %s\n", "", width, "", r.hint)
+		}
+	}
+	return sb.String()
+}
+
+func checkSourceTreeDoctor(ctx Context, config Config) doctorResult {
+	if _, err := os.Stat(srcDirFileCheck); err != nil {
+		return doctorResult{doctorFail, fmt.Sprintf("%s not found: %v", srcDirFileCheck, err),
+			"Run the build from the top of the source tree."}
+	}
+	return doctorResult{doctorPass, "running from the top of the source tree", ""}
+}
+
+func checkProblematicFilesDoctor(ctx Context, config Config) doctorResult {
+	files := problematicFiles()
+	if len(files) > 0 {
+		return doctorResult{doctorFail, "found " + strings.Join(files, ", "),
+			"Remove them with: rm " + strings.Join(files, " ")}
+	}
+	return doctorResult{doctorPass, "no Android.mk or CleanSpec.mk", ""}
+}
+
+func checkCaseSensitivityDoctor(ctx Context, config Config) doctorResult {
+	caseSensitive, err := isCaseSensitive(config.OutDir())
+	if err != nil {
+		return doctorResult{doctorFail, fmt.Sprintf("failed to check: %v", err),
+			"Make sure the output directory is writable."}
+	} else if !caseSensitive {
+		return doctorResult{doctorFail, "the output directory is on a case-insensitive filesystem",
+			"Move the source tree and output directory to a case-sensitive filesystem."}
+	}
+	return doctorResult{doctorPass, "case-sensitive", ""}
+}
+
+func checkRAMDoctor(ctx Context, config Config) doctorResult {
+	return ramResult(config.TotalRAM(), config.Parallel())
+}
+
+// ramResult uses the same limits as checkRAM.
+func ramResult(totalRAM uint64, parallel int) doctorResult {
+	if totalRAM == 0 {
+		return doctorResult{doctorWarn, "failed to detect the total RAM", ""}
+	}
+	ram := float32(totalRAM) / (1024 * 1024 * 1024)
+	details := fmt.Sprintf("%.3vGB for -j%d", ram, parallel)
+	if ram <= 16 {
+		return doctorResult{doctorWarn, details,
+			"Around 16GB is the minimum, some configurations may not build. Try a lower -j value if you run into segfaults."}
+	} else if ram <= float32(parallel) {
+		return doctorResult{doctorWarn, details,
+			"Less than 1GB per job. Try a lower -j value if you run into segfaults."}
+	}
+	return doctorResult{doctorPass, details, ""}
+}
+
+func checkOpenFilesDoctor(ctx Context, config Config) doctorResult {
+	var limits syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limits); err != nil {
+		return doctorResult{doctorWarn, fmt.Sprintf("failed to get the limit: %v", err), ""}
+	}
+	return openFilesResult(limits.Cur, limits.Max, config.UseRemoteBuild())
+}
+
+// openFilesResult checks the open files limits against what the remote
+// execution proxies require.
+func openFilesResult(soft, hard uint64, remote bool) doctorResult {
+	details := fmt.Sprintf("%d (hard limit %d)", soft, hard)
+	if soft >= rbeLeastNFiles {
+		return doctorResult{doctorPass, details, ""}
+	}
+	hint := fmt.Sprintf("Raise the limit with: ulimit -n %d", rbeLeastNFiles)
+	if hard < rbeLeastNFiles {
+		hint = fmt.Sprintf("Ask your administrator to raise the hard limit to at least %d in /etc/security/limits.conf.", rbeLeastNFiles)
+	}
+	if remote {
+		return doctorResult{doctorFail, details + ", remote builds need " + fmt.Sprint(rbeLeastNFiles), hint}
+	}
+	return doctorResult{doctorWarn, details, hint}
+}
+
+func checkDiskSpaceDoctor(ctx Context, config Config) doctorResult {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(config.OutDir(), &stat); err != nil {
+		return doctorResult{doctorWarn, fmt.Sprintf("failed to get the free space: %v", err), ""}
+	}
+	return diskSpaceResult(uint64(stat.Bavail) * uint64(stat.Bsize))
+}
+
+func diskSpaceResult(free uint64) doctorResult {
+	details := humanizeBytes(int64(free)) + " available in the output directory"
+	hint := "Free up space, for example with soong_ui --gc, or move the output directory with OUT_DIR."
+	if free < minFreeDiskSpace {
+		return doctorResult{doctorFail, details, hint}
+	} else if free < warnFreeDiskSpace {
+		return doctorResult{doctorWarn, details, hint}
+	}
+	return doctorResult{doctorPass, details, ""}
+}
+
+func checkFilesystemDoctor(ctx Context, config Config) doctorResult {
+	fsType, problem, err := filesystemType(config.OutDir())
+	if err != nil {
+		return doctorResult{doctorWarn, fmt.Sprintf("failed to get the filesystem type: %v", err), ""}
+	} else if problem != "" {
+		return doctorResult{doctorWarn, fsType + ": " + problem,
+			"Use a local filesystem such as ext4 for the output directory."}
+	}
+	return doctorResult{doctorPass, fsType, ""}
+}
+
+func checkInotifyDoctor(ctx Context, config Config) doctorResult {
+	limit, ok := inotifyWatchLimit()
+	if !ok {
+		return doctorResult{doctorPass, "not applicable", ""}
+	}
+	return inotifyResult(limit)
+}
+
+func inotifyResult(limit int) doctorResult {
+	details := fmt.Sprintf("%d watches", limit)
+	if limit < minInotifyWatches {
+		return doctorResult{doctorWarn, details + ", too few to watch the source tree",
+			fmt.Sprintf("Raise the limit with: sudo sysctl fs.inotify.max_user_watches=%d", minInotifyWatches)}
+	}
+	return doctorResult{doctorPass, details, ""}
+}
+
+func checkBuildLockDoctor(ctx Context, config Config) doctorResult {
+	lockPath := filepath.Join(config.OutDir(), ".lock")
+	f, err := os.OpenFile(lockPath, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return doctorResult{doctorPass, "no lock file", ""}
+	} else if err != nil {
+		return doctorResult{doctorFail, fmt.Sprintf("can't open %s: %v", lockPath, err),
+			"The lock file was probably created by a build run as another user, remove it with: rm " + absPath(ctx, lockPath)}
+	}
+	lock := fileLock{File: f}
+	defer lock.Unlock()
+
+	if err := lock.tryLock(); err != nil {
+		return doctorResult{doctorWarn, "another build is running in the output directory",
+			"Wait for it to finish, or use a different OUT_DIR."}
+	}
+	return doctorResult{doctorPass, "not held", ""}
+}
+
+func checkSandboxDoctor(ctx Context, config Config) doctorResult {
+	if working, failure := sandboxWorks(ctx, config); !working {
+		return doctorResult{doctorWarn, "builds run without a sandbox: " + failure,
+			"Sandboxing catches actions that read or write outside of the source and output directories, but isn't required."}
+	}
+	return doctorResult{doctorPass, "working", ""}
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"syscall"
+)
+
+// filesystemType returns the type of the filesystem containing path, and the
+// problem it causes for builds if there is a known one.
+func filesystemType(path string) (fsType string, problem string, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", "", err
+	}
+	var name []byte
+	for _, c := range stat.Fstypename {
+		if c == 0 {
+			break
+		}
+		name = append(name, byte(c))
+	}
+	fsType = string(name)
+	switch fsType {
+	case "nfs", "smbfs", "afpfs", "webdav":
+		problem = "network filesystems make builds much slower"
+	case "osxfuse", "macfuse":
+		problem = "FUSE filesystems make builds much slower"
+	}
+	return fsType, problem, nil
+}
+
+// inotifyWatchLimit returns false, as there is no inotify on Darwin.
+func inotifyWatchLimit() (int, bool) {
+	return 0, false
+}
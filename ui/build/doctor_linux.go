@@ -0,0 +1,70 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Filesystems identified by the magic numbers in statfs(2), and the problems
+// they cause for builds if they are known to.
+var filesystemMagic = map[int64]struct {
+	name    string
+	problem string
+}{
+	0x6969:     {"nfs", "network filesystems make builds much slower"},
+	0xff534d42: {"cifs", "network filesystems make builds much slower"},
+	0xfe534d42: {"smb2", "network filesystems make builds much slower"},
+	0x786f4256: {"vboxsf", "shared folders make builds much slower and don't support all file operations"},
+	0x65735546: {"fuse", "FUSE filesystems make builds much slower"},
+	0xf15f:     {"ecryptfs", "ecryptfs limits the length of file names, which breaks some modules"},
+	0x01021994: {"tmpfs", "outputs are lost on reboot and use RAM that the build needs"},
+	0xef53:     {"ext4", ""},
+	0x9123683e: {"btrfs", ""},
+	0x58465342: {"xfs", ""},
+	0x794c7630: {"overlayfs", ""},
+	0x2fc12fc1: {"zfs", ""},
+	0xf2f52010: {"f2fs", ""},
+}
+
+// filesystemType returns the type of the filesystem containing path, and the
+// problem it causes for builds if there is a known one.
+func filesystemType(path string) (fsType string, problem string, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return "", "", err
+	}
+	if fs, ok := filesystemMagic[int64(stat.Type)]; ok {
+		return fs.name, fs.problem, nil
+	}
+	return fmt.Sprintf("unknown (0x%x)", stat.Type), "", nil
+}
+
+// inotifyWatchLimit returns the maximum number of inotify watches per user.
+func inotifyWatchLimit() (int, bool) {
+	data, err := ioutil.ReadFile("/proc/sys/fs/inotify/max_user_watches")
+	if err != nil {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"testing"
+)
+
+func TestDoctorResults(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	tests := []struct {
+		name   string
+		result doctorResult
+		want   doctorStatus
+	}{
+		{"plenty of RAM", ramResult(64*gb, 32), doctorPass},
+		{"little RAM", ramResult(8*gb, 4), doctorWarn},
+		{"RAM per job", ramResult(32*gb, 64), doctorWarn},
+		{"unknown RAM", ramResult(0, 8), doctorWarn},
+		{"open files", openFilesResult(65536, 65536, true), doctorPass},
+		{"few open files", openFilesResult(1024, 65536, false), doctorWarn},
+		{"few open files remote", openFilesResult(1024, 65536, true), doctorFail},
+		{"disk space", diskSpaceResult(500 * gb), doctorPass},
+		{"low disk space", diskSpaceResult(50 * gb), doctorWarn},
+		{"out of disk space", diskSpaceResult(5 * gb), doctorFail},
+		{"inotify watches", inotifyResult(minInotifyWatches), doctorPass},
+		{"few inotify watches", inotifyResult(8192), doctorWarn},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.result.status != tt.want {
+				t.Errorf("want %s, got %s: %s", tt.want, tt.result.status, tt.result.details)
+			}
+			if tt.result.status != doctorPass && tt.result.hint == "" && tt.result.details != "failed to detect the total RAM" {
+				t.Errorf("want a hint for %s", tt.result.details)
+			}
+		})
+	}
+}
+
+func TestOpenFilesHardLimit(t *testing.T) {
+	if g := openFilesResult(1024, 1024, true).hint; g == openFilesResult(1024, 65536, true).hint {
+		t.Errorf("want a different hint when the hard limit is too low, got %q", g)
+	}
+}
+
+func TestFormatDoctorResults(t *testing.T) {
+	checks := []doctorCheck{{name: "short"}, {name: "longer name"}, {name: "last"}}
+	results := []doctorResult{
+		{doctorPass, "ok", "unused hint"},
+		{doctorWarn, "hmm", "do this"},
+		{doctorFail, "broken", ""},
+	}
+	want := "" +
+		"PASS  short        ok\n" +
+		"WARN  longer name  hmm\n" +
+		"                   hint: do this\n" +
+		"FAIL  last         broken\n"
+	if g := formatDoctorResults(checks, results); g != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, g)
+	}
+}
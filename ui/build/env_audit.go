@@ -0,0 +1,331 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements the --audit-env mode, which reports the environment
+// variables read by each phase of the build and fails the build if ninja
+// actions read variables that can change their outputs without causing them
+// to be rebuilt.
+//
+// soong_build and Kati rerun when the variables they read change, so they are
+// tracked. Product config is evaluated by dumpvars on every build, but Kati
+// doesn't record the variables it reads in that mode, so it is listed as not
+// audited. Ninja doesn't track the
+// environment at all, so every variable that is passed to ninja and
+// referenced by an action is untracked, unless soong_ui sets it to a stable
+// value.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"android/soong/shared"
+	"android/soong/ui/metrics"
+)
+
+const envAuditFile = "env_audit.json"
+
+// Environment variables passed to ninja that soong_ui sets to stable values.
+var stableNinjaEnvVars = []string{
+	"DIST_DIR",
+	"OUT_DIR",
+	"PATH",
+	"PWD",
+	"SHELL",
+	"TMPDIR",
+}
+
+// envAuditPhase is the list of environment variables read by a phase of the
+// build.
+type envAuditPhase struct {
+	Name string `json:"name"`
+	// How the phase reacts to changes of the environment.
+	Tracking string `json:"tracking"`
+	// The variables read by the phase and their values, if known.
+	Read map[string]string `json:"read,omitempty"`
+}
+
+// untrackedEnvVar is an environment variable read by ninja actions.
+type untrackedEnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// The number of references to the variable in the ninja files.
+	References int `json:"references"`
+	// Why the variable is passed to ninja: "allowlist", "ALLOW_NINJA_ENV"
+	// or "BUILD_BROKEN_NINJA_USES_ENV_VARS".
+	Source string `json:"source"`
+	// Whether the product acknowledged the variable with
+	// BUILD_BROKEN_NINJA_USES_ENV_VARS, these don't fail the audit.
+	Acknowledged bool `json:"acknowledged"`
+}
+
+type envAuditReport struct {
+	Phases []*envAuditPhase `json:"phases"`
+	// The phases whose variables aren't known, and why.
+	NotAudited map[string]string  `json:"not_audited"`
+	Untracked  []*untrackedEnvVar `json:"untracked"`
+}
+
+// auditEnvironment writes the environment audit report to the logs directory,
+// and fails the build if ninja actions read untracked variables that weren't
+// acknowledged by the product. It needs to run after the combined ninja file
+// was written.
+func auditEnvironment(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.RunSetupTool, "env audit")
+	defer ctx.EndTrace()
+
+	report := &envAuditReport{
+		NotAudited: map[string]string{
+			"dumpvars": "product config is evaluated on every build, but Kati doesn't record the variables it reads when dumping variables",
+		},
+	}
+
+	if usedEnv, err := shared.EnvFromFile(filepath.Join(config.SoongOutDir(), usedEnvFile)); err == nil {
+		report.Phases = append(report.Phases, &envAuditPhase{
+			Name:     "soong_build",
+			Tracking: "soong_build reruns when these variables change",
+			Read:     usedEnv,
+		})
+	} else if !os.IsNotExist(err) {
+		ctx.Verbosef("Failed to read the environment used by soong_build: %v", err)
+	}
+
+	if config.HasKatiSuffix() {
+		for _, suffix := range []string{katiBuildSuffix, katiPackageSuffix} {
+			stamp := filepath.Join(config.OutDir(), ".kati_stamp"+config.KatiSuffix()+suffix)
+			katiEnv, err := readKatiStampEnv(stamp)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					ctx.Verbosef("Failed to read the environment used by Kati from %s: %v", stamp, err)
+				}
+				continue
+			}
+			report.Phases = append(report.Phases, &envAuditPhase{
+				Name:     "kati" + suffix,
+				Tracking: "Kati regenerates its ninja file when these variables change",
+				Read:     katiEnv,
+			})
+		}
+	}
+
+	references, err := ninjaEnvReferences(config.CombinedNinjaFile())
+	if err != nil {
+		ctx.Fatalln("Failed to read the ninja files for the environment audit:", err)
+	}
+
+	ninjaPhase := &envAuditPhase{
+		Name:     "ninja",
+		Tracking: "ninja doesn't rebuild actions when the environment changes",
+		Read:     make(map[string]string),
+	}
+	report.Phases = append(report.Phases, ninjaPhase)
+	report.Untracked = untrackedNinjaEnvVars(config, references)
+	for _, v := range report.Untracked {
+		ninjaPhase.Read[v.Name] = v.Value
+	}
+
+	reportFile := filepath.Join(config.LogsDir(), envAuditFile)
+	if data, err := json.MarshalIndent(report, "", "  "); err != nil {
+		ctx.Fatalln("Failed to marshal the environment audit:", err)
+	} else if err := ioutil.WriteFile(reportFile, data, 0666); err != nil {
+		ctx.Fatalln("Failed to write the environment audit:", err)
+	}
+
+	var failures []string
+	for _, v := range report.Untracked {
+		if !v.Acknowledged {
+			failures = append(failures, fmt.Sprintf("  %s (%d references, passed to ninja by the %s)",
+				v.Name, v.References, v.Source))
+		}
+	}
+	if len(failures) > 0 {
+		ctx.Printf("These environment variables are read by ninja actions, but changing them doesn't rebuild the actions:\n%s",
+			strings.Join(failures, "\n"))
+		ctx.Fatalf("Environment audit failed, see %s. Pass the values on the command lines of the actions instead.", reportFile)
+	}
+	ctx.Verbosef("Environment audit passed, see %s", reportFile)
+}
+
+// untrackedNinjaEnvVars returns the referenced environment variables that are
+// passed to ninja and not set to stable values by soong_ui.
+func untrackedNinjaEnvVars(config Config, references map[string]int) []*untrackedEnvVar {
+	env := config.Environment().Copy()
+	allowAll := env.IsEnvTrue("ALLOW_NINJA_ENV")
+	if !allowAll {
+		env.Allow(ninjaAllowedEnvVars(config)...)
+	}
+
+	var ret []*untrackedEnvVar
+	for name, count := range references {
+		value, ok := env.Get(name)
+		if !ok || inList(name, stableNinjaEnvVars) {
+			continue
+		}
+		v := &untrackedEnvVar{
+			Name:       name,
+			Value:      value,
+			References: count,
+			Source:     "allowlist",
+		}
+		if inList(name, config.BuildBrokenNinjaUsesEnvVars()) {
+			v.Source = "BUILD_BROKEN_NINJA_USES_ENV_VARS"
+			v.Acknowledged = true
+		} else if allowAll && !inList(name, ninjaEnvAllowlist) {
+			v.Source = "ALLOW_NINJA_ENV"
+		}
+		ret = append(ret, v)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Name < ret[j].Name })
+	return ret
+}
+
+// Matches shell variable references in ninja files, where "$" is escaped as
+// "$$".
+var ninjaShellVarRe = regexp.MustCompile(`\$\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// Matches the ninja statements that read other ninja files.
+var ninjaIncludeRe = regexp.MustCompile(`^(?:include|subninja)\s+(\S+)\s*$`)
+
+// ninjaEnvReferences returns the number of references to each shell variable
+// in a ninja file and the files it includes.
+func ninjaEnvReferences(ninjaFile string) (map[string]int, error) {
+	ret := make(map[string]int)
+	seen := make(map[string]bool)
+	files := []string{ninjaFile}
+	for len(files) > 0 {
+		file := files[0]
+		files = files[1:]
+		if seen[file] {
+			continue
+		}
+		seen[file] = true
+
+		included, err := scanNinjaFile(file, ret)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, included...)
+	}
+	return ret, nil
+}
+
+// scanNinjaFile adds the shell variable references in a ninja file to refs,
+// and returns the ninja files it includes. Included files with variables in
+// their paths are skipped.
+func scanNinjaFile(file string, refs map[string]int) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var included []string
+	r := bufio.NewReaderSize(f, 1024*1024)
+	for {
+		line, err := r.ReadBytes('\n')
+		if bytes.Contains(line, []byte("$$")) {
+			for _, match := range ninjaShellVarRe.FindAllSubmatch(line, -1) {
+				refs[string(match[1])]++
+			}
+		} else if match := ninjaIncludeRe.FindSubmatch(line); match != nil && !bytes.Contains(match[1], []byte("$")) {
+			included = append(included, string(match[1]))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return included, nil
+}
+
+// readKatiStampEnv returns the environment variables read by Kati from the
+// stamp file it uses to decide whether to regenerate its ninja file. The
+// stamp starts with the generation time, the files read and the undefined
+// variables used, followed by the environment variables and their values.
+func readKatiStampEnv(stamp string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(stamp)
+	if err != nil {
+		return nil, err
+	}
+
+	r := bytes.NewReader(data)
+	readInt := func() (int, error) {
+		var v int32
+		err := binary.Read(r, binary.LittleEndian, &v)
+		if err == nil && v < 0 {
+			err = fmt.Errorf("negative length %d", v)
+		}
+		return int(v), err
+	}
+	readString := func() (string, error) {
+		n, err := readInt()
+		if err != nil {
+			return "", err
+		}
+		if n > r.Len() {
+			return "", io.ErrUnexpectedEOF
+		}
+		buf := make([]byte, n)
+		_, err = io.ReadFull(r, buf)
+		return string(buf), err
+	}
+	skipStrings := func() error {
+		n, err := readInt()
+		for i := 0; i < n && err == nil; i++ {
+			_, err = readString()
+		}
+		return err
+	}
+
+	var genTime float64
+	if err := binary.Read(r, binary.LittleEndian, &genTime); err != nil {
+		return nil, fmt.Errorf("%s: %v", stamp, err)
+	}
+	// The files read, then the undefined variables used.
+	for i := 0; i < 2; i++ {
+		if err := skipStrings(); err != nil {
+			return nil, fmt.Errorf("%s: %v", stamp, err)
+		}
+	}
+
+	n, err := readInt()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", stamp, err)
+	}
+	ret := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		name, err := readString()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", stamp, err)
+		}
+		value, err := readString()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", stamp, err)
+		}
+		ret[name] = value
+	}
+	return ret, nil
+}
@@ -0,0 +1,152 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeKatiStamp(t *testing.T, file string, files, undefined []string, env [][2]string) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	writeInt := func(v int) { binary.Write(buf, binary.LittleEndian, int32(v)) }
+	writeString := func(s string) {
+		writeInt(len(s))
+		buf.WriteString(s)
+	}
+
+	binary.Write(buf, binary.LittleEndian, float64(1234.5))
+	writeInt(len(files))
+	for _, f := range files {
+		writeString(f)
+	}
+	writeInt(len(undefined))
+	for _, u := range undefined {
+		writeString(u)
+	}
+	writeInt(len(env))
+	for _, e := range env {
+		writeString(e[0])
+		writeString(e[1])
+	}
+	// The globs and shell commands that follow aren't read.
+	writeInt(0)
+	writeInt(0)
+
+	if err := ioutil.WriteFile(file, buf.Bytes(), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadKatiStampEnv(t *testing.T) {
+	dir := t.TempDir()
+	stamp := filepath.Join(dir, ".kati_stamp-aosp_arm")
+	writeKatiStamp(t, stamp,
+		[]string{"prebuilts/build-tools/linux-x86/bin/ckati", "build/make/core/main.mk"},
+		[]string{"UNDEFINED_VAR"},
+		[][2]string{{"TARGET_PRODUCT", "aosp_arm"}, {"USE_CCACHE", ""}})
+
+	env, err := readKatiStampEnv(stamp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"TARGET_PRODUCT": "aosp_arm", "USE_CCACHE": ""}
+	if !reflect.DeepEqual(env, want) {
+		t.Errorf("want %v, got %v", want, env)
+	}
+
+	// Truncated stamps are reported as errors.
+	data, _ := ioutil.ReadFile(stamp)
+	if err := ioutil.WriteFile(stamp, data[:len(data)-20], 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readKatiStampEnv(stamp); err == nil {
+		t.Errorf("expected an error for a truncated stamp")
+	}
+}
+
+func TestNinjaEnvReferences(t *testing.T) {
+	dir := t.TempDir()
+	combined := filepath.Join(dir, "combined.ninja")
+	soong := filepath.Join(dir, "build.ninja")
+
+	if err := ioutil.WriteFile(combined, []byte("builddir = out\n"+
+		"subninja "+soong+"\n"+
+		"include $builddir/ignored.ninja\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(soong, []byte("rule r\n"+
+		"    command = echo $$HOME $${USER} $in > $out && for f in $$files; do echo $$f; done\n"+
+		"build out/a: r in/a\n"+
+		"    description = $$HOME\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := ninjaEnvReferences(combined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{"HOME": 2, "USER": 1, "files": 1, "f": 1}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("want %v, got %v", want, refs)
+	}
+}
+
+func TestUntrackedNinjaEnvVars(t *testing.T) {
+	refs := map[string]int{
+		"HOME":           2,
+		"PATH":           10,
+		"BUILD_NUMBER":   1,
+		"MY_BROKEN_VAR":  3,
+		"NOT_IN_THE_ENV": 1,
+	}
+
+	env := Environment([]string{
+		"HOME=/home/user",
+		"PATH=/bin",
+		"BUILD_NUMBER=1234",
+		"MY_BROKEN_VAR=1",
+	})
+	config := Config{&configImpl{
+		environ:            &env,
+		brokenNinjaEnvVars: []string{"MY_BROKEN_VAR"},
+	}}
+
+	got := untrackedNinjaEnvVars(config, refs)
+	want := []*untrackedEnvVar{
+		{Name: "HOME", Value: "/home/user", References: 2, Source: "allowlist"},
+		{Name: "MY_BROKEN_VAR", Value: "1", References: 3, Source: "BUILD_BROKEN_NINJA_USES_ENV_VARS", Acknowledged: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	env.Set("ALLOW_NINJA_ENV", "true")
+	got = untrackedNinjaEnvVars(config, refs)
+	want = []*untrackedEnvVar{
+		{Name: "BUILD_NUMBER", Value: "1234", References: 1, Source: "ALLOW_NINJA_ENV"},
+		{Name: "HOME", Value: "/home/user", References: 2, Source: "allowlist"},
+		{Name: "MY_BROKEN_VAR", Value: "1", References: 3, Source: "BUILD_BROKEN_NINJA_USES_ENV_VARS", Acknowledged: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("with ALLOW_NINJA_ENV: want %+v, got %+v", want, got)
+	}
+}
@@ -37,6 +37,17 @@ type Cmd struct {
 	name   string
 
 	started time.Time
+
+	// Stops sampling the resource usage of the process, set while the
+	// process is running.
+	stopSampling func()
+
+	// If set, called with each line of output by RunAndStreamOrFatal. Lines
+	// for which it returns false aren't printed.
+	outputFilter func(line string) bool
+
+	// If set, called with the PID of the process once it has started.
+	onStart func(pid int)
 }
 
 func Command(ctx Context, config Config, name string, executable string, args ...string) *Cmd {
@@ -82,7 +93,21 @@ func (c *Cmd) report() {
 
 func (c *Cmd) Start() error {
 	c.prepare()
-	return c.Cmd.Start()
+	if err := c.Cmd.Start(); err != nil {
+		return err
+	}
+
+	// Long running processes like kati and ninja are started with Start
+	// rather than Run, so sample their resource usage until Wait. This
+	// includes their descendants, such as the soong_build started by the
+	// bootstrap ninja.
+	if c.ctx.Metrics != nil {
+		c.stopSampling = c.ctx.Metrics.EventTracer.StartSampling(c.name, c.Process.Pid, c.ctx.Tracer)
+	}
+	if c.onStart != nil {
+		c.onStart(c.Process.Pid)
+	}
+	return nil
 }
 
 func (c *Cmd) Run() error {
@@ -108,6 +133,10 @@ func (c *Cmd) CombinedOutput() ([]byte, error) {
 
 func (c *Cmd) Wait() error {
 	err := c.Cmd.Wait()
+	if c.stopSampling != nil {
+		c.stopSampling()
+		c.stopSampling = nil
+	}
 	c.report()
 	return err
 }
@@ -190,7 +219,10 @@ func (c *Cmd) RunAndStreamOrFatal() {
 		// Attempt to read whole lines, but write partial lines that are too long to fit in the buffer or hit EOF
 		line, err := buf.ReadString('\n')
 		if line != "" {
-			st.Print(strings.TrimSuffix(line, "\n"))
+			line = strings.TrimSuffix(line, "\n")
+			if c.outputFilter == nil || c.outputFilter(line) {
+				st.Print(line)
+			}
 		} else if err == io.EOF {
 			break
 		} else if err != nil {
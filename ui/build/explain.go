@@ -0,0 +1,403 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements the --explain-rebuild mode. Every build records the
+// inputs of the regeneration steps (soong_build, Kati and the combined ninja
+// file) in the out directory. When explaining, the inputs are compared with
+// the ones recorded by the previous build to report why each step reran, and
+// ninja's "-d explain" output is summarized per module.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"android/soong/ui/status"
+)
+
+const (
+	rebuildInputsFile      = ".rebuild_inputs.json"
+	rebuildExplanationFile = "rebuild_explanation.txt"
+
+	ninjaExplainPrefix = "ninja explain: "
+
+	// The number of modules printed to the terminal, the explanation file has
+	// all of them.
+	explainMaxModules = 10
+)
+
+// rebuildStepInputs are the inputs of a regeneration step that cause it to
+// rerun when they change.
+type rebuildStepInputs struct {
+	Values map[string]string `json:"values,omitempty"`
+	Env    map[string]string `json:"env,omitempty"`
+	// The modification times of input files in nanoseconds, 0 if missing.
+	Files map[string]int64 `json:"files,omitempty"`
+}
+
+func newRebuildStepInputs() *rebuildStepInputs {
+	return &rebuildStepInputs{
+		Values: make(map[string]string),
+		Env:    make(map[string]string),
+		Files:  make(map[string]int64),
+	}
+}
+
+// addFiles adds input files. Their modification times are read once the step
+// finished, to include inputs that are rebuilt by the step itself like the
+// soong_build binary.
+func (i *rebuildStepInputs) addFiles(files ...string) {
+	for _, file := range files {
+		i.Files[file] = 0
+	}
+}
+
+// changes returns a description of each input that differs from the ones in
+// prev.
+func (i *rebuildStepInputs) changes(prev *rebuildStepInputs) []string {
+	var ret []string
+	for _, k := range sortedKeys(i.Values) {
+		if old, ok := prev.Values[k]; ok && old != i.Values[k] {
+			ret = append(ret, fmt.Sprintf("%s changed from %q to %q", k, old, i.Values[k]))
+		}
+	}
+	for _, k := range sortedKeys(i.Env) {
+		if old, ok := prev.Env[k]; ok && old != i.Env[k] {
+			ret = append(ret, fmt.Sprintf("environment variable %s changed from %q to %q", k, old, i.Env[k]))
+		}
+	}
+	var files []string
+	for file := range i.Files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		old, ok := prev.Files[file]
+		switch cur := i.Files[file]; {
+		case !ok || old == cur:
+		case old == 0:
+			ret = append(ret, file+" was created")
+		case cur == 0:
+			ret = append(ret, file+" was removed")
+		default:
+			ret = append(ret, file+" was modified")
+		}
+	}
+	return ret
+}
+
+type rebuildStep struct {
+	name    string
+	reasons []string
+	// The "ninja explain" lines printed while running the step.
+	explanations []string
+}
+
+// rebuildExplainer records the inputs of the regeneration steps, and explains
+// the ones that reran if enabled.
+type rebuildExplainer struct {
+	enabled  bool
+	filename string
+
+	prev    map[string]*rebuildStepInputs
+	current map[string]*rebuildStepInputs
+
+	steps []*rebuildStep
+}
+
+func newRebuildExplainer(ctx Context, config Config) *rebuildExplainer {
+	e := &rebuildExplainer{
+		enabled:  config.ExplainRebuild(),
+		filename: filepath.Join(config.OutDir(), rebuildInputsFile),
+		prev:     make(map[string]*rebuildStepInputs),
+		current:  make(map[string]*rebuildStepInputs),
+	}
+
+	if data, err := ioutil.ReadFile(e.filename); err == nil {
+		if err := json.Unmarshal(data, &e.prev); err != nil {
+			ctx.Verbosef("Failed to parse %s: %v", e.filename, err)
+			e.prev = make(map[string]*rebuildStepInputs)
+		}
+	}
+
+	return e
+}
+
+// step returns the step with the given name, adding it if needed.
+func (e *rebuildExplainer) step(name string) *rebuildStep {
+	for _, step := range e.steps {
+		if step.name == name {
+			return step
+		}
+	}
+	step := &rebuildStep{name: name}
+	e.steps = append(e.steps, step)
+	return step
+}
+
+// beginStep records the inputs of a step before it runs. The returned function
+// needs to be called once the step finished successfully, the step is
+// considered to have rerun if output was modified. If output is empty, the
+// step is considered to have rerun if any of its inputs changed. otherReason
+// explains a rerun if none of the recorded inputs changed.
+func (e *rebuildExplainer) beginStep(name string, inputs *rebuildStepInputs, output, otherReason string) func() {
+	if e == nil {
+		return func() {}
+	}
+
+	step := e.step(name)
+	outputTime := modTime(output)
+
+	return func() {
+		for file := range inputs.Files {
+			inputs.Files[file] = modTime(file)
+		}
+		e.current[name] = inputs
+
+		prev, ok := e.prev[name]
+		if !ok {
+			if output == "" || modTime(output) != outputTime {
+				step.reasons = []string{"no inputs were recorded by the previous build"}
+			}
+			return
+		}
+
+		reasons := inputs.changes(prev)
+		if output == "" {
+			step.reasons = reasons
+			return
+		}
+		if modTime(output) == outputTime {
+			return
+		}
+		if len(reasons) == 0 {
+			reasons = []string{otherReason}
+		}
+		step.reasons = reasons
+	}
+}
+
+// ninjaExplainFilter returns a function for Cmd.outputFilter that collects the
+// "ninja explain" lines of a ninja run, or nil if not explaining.
+func (e *rebuildExplainer) ninjaExplainFilter(name string) func(string) bool {
+	if e == nil || !e.enabled {
+		return nil
+	}
+
+	step := e.step(name)
+
+	return func(line string) bool {
+		if strings.HasPrefix(line, ninjaExplainPrefix) {
+			step.explanations = append(step.explanations, strings.TrimPrefix(line, ninjaExplainPrefix))
+			return false
+		}
+		return true
+	}
+}
+
+// finish writes the inputs of the steps that ran for the next build, and the
+// explanation of this build if enabled.
+func (e *rebuildExplainer) finish(ctx Context, config Config) {
+	if e == nil {
+		return
+	}
+
+	for name, inputs := range e.current {
+		e.prev[name] = inputs
+	}
+	if data, err := json.Marshal(e.prev); err != nil {
+		ctx.Verbosef("Failed to marshal rebuild inputs: %v", err)
+	} else if err := ioutil.WriteFile(e.filename, data, 0666); err != nil {
+		ctx.Verbosef("Failed to write %s: %v", e.filename, err)
+	}
+
+	if !e.enabled {
+		return
+	}
+
+	installed, _ := status.ReadModuleInfoInstalled(filepath.Join(config.ProductOut(), "module-info.json"))
+
+	explanationFile := filepath.Join(config.LogsDir(), rebuildExplanationFile)
+	f, err := os.Create(explanationFile)
+	if err != nil {
+		ctx.Println("Failed to write rebuild explanation:", err)
+		return
+	}
+	defer f.Close()
+
+	var summary strings.Builder
+	for _, step := range e.steps {
+		e.writeStep(&summary, step, installed, explainMaxModules)
+		e.writeStep(f, step, installed, -1)
+	}
+	if summary.Len() == 0 {
+		summary.WriteString("Nothing was regenerated or rebuilt.\n")
+	}
+	summary.WriteString("See " + explanationFile + " for details.")
+	ctx.Println(summary.String())
+}
+
+// writeStep writes the explanation of a step, with at most maxModules modules
+// if it isn't negative.
+func (e *rebuildExplainer) writeStep(w io.Writer, step *rebuildStep, installed map[string]string, maxModules int) {
+	if len(step.reasons) > 0 {
+		fmt.Fprintf(w, "%s reran because:\n", step.name)
+		for _, reason := range step.reasons {
+			fmt.Fprintf(w, "  %s\n", reason)
+		}
+	}
+
+	if len(step.explanations) == 0 {
+		return
+	}
+	modules := summarizeNinjaExplanations(step.explanations, installed)
+	outputs := 0
+	for _, module := range modules {
+		outputs += module.outputs
+	}
+	fmt.Fprintf(w, "%s rebuilt %d outputs in %d modules:\n", step.name, outputs, len(modules))
+	for i, module := range modules {
+		if maxModules >= 0 && i >= maxModules {
+			fmt.Fprintf(w, "  ... and %d more modules\n", len(modules)-i)
+			break
+		}
+		fmt.Fprintf(w, "  %s: %d outputs\n", module.name, module.outputs)
+		for _, cause := range module.causes() {
+			fmt.Fprintf(w, "    %s\n", cause)
+		}
+	}
+}
+
+// Matches the "ninja explain" lines that name the cause of a rebuild. Outputs
+// that are "dirty" are rebuilt because one of their inputs is rebuilt, so
+// they are only counted.
+var (
+	ninjaExplainOlderRe   = regexp.MustCompile(`^(?:output|restat of output|recorded mtime of) (\S+) older than most recent input (\S+)`)
+	ninjaExplainMissingRe = regexp.MustCompile(`^output (\S+) (?:of phony edge with no inputs )?doesn't exist`)
+	ninjaExplainCommandRe = regexp.MustCompile(`^command line changed for (\S+)`)
+	ninjaExplainDepsRe    = regexp.MustCompile(`^deps for '(\S+)' are missing`)
+	ninjaExplainDirtyRe   = regexp.MustCompile(`^(\S+) is dirty`)
+)
+
+type explainedModule struct {
+	name    string
+	outputs int
+
+	// The root causes of the rebuilds, mapped to the number of outputs.
+	causeCounts map[string]int
+	seen        map[string]bool
+}
+
+// causes returns the causes of the rebuilds of the module, most common first.
+func (m *explainedModule) causes() []string {
+	causes := make([]string, 0, len(m.causeCounts))
+	for cause := range m.causeCounts {
+		causes = append(causes, cause)
+	}
+	sort.Slice(causes, func(i, j int) bool {
+		if m.causeCounts[causes[i]] != m.causeCounts[causes[j]] {
+			return m.causeCounts[causes[i]] > m.causeCounts[causes[j]]
+		}
+		return causes[i] < causes[j]
+	})
+	for i, cause := range causes {
+		causes[i] = fmt.Sprintf("%s (%d)", cause, m.causeCounts[cause])
+	}
+	return causes
+}
+
+// summarizeNinjaExplanations groups the outputs in "ninja explain" lines by
+// module, sorted by the number of outputs rebuilt.
+func summarizeNinjaExplanations(lines []string, installed map[string]string) []*explainedModule {
+	modules := make(map[string]*explainedModule)
+	for _, line := range lines {
+		var output, cause string
+		if match := ninjaExplainOlderRe.FindStringSubmatch(line); match != nil {
+			output, cause = match[1], "input "+match[2]+" is newer"
+		} else if match := ninjaExplainMissingRe.FindStringSubmatch(line); match != nil {
+			output, cause = match[1], "output is missing"
+		} else if match := ninjaExplainCommandRe.FindStringSubmatch(line); match != nil {
+			output, cause = match[1], "command line changed"
+		} else if match := ninjaExplainDepsRe.FindStringSubmatch(line); match != nil {
+			output, cause = match[1], "dependency information is missing"
+		} else if match := ninjaExplainDirtyRe.FindStringSubmatch(line); match != nil {
+			output = match[1]
+		} else {
+			continue
+		}
+
+		name := status.OutputModule(output, installed)
+		if name == "" {
+			name = "(no module)"
+		}
+		module := modules[name]
+		if module == nil {
+			module = &explainedModule{
+				name:        name,
+				causeCounts: make(map[string]int),
+				seen:        make(map[string]bool),
+			}
+			modules[name] = module
+		}
+		if !module.seen[output] {
+			module.seen[output] = true
+			module.outputs++
+		}
+		if cause != "" {
+			module.causeCounts[cause]++
+		}
+	}
+
+	ret := make([]*explainedModule, 0, len(modules))
+	for _, module := range modules {
+		ret = append(ret, module)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].outputs != ret[j].outputs {
+			return ret[i].outputs > ret[j].outputs
+		}
+		return ret[i].name < ret[j].name
+	})
+	return ret
+}
+
+// modTime returns the modification time of a file in nanoseconds, or 0 if it
+// doesn't exist.
+func modTime(file string) int64 {
+	if file == "" {
+		return 0
+	}
+	fi, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return fi.ModTime().UnixNano()
+}
+
+func sortedKeys(m map[string]string) []string {
+	ret := make([]string, 0, len(m))
+	for k := range m {
+		ret = append(ret, k)
+	}
+	sort.Strings(ret)
+	return ret
+}
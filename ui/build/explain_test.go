@@ -0,0 +1,139 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRebuildExplainer(t *testing.T) {
+	ctx := testContext()
+	outDir := t.TempDir()
+
+	env := Environment([]string{"OUT_DIR=" + outDir})
+	config := Config{&configImpl{
+		environ:        &env,
+		explainRebuild: true,
+	}}
+
+	output := filepath.Join(outDir, "soong", "build.ninja")
+	input := filepath.Join(outDir, "soong_build")
+	if err := os.MkdirAll(filepath.Dir(output), 0777); err != nil {
+		t.Fatal(err)
+	}
+	writeFile := func(file string, mtime time.Time) {
+		t.Helper()
+		if err := ioutil.WriteFile(file, nil, 0666); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	writeFile(input, start)
+
+	build := func(build int, foo string, rerun bool) *rebuildExplainer {
+		e := newRebuildExplainer(ctx, config)
+
+		inputs := newRebuildStepInputs()
+		inputs.Env["FOO"] = foo
+		inputs.Values["suffix"] = "-a"
+		inputs.addFiles(input)
+		finish := e.beginStep("soong_build", inputs, output, "something else changed")
+		if rerun {
+			writeFile(output, start.Add(time.Duration(build)*time.Minute))
+		}
+		finish()
+
+		filter := e.ninjaExplainFilter("ninja")
+		for _, line := range []string{
+			"ninja explain: output out/soong/.intermediates/foo/libfoo/android_arm64_armv8-a_shared/libfoo.o older than most recent input foo/foo.c (1 vs 2)",
+			"ninja explain: out/soong/.intermediates/foo/libfoo/android_arm64_armv8-a_shared/libfoo.o is dirty",
+			"ninja explain: out/soong/.intermediates/foo/libfoo/android_arm64_armv8-a_shared/libfoo.so is dirty",
+			"ninja explain: command line changed for out/target/product/generic/obj/JAVA_LIBRARIES/bar_intermediates/classes.jar",
+		} {
+			if filter(line) {
+				t.Errorf("expected %q to be filtered", line)
+			}
+		}
+		if !filter("FAILED: foo") {
+			t.Errorf("expected other lines not to be filtered")
+		}
+
+		e.finish(ctx, config)
+		return e
+	}
+
+	e := build(1, "a", true)
+	if g, w := e.steps[0].reasons, []string{"no inputs were recorded by the previous build"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("first build: want reasons %q, got %q", w, g)
+	}
+
+	// The step didn't rerun, even though an input changed.
+	e = build(2, "b", false)
+	if g := e.steps[0].reasons; len(g) != 0 {
+		t.Errorf("second build: want no reasons, got %q", g)
+	}
+
+	writeFile(input, start.Add(time.Minute))
+	e = build(3, "c", true)
+	w := []string{
+		`environment variable FOO changed from "b" to "c"`,
+		input + " was modified",
+	}
+	if g := e.steps[0].reasons; !reflect.DeepEqual(g, w) {
+		t.Errorf("third build: want reasons %q, got %q", w, g)
+	}
+
+	e = build(4, "c", true)
+	if g, w := e.steps[0].reasons, []string{"something else changed"}; !reflect.DeepEqual(g, w) {
+		t.Errorf("fourth build: want reasons %q, got %q", w, g)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(outDir, rebuildExplanationFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantExplanation := strings.Join([]string{
+		"soong_build reran because:",
+		"  something else changed",
+		"ninja rebuilt 3 outputs in 2 modules:",
+		"  //foo:libfoo: 2 outputs",
+		"    input foo/foo.c is newer (1)",
+		"  bar: 1 outputs",
+		"    command line changed (1)",
+		"",
+	}, "\n")
+	if g := string(data); g != wantExplanation {
+		t.Errorf("want explanation:\n%s\ngot:\n%s", wantExplanation, g)
+	}
+}
+
+func TestRebuildExplainerNil(t *testing.T) {
+	// Steps run outside of Build don't have an explainer.
+	var e *rebuildExplainer
+	e.beginStep("kati", newRebuildStepInputs(), "", "")()
+	if e.ninjaExplainFilter("ninja") != nil {
+		t.Errorf("expected no filter without an explainer")
+	}
+	e.finish(testContext(), Config{&configImpl{}})
+}
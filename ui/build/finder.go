@@ -33,11 +33,64 @@ import (
 // under `$OUT_DIR/.module_paths`. This directory can also be dist'd.
 
 // NewSourceFinder returns a new Finder configured to search for source files.
+// The queries are answered by the finder daemon started by RunFinderDaemon,
+// if there is one.
 // Callers of NewSourceFinder should call <f.Shutdown()> when done
 func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
 	ctx.BeginTrace(metrics.RunSetupTool, "find modules")
 	defer ctx.EndTrace()
 
+	dumpDir := config.FileListDir()
+	f, err := finder.NewWithDaemon(sourceFinderParams(ctx), fs.OsFs, logger.New(ioutil.Discard),
+		filepath.Join(dumpDir, "files.db"), finderSocket(config))
+	if err != nil {
+		ctx.Fatalf("Could not create module-finder: %v", err)
+	}
+	return f
+}
+
+// RunFinderDaemon runs a finder daemon that keeps the list of source files up
+// to date and answers the queries of the Finders returned by NewSourceFinder,
+// until ctx is done.
+func RunFinderDaemon(ctx Context, config Config) {
+	dumpDir := config.FileListDir()
+	if err := os.MkdirAll(dumpDir, 0777); err != nil {
+		ctx.Fatalf("Could not create %v: %v", dumpDir, err)
+	}
+
+	f, err := finder.New(sourceFinderParams(ctx), fs.OsFs, logger.New(ioutil.Discard),
+		filepath.Join(dumpDir, "files.db"))
+	if err != nil {
+		ctx.Fatalf("Could not create module-finder: %v", err)
+	}
+	defer f.Shutdown()
+
+	daemon, err := finder.NewDaemon(f, finderSocket(config))
+	if err != nil {
+		ctx.Fatalf("Could not start finder daemon: %v", err)
+	}
+	closed := make(chan bool)
+	go func() {
+		<-ctx.Done()
+		daemon.Close()
+		close(closed)
+	}()
+
+	ctx.Printf("Finder daemon listening on %v", finderSocket(config))
+	if err := daemon.Serve(); err != nil {
+		ctx.Fatalf("Finder daemon failed: %v", err)
+	}
+	// wait for Close to write the cache
+	<-closed
+}
+
+// finderSocket returns the path of the unix socket of the finder daemon
+func finderSocket(config Config) string {
+	return filepath.Join(config.FileListDir(), "finder.sock")
+}
+
+// sourceFinderParams returns the cache params of the Finder for source files
+func sourceFinderParams(ctx Context) finder.CacheParams {
 	// Set up the working directory for the Finder.
 	dir, err := os.Getwd()
 	if err != nil {
@@ -89,13 +142,7 @@ func NewSourceFinder(ctx Context, config Config) (f *finder.Finder) {
 		// Bazel Starlark configuration files.
 		IncludeSuffixes: []string{".bzl"},
 	}
-	dumpDir := config.FileListDir()
-	f, err = finder.New(cacheParams, filesystem, logger.New(ioutil.Discard),
-		filepath.Join(dumpDir, "files.db"))
-	if err != nil {
-		ctx.Fatalf("Could not create module-finder: %v", err)
-	}
-	return f
+	return cacheParams
 }
 
 // Finds the list of Bazel-related files (BUILD, WORKSPACE and Starlark) in the tree.
@@ -117,8 +164,17 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 	dumpDir := config.FileListDir()
 	os.MkdirAll(dumpDir, 0777)
 
+	// The queries have no results if the finder daemon exits and the cache
+	// can't be loaded instead, so don't write empty lists.
+	checkFinder := func() {
+		if err := f.Err(); err != nil {
+			ctx.Fatalf("Could not find source files: %v", err)
+		}
+	}
+
 	// Stop searching a subdirectory recursively after finding an Android.mk.
 	androidMks := f.FindFirstNamedAt(".", "Android.mk")
+	checkFinder()
 	err := dumpListToFile(ctx, config, androidMks, filepath.Join(dumpDir, "Android.mk.list"))
 	if err != nil {
 		ctx.Fatalf("Could not export module list: %v", err)
@@ -126,6 +182,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 
 	// Stop searching a subdirectory recursively after finding a CleanSpec.mk.
 	cleanSpecs := f.FindFirstNamedAt(".", "CleanSpec.mk")
+	checkFinder()
 	err = dumpListToFile(ctx, config, cleanSpecs, filepath.Join(dumpDir, "CleanSpec.mk.list"))
 	if err != nil {
 		ctx.Fatalf("Could not export module list: %v", err)
@@ -135,6 +192,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 	androidProductsMks := f.FindNamedAt("device", "AndroidProducts.mk")
 	androidProductsMks = append(androidProductsMks, f.FindNamedAt("vendor", "AndroidProducts.mk")...)
 	androidProductsMks = append(androidProductsMks, f.FindNamedAt("product", "AndroidProducts.mk")...)
+	checkFinder()
 	err = dumpListToFile(ctx, config, androidProductsMks, filepath.Join(dumpDir, "AndroidProducts.mk.list"))
 	if err != nil {
 		ctx.Fatalf("Could not export product list: %v", err)
@@ -142,6 +200,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 
 	// Recursively look for all Bazel related files.
 	bazelFiles := f.FindMatching(".", findBazelFiles)
+	checkFinder()
 	err = dumpListToFile(ctx, config, bazelFiles, filepath.Join(dumpDir, "bazel.list"))
 	if err != nil {
 		ctx.Fatalf("Could not export bazel BUILD list: %v", err)
@@ -149,6 +208,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 
 	// Recursively look for all OWNERS files.
 	owners := f.FindNamedAt(".", "OWNERS")
+	checkFinder()
 	err = dumpListToFile(ctx, config, owners, filepath.Join(dumpDir, "OWNERS.list"))
 	if err != nil {
 		ctx.Fatalf("Could not find OWNERS: %v", err)
@@ -156,6 +216,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 
 	// Recursively look for all TEST_MAPPING files.
 	testMappings := f.FindNamedAt(".", "TEST_MAPPING")
+	checkFinder()
 	err = dumpListToFile(ctx, config, testMappings, filepath.Join(dumpDir, "TEST_MAPPING.list"))
 	if err != nil {
 		ctx.Fatalf("Could not find TEST_MAPPING: %v", err)
@@ -165,6 +226,7 @@ func FindSources(ctx Context, config Config, f *finder.Finder) {
 	androidBps := f.FindNamedAt(".", "Android.bp")
 	// The files are named "Blueprints" only in the build/blueprint directory.
 	androidBps = append(androidBps, f.FindNamedAt("build/blueprint", "Blueprints")...)
+	checkFinder()
 	if len(androidBps) == 0 {
 		ctx.Fatalf("No Android.bp found")
 	}
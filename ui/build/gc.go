@@ -0,0 +1,285 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/ui/metrics"
+)
+
+// gcEntry is a directory under the intermediates trees that doesn't contain
+// any file of the current build.
+type gcEntry struct {
+	path string
+	size int64
+}
+
+// GarbageCollect removes the directories under the intermediates trees that
+// don't contain any output or input of the actions in the combined ninja file
+// of the current product. Modules that were renamed or deleted leave their
+// intermediates behind, and nothing else removes them short of a clean build.
+//
+// Only whole directories are removed: files next to live outputs may be
+// undeclared side outputs of their actions. The intermediates of other
+// products built in the same output directory are removed too, so switching
+// back to them rebuilds those intermediates. With dryRun, the directories are
+// listed without removing them.
+func GarbageCollect(ctx Context, config Config, dryRun bool) {
+	ctx.BeginTrace(metrics.RunSetupTool, "gc")
+	defer ctx.EndTrace()
+
+	// Make sure that no build is writing to the output directory.
+	buildLock := BecomeSingletonOrFail(ctx, config)
+	defer buildLock.Unlock()
+
+	SetupPath(ctx, config)
+	runMakeProductConfig(ctx, config)
+	genKatiSuffix(ctx, config)
+
+	if _, err := os.Stat(config.CombinedNinjaFile()); err != nil {
+		ctx.Fatalf("Can't find the ninja file of the last build of %s, run a build first: %v",
+			config.TargetProduct(), err)
+	}
+
+	nodes := ninjaNodes(ctx, config)
+	if len(nodes) == 0 {
+		ctx.Fatalln("No outputs found in", config.CombinedNinjaFile())
+	}
+
+	garbage, err := findGarbage(gcRoots(config), nodes, gcExcludes(config))
+	if err != nil {
+		ctx.Fatalln("Failed to find unreachable intermediates:", err)
+	}
+
+	var total int64
+	for _, entry := range garbage {
+		total += entry.size
+		if dryRun {
+			ctx.Printf("Would remove %s (%s)", entry.path, humanizeBytes(entry.size))
+			continue
+		}
+		ctx.Verbosef("Removing %s (%s)", entry.path, humanizeBytes(entry.size))
+		removeGlobs(ctx, globEscaper.Replace(entry.path))
+		cleanEmptyDirs(ctx, filepath.Dir(entry.path))
+	}
+
+	if dryRun {
+		ctx.Printf("Would remove %d unreachable directories, freeing %s", len(garbage), humanizeBytes(total))
+	} else {
+		ctx.Printf("Removed %d unreachable directories, freed %s", len(garbage), humanizeBytes(total))
+	}
+}
+
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`)
+
+// ninjaNodes returns the files in the graph of the combined ninja file: the
+// outputs of all of the actions, and the files they read that no action
+// writes. The latter include files written by Kati or soong_build while
+// generating the ninja files, which nothing would recreate if they were
+// removed.
+func ninjaNodes(ctx Context, config Config) map[string]bool {
+	nodes := make(map[string]bool)
+	// "targets all" prints "<output>: <rule>" lines, and "targets rule"
+	// without a rule name prints the inputs that aren't outputs.
+	runNinjaTargetsTool(ctx, config, nodes, true, "all")
+	runNinjaTargetsTool(ctx, config, nodes, false, "rule")
+	return nodes
+}
+
+func runNinjaTargetsTool(ctx Context, config Config, nodes map[string]bool, withRule bool, args ...string) {
+	executable := config.PrebuiltBuildTool("ninja")
+	cmd := Command(ctx, config, "ninja", executable,
+		append([]string{"-f", config.CombinedNinjaFile(), "-t", "targets"}, args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		ctx.Fatal(err)
+	}
+
+	cmd.StartOrFatal()
+
+	if err := parseNinjaTargets(stdout, withRule, nodes); err != nil {
+		ctx.Fatalln("Failed to read the ninja targets:", err)
+	}
+
+	cmd.WaitOrFatal()
+}
+
+// parseNinjaTargets adds the paths printed by "ninja -t targets" to nodes,
+// one per line, followed by ": <rule>" if withRule is set.
+func parseNinjaTargets(r io.Reader, withRule bool, nodes map[string]bool) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if withRule {
+			i := strings.LastIndex(line, ": ")
+			if i <= 0 {
+				continue
+			}
+			line = line[:i]
+		}
+		if line != "" {
+			nodes[filepath.Clean(line)] = true
+		}
+	}
+	return scanner.Err()
+}
+
+// gcRoots returns the intermediates directories that are garbage collected.
+func gcRoots(config Config) []string {
+	roots := []string{
+		filepath.Join(config.SoongOutDir(), ".intermediates"),
+		filepath.Join(config.hostOutRoot(), "common", "obj"),
+		filepath.Join(config.OutDir(), "target", "common", "obj"),
+	}
+
+	globs := []string{
+		filepath.Join(config.ProductOut(), "obj*"),
+		filepath.Join(config.HostOut(), "obj*"),
+	}
+	if hostCrossOut := config.hostCrossOut(); hostCrossOut != "" {
+		globs = append(globs, filepath.Join(hostCrossOut, "obj*"))
+	}
+	for _, glob := range globs {
+		// The only possible error is ErrBadPattern.
+		matches, _ := filepath.Glob(glob)
+		roots = append(roots, matches...)
+	}
+
+	return roots
+}
+
+// gcExcludes returns the directories that are never garbage collected, even
+// if they are under an intermediates directory.
+func gcExcludes(config Config) []string {
+	excludes := []string{
+		config.TempDir(),
+		filepath.Join(config.SoongOutDir(), ".bootstrap"),
+		filepath.Join(config.SoongOutDir(), ".minibootstrap"),
+		config.DistDir(),
+		config.RealDistDir(),
+	}
+	// The logs are written to the top of the output directory outside of
+	// dist builds, where they are never under an intermediates directory.
+	if logsDir := config.LogsDir(); logsDir != config.OutDir() {
+		excludes = append(excludes, logsDir)
+	}
+	return excludes
+}
+
+// findGarbage returns the directories under roots that contain neither a
+// ninja node nor an excluded directory, sorted by path. Roots that don't
+// contain any nodes are skipped rather than removed entirely, in case they
+// belong to a different build configuration.
+func findGarbage(roots []string, nodes map[string]bool, excludes []string) ([]gcEntry, error) {
+	keep := make(map[string]bool, len(nodes)+len(excludes))
+	for node := range nodes {
+		keep[node] = true
+	}
+	for _, exclude := range excludes {
+		if exclude != "" {
+			keep[filepath.Clean(exclude)] = true
+		}
+	}
+
+	// Every directory containing something to keep is live.
+	live := make(map[string]bool)
+	for path := range keep {
+		for dir := filepath.Dir(path); !live[dir]; dir = filepath.Dir(dir) {
+			live[dir] = true
+			if dir == "." || dir == "/" {
+				break
+			}
+		}
+	}
+
+	var ret []gcEntry
+	var walk func(dir string) error
+	walk = func(dir string) error {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			// Symlinks aren't followed, and files next to live outputs are
+			// kept in case they are undeclared side outputs.
+			if keep[path] || !entry.IsDir() {
+				continue
+			}
+			if live[path] {
+				if err := walk(path); err != nil {
+					return err
+				}
+				continue
+			}
+			size, err := diskUsage(path)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, gcEntry{path: path, size: size})
+		}
+		return nil
+	}
+
+	for _, root := range roots {
+		root = filepath.Clean(root)
+		if keep[root] || !live[root] {
+			continue
+		}
+		if err := walk(root); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].path < ret[j].path })
+	return ret, nil
+}
+
+// diskUsage returns the total size of the regular files under dir.
+func diskUsage(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// humanizeBytes formats a size in bytes with a binary unit.
+func humanizeBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTP"[exp])
+}
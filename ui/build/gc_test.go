@@ -0,0 +1,107 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindGarbage(t *testing.T) {
+	outDir := t.TempDir()
+	intermediates := filepath.Join(outDir, "soong", ".intermediates")
+	productObj := filepath.Join(outDir, "target", "product", "generic", "obj")
+	otherObj := filepath.Join(outDir, "target", "product", "other", "obj")
+
+	files := map[string]int{
+		"soong/.intermediates/foo/libfoo/android_arm64_shared/libfoo.so":             10,
+		"soong/.intermediates/foo/libfoo/android_arm64_shared/libfoo.so.rsp":         1,
+		"soong/.intermediates/foo/libfoo/android_arm64_static/libfoo.a":              20,
+		"soong/.intermediates/foo/libold/android_arm64_shared/libold.so":             30,
+		"soong/.intermediates/foo/libold/android_arm64_shared/obj/old.o":             40,
+		"soong/.intermediates/removed/libbar/android_arm64_shared/libbar.so":         50,
+		"soong/.intermediates/dist/keep.txt":                                         60,
+		"target/product/generic/obj/SHARED_LIBRARIES/libbaz_intermediates/libbaz.so": 70,
+		"target/product/generic/obj/SHARED_LIBRARIES/libqux_intermediates/libqux.so": 80,
+		"target/product/other/obj/SHARED_LIBRARIES/libbaz_intermediates/libbaz.so":   90,
+		// Written by Kati while generating the ninja file, and only read by
+		// actions.
+		"target/product/generic/obj/ETC/kati_list_intermediates/list.txt": 100,
+	}
+	for file, size := range files {
+		path := filepath.Join(outDir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The output of "ninja -t targets all".
+	targets := strings.Join([]string{
+		filepath.Join(intermediates, "foo/libfoo/android_arm64_shared/libfoo.so") + ": clang_link",
+		filepath.Join(productObj, "SHARED_LIBRARIES/libbaz_intermediates/libbaz.so") + ": rule12",
+		filepath.Join(intermediates, "foo/libnew/android_arm64_shared/libnew.so") + ": clang_link",
+		filepath.Join(outDir, "target/product/generic/system/lib64/libbaz.so") + ": cp",
+	}, "\n")
+	// The output of "ninja -t targets rule".
+	sources := strings.Join([]string{
+		"foo/foo.cpp",
+		filepath.Join(productObj, "ETC/kati_list_intermediates/list.txt"),
+	}, "\n")
+	nodes := make(map[string]bool)
+	if err := parseNinjaTargets(strings.NewReader(targets), true, nodes); err != nil {
+		t.Fatal(err)
+	}
+	if err := parseNinjaTargets(strings.NewReader(sources), false, nodes); err != nil {
+		t.Fatal(err)
+	}
+	excludes := []string{filepath.Join(intermediates, "dist")}
+
+	got, err := findGarbage([]string{intermediates, productObj, otherObj}, nodes, excludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gcEntry{
+		{path: filepath.Join(intermediates, "foo/libfoo/android_arm64_static"), size: 20},
+		{path: filepath.Join(intermediates, "foo/libold"), size: 70},
+		{path: filepath.Join(intermediates, "removed"), size: 50},
+		{path: filepath.Join(productObj, "SHARED_LIBRARIES/libqux_intermediates"), size: 80},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestHumanizeBytes(t *testing.T) {
+	for size, want := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KiB",
+		1536:          "1.5 KiB",
+		5 << 20:       "5.0 MiB",
+		3 << 30:       "3.0 GiB",
+		(3 << 40) / 2: "1.5 TiB",
+	} {
+		if got := humanizeBytes(size); got != want {
+			t.Errorf("humanizeBytes(%d): want %q, got %q", size, want, got)
+		}
+	}
+}
@@ -78,3 +78,34 @@ func startGoma(ctx Context, config Config) {
 		ctx.Fatalf("goma_ctl.py ensure_start failed with: %v\n%s\n", err, output)
 	}
 }
+
+// gomaBackend runs compiles with goma. The compiler proxy is shared between
+// builds, so it isn't stopped after the build.
+type gomaBackend struct{}
+
+func (gomaBackend) Name() string {
+	return "goma"
+}
+
+func (gomaBackend) Enabled(config Config) bool {
+	return config.UseGoma()
+}
+
+func (gomaBackend) ShouldStart(config Config) bool {
+	return config.StartGoma()
+}
+
+func (gomaBackend) Env(ctx Context, config Config) map[string]string {
+	return nil
+}
+
+func (gomaBackend) Parallel(config Config) int {
+	return defaultRemoteParallel
+}
+
+func (gomaBackend) Start(ctx Context, config Config) {
+	startGoma(ctx, config)
+}
+
+func (gomaBackend) Stop(ctx Context, config Config, metricsFile string) {
+}
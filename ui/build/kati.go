@@ -222,8 +222,19 @@ func runKatiBuild(ctx Context, config Config) {
 		// the dist.mk file, containing dist-for-goals data.
 		"KATI_PACKAGE_MK_DIR="+config.KatiPackageMkDir())
 
+	// Record the inputs tracked by soong_ui to explain why Kati reran. Kati
+	// tracks the makefiles, environment variables and globs it reads itself.
+	explainInputs := newRebuildStepInputs()
+	explainInputs.Values["kati suffix"] = config.KatiSuffix()
+	explainInputs.Values["kati args"] = strings.Join(config.KatiArgs(), " ")
+	explainInputs.addFiles(config.SoongMakeVarsMk(), config.SoongAndroidMk(), config.PrebuiltBuildTool("ckati"))
+	finishExplainStep := config.explainer.beginStep("kati", explainInputs, config.KatiBuildNinjaFile(),
+		"a makefile, or an environment variable or directory read by Kati changed")
+
 	runKati(ctx, config, katiBuildSuffix, args, func(env *Environment) {})
 
+	finishExplainStep()
+
 	// compress and dist the main build ninja file.
 	distGzipFile(ctx, config, config.KatiBuildNinjaFile())
 
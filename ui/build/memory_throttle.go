@@ -0,0 +1,246 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file holds back heavy actions while ninja runs if the system is low on
+// memory. The ninja pools are sized up front from the total RAM, but builds
+// still run out of memory when many javac, R8 or lld actions happen to run at
+// the same time. Ninja can't be told to lower its parallelism while it runs,
+// so instead heavy processes that ninja starts while the available memory is
+// below a threshold are stopped with SIGSTOP before they grow, and continued
+// one at a time once memory is available again. The stopped actions keep
+// their ninja job slots, which keeps ninja from starting more actions.
+//
+// Holding back actions is opt-in, by setting NINJA_MIN_AVAILABLE_MEMORY_MB.
+// Ninja doesn't know that an action was stopped, so the time an action was
+// held back is included in its duration in .ninja_log, the build trace and
+// the action history that build time regressions are reported from.
+
+import (
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"android/soong/finder/fs"
+	"android/soong/ui/metrics/proc"
+)
+
+// The executables of actions that use a lot of memory, as they appear in
+// /proc/<pid>/stat. The JDK launchers keep their own names, and R8, turbine,
+// metalava and kotlinc run as java.
+var heavyActionCommands = map[string]bool{
+	"dex2oat":   true,
+	"dex2oat32": true,
+	"dex2oat64": true,
+	"dex2oatd":  true,
+	"java":      true,
+	"javac":     true,
+	"ld":        true,
+	"ld.gold":   true,
+	"ld.lld":    true,
+	"lld":       true,
+}
+
+// The time between checks of the available memory.
+const memoryThrottleInterval = time.Second
+
+type memoryThrottle struct {
+	ctx        Context
+	fileSystem fs.FileSystem
+
+	// New heavy processes are stopped while the available memory is below
+	// threshold, and continued while it is at or above resumeThreshold.
+	threshold       uint64
+	resumeThreshold uint64
+
+	// The heavy processes found by the previous update, which are no longer
+	// new.
+	seen map[int]bool
+	// The processes that are stopped, in the order they were stopped.
+	paused []int
+	// The number of processes stopped since the start.
+	held int
+
+	signal func(pid int, sig syscall.Signal) error
+
+	done     chan bool
+	finished chan bool
+}
+
+func newMemoryThrottle(ctx Context, threshold uint64) *memoryThrottle {
+	return &memoryThrottle{
+		ctx:             ctx,
+		fileSystem:      fs.OsFs,
+		threshold:       threshold,
+		resumeThreshold: threshold + threshold/2,
+		seen:            make(map[int]bool),
+		signal:          syscall.Kill,
+	}
+}
+
+// start checks the available memory in the background until stop is called,
+// and holds back the heavy descendants of root while it is low. It does
+// nothing if the threshold is 0 or the available memory can't be read.
+func (m *memoryThrottle) start(root int) {
+	if m.threshold == 0 {
+		return
+	}
+	if _, err := proc.NewMemInfo(m.fileSystem); err != nil {
+		m.ctx.Verbosef("Not throttling heavy actions: %v", err)
+		return
+	}
+	m.ctx.Verbosef("Holding back heavy actions while available memory is below %s", humanizeBytes(int64(m.threshold)))
+
+	m.done = make(chan bool)
+	m.finished = make(chan bool)
+	go func() {
+		defer close(m.finished)
+		ticker := time.NewTicker(memoryThrottleInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-m.done:
+				return
+			case <-ticker.C:
+			}
+
+			mem, err := proc.NewMemInfo(m.fileSystem)
+			if err != nil {
+				return
+			}
+			m.update(mem.MemAvailable, heavyDescendants(m.fileSystem, root))
+		}
+	}()
+}
+
+// stop stops checking the available memory and continues all the stopped
+// processes.
+func (m *memoryThrottle) stop() {
+	if m.done == nil {
+		return
+	}
+	close(m.done)
+	<-m.finished
+	m.done = nil
+
+	for len(m.paused) > 0 {
+		m.resumeOldest()
+	}
+	if m.held > 0 {
+		m.ctx.Printf("Held back %d heavy actions while available memory was below %s, their durations include the time they were held back\n",
+			m.held, humanizeBytes(int64(m.threshold)))
+	}
+}
+
+// update stops the new processes in heavy if the available memory is below
+// the threshold, or continues the oldest stopped process if there is enough
+// available memory again. At least one heavy process is always left running
+// so that the build makes progress.
+func (m *memoryThrottle) update(available uint64, heavy []int) {
+	alive := make(map[int]bool, len(heavy))
+	for _, pid := range heavy {
+		alive[pid] = true
+	}
+
+	// Forget the stopped processes that were killed, for example when ninja
+	// is interrupted.
+	paused := make(map[int]bool, len(m.paused))
+	stillPaused := m.paused[:0]
+	for _, pid := range m.paused {
+		if alive[pid] {
+			stillPaused = append(stillPaused, pid)
+			paused[pid] = true
+		}
+	}
+	m.paused = stillPaused
+	running := len(heavy) - len(m.paused)
+
+	if available < m.threshold {
+		for _, pid := range heavy {
+			if m.seen[pid] || paused[pid] || running <= 1 {
+				continue
+			}
+			if err := m.signal(pid, syscall.SIGSTOP); err != nil {
+				continue
+			}
+			m.ctx.Verbosef("Holding back process %d, %s of memory available", pid, humanizeBytes(int64(available)))
+			m.paused = append(m.paused, pid)
+			m.held++
+			running--
+		}
+	} else if available >= m.resumeThreshold && len(m.paused) > 0 {
+		m.resumeOldest()
+		running++
+	}
+
+	if running == 0 && len(m.paused) > 0 {
+		m.resumeOldest()
+	}
+
+	m.seen = alive
+	if m.ctx.Tracer != nil {
+		m.ctx.Tracer.Counter("held back actions", int64(len(m.paused)))
+	}
+}
+
+func (m *memoryThrottle) resumeOldest() {
+	pid := m.paused[0]
+	m.paused = m.paused[1:]
+	m.ctx.Verbosef("Continuing process %d", pid)
+	m.signal(pid, syscall.SIGCONT)
+}
+
+// heavyDescendants returns the processes under root in /proc that run one of
+// heavyActionCommands, in PID order.
+func heavyDescendants(fileSystem fs.FileSystem, root int) []int {
+	entries, err := fileSystem.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	children := make(map[int][]int)
+	heavy := make(map[int]bool)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		stat, err := proc.NewProcStat(pid, fileSystem)
+		if err != nil {
+			// The process exited while reading /proc.
+			continue
+		}
+		children[stat.Ppid] = append(children[stat.Ppid], pid)
+		if heavyActionCommands[stat.Command] {
+			heavy[pid] = true
+		}
+	}
+
+	var ret []int
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		for _, child := range children[pid] {
+			if heavy[child] {
+				ret = append(ret, child)
+			}
+			queue = append(queue, child)
+		}
+	}
+	sort.Ints(ret)
+	return ret
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+func TestMemoryThrottleUpdate(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	var signals []string
+	m := newMemoryThrottle(testContext(), 4*gb)
+	m.signal = func(pid int, sig syscall.Signal) error {
+		if sig == syscall.SIGSTOP {
+			signals = append(signals, fmt.Sprintf("stop %d", pid))
+		} else {
+			signals = append(signals, fmt.Sprintf("cont %d", pid))
+		}
+		return nil
+	}
+
+	steps := []struct {
+		description string
+		available   uint64
+		heavy       []int
+		signals     []string
+		paused      []int
+	}{
+		{
+			description: "enough memory",
+			available:   16 * gb,
+			heavy:       []int{10, 11},
+		},
+		{
+			description: "new processes are held back",
+			available:   2 * gb,
+			heavy:       []int{10, 11, 12, 13},
+			signals:     []string{"stop 12", "stop 13"},
+			paused:      []int{12, 13},
+		},
+		{
+			description: "one process is left running",
+			available:   2 * gb,
+			heavy:       []int{12, 13, 14},
+			paused:      []int{12, 13},
+		},
+		{
+			description: "the last running process exited",
+			available:   2 * gb,
+			heavy:       []int{12, 13},
+			signals:     []string{"cont 12"},
+			paused:      []int{13},
+		},
+		{
+			description: "below the resume threshold",
+			available:   5 * gb,
+			heavy:       []int{12, 13},
+			paused:      []int{13},
+		},
+		{
+			description: "memory is available again",
+			available:   6 * gb,
+			heavy:       []int{12, 13},
+			signals:     []string{"cont 13"},
+		},
+	}
+	for _, step := range steps {
+		signals = nil
+		m.update(step.available, step.heavy)
+		if !reflect.DeepEqual(signals, step.signals) {
+			t.Errorf("%s: want signals %q, got %q", step.description, step.signals, signals)
+		}
+		if len(m.paused) > 0 || len(step.paused) > 0 {
+			if !reflect.DeepEqual(m.paused, step.paused) {
+				t.Errorf("%s: want paused %v, got %v", step.description, step.paused, m.paused)
+			}
+		}
+	}
+	if m.held != 2 {
+		t.Errorf("want 2 held back actions, got %d", m.held)
+	}
+}
+
+func TestHeavyDescendants(t *testing.T) {
+	mockFs := fs.NewMockFs(nil)
+	processes := []struct {
+		pid     int
+		ppid    int
+		command string
+	}{
+		{100, 1, "ninja"},
+		{101, 100, "bash"},
+		{102, 101, "java"},
+		{103, 100, "bash"},
+		{104, 103, "ld.lld"},
+		{105, 100, "clang"},
+		{106, 100, "javac"},
+		// Not under ninja.
+		{200, 1, "java"},
+	}
+	for _, p := range processes {
+		dir := filepath.Join("/proc", strconv.Itoa(p.pid))
+		if err := mockFs.MkDirs(dir); err != nil {
+			t.Fatal(err)
+		}
+		stat := fmt.Sprintf("%d (%s) S %d 0 0 0 -1 0 0 0 0 0 0 0 0 0 20 0 1 0 0 0 0\n", p.pid, p.command, p.ppid)
+		if err := mockFs.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if g, w := heavyDescendants(mockFs, 100), []int{102, 104, 106}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want %v, got %v", w, g)
+	}
+}
+
+func TestMemoryThrottleThreshold(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	tests := []struct {
+		env      []string
+		totalRAM uint64
+		want     uint64
+	}{
+		{totalRAM: 0, want: 0},
+		{totalRAM: 64 * gb, want: 0},
+		{env: []string{"NINJA_MIN_AVAILABLE_MEMORY_MB=2048"}, totalRAM: 64 * gb, want: 2 * gb},
+		{env: []string{"NINJA_MIN_AVAILABLE_MEMORY_MB=0"}, totalRAM: 64 * gb, want: 0},
+	}
+	for _, tt := range tests {
+		env := Environment(tt.env)
+		config := Config{&configImpl{environ: &env, totalRAM: tt.totalRAM}}
+		if g := config.MemoryThrottleThreshold(); g != tt.want {
+			t.Errorf("env %q, total RAM %d: want %d, got %d", tt.env, tt.totalRAM, tt.want, g)
+		}
+	}
+}
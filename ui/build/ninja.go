@@ -49,6 +49,10 @@ func runNinjaForBuild(ctx Context, config Config) {
 		"--frontend_file", fifo,
 	}
 
+	if config.ExplainRebuild() {
+		args = append(args, "-d", "explain")
+	}
+
 	args = append(args, config.NinjaArgs()...)
 
 	var parallel int
@@ -71,6 +75,8 @@ func runNinjaForBuild(ctx Context, config Config) {
 
 	cmd := Command(ctx, config, "ninja", executable, args...)
 
+	cmd.outputFilter = config.explainer.ninjaExplainFilter("ninja")
+
 	// Set up the nsjail sandbox Ninja runs in.
 	cmd.Sandbox = ninjaSandbox
 	if config.HasKatiSuffix() {
@@ -101,73 +107,13 @@ func runNinjaForBuild(ctx Context, config Config) {
 	// Filter the environment, as ninja does not rebuild files when environment
 	// variables change.
 	//
-	// Anything listed here must not change the output of rules/actions when the
-	// value changes, otherwise incremental builds may be unsafe. Vars
-	// explicitly set to stable values elsewhere in soong_ui are fine.
-	//
 	// For the majority of cases, either Soong or the makefiles should be
 	// replicating any necessary environment variables in the command line of
 	// each action that needs it.
 	if cmd.Environment.IsEnvTrue("ALLOW_NINJA_ENV") {
 		ctx.Println("Allowing all environment variables during ninja; incremental builds may be unsafe.")
 	} else {
-		cmd.Environment.Allow(append([]string{
-			// Set the path to a symbolizer (e.g. llvm-symbolizer) so ASAN-based
-			// tools can symbolize crashes.
-			"ASAN_SYMBOLIZER_PATH",
-			"HOME",
-			"JAVA_HOME",
-			"LANG",
-			"LC_MESSAGES",
-			"OUT_DIR",
-			"PATH",
-			"PWD",
-			// https://docs.python.org/3/using/cmdline.html#envvar-PYTHONDONTWRITEBYTECODE
-			"PYTHONDONTWRITEBYTECODE",
-			"TMPDIR",
-			"USER",
-
-			// TODO: remove these carefully
-			// Options for the address sanitizer.
-			"ASAN_OPTIONS",
-			// The list of Android app modules to be built in an unbundled manner.
-			"TARGET_BUILD_APPS",
-			// The variant of the product being built. e.g. eng, userdebug, debug.
-			"TARGET_BUILD_VARIANT",
-			// The product name of the product being built, e.g. aosp_arm, aosp_flame.
-			"TARGET_PRODUCT",
-			// b/147197813 - used by art-check-debug-apex-gen
-			"EMMA_INSTRUMENT_FRAMEWORK",
-
-			// RBE client
-			"RBE_compare",
-			"RBE_exec_root",
-			"RBE_exec_strategy",
-			"RBE_invocation_id",
-			"RBE_log_dir",
-			"RBE_num_retries_if_mismatched",
-			"RBE_platform",
-			"RBE_remote_accept_cache",
-			"RBE_remote_update_cache",
-			"RBE_server_address",
-			// TODO: remove old FLAG_ variables.
-			"FLAG_compare",
-			"FLAG_exec_root",
-			"FLAG_exec_strategy",
-			"FLAG_invocation_id",
-			"FLAG_log_dir",
-			"FLAG_platform",
-			"FLAG_remote_accept_cache",
-			"FLAG_remote_update_cache",
-			"FLAG_server_address",
-
-			// ccache settings
-			"CCACHE_COMPILERCHECK",
-			"CCACHE_SLOPPINESS",
-			"CCACHE_BASEDIR",
-			"CCACHE_CPP2",
-			"CCACHE_DIR",
-		}, config.BuildBrokenNinjaUsesEnvVars()...)...)
+		cmd.Environment.Allow(ninjaAllowedEnvVars(config)...)
 	}
 
 	cmd.Environment.Set("DIST_DIR", config.DistDir())
@@ -202,10 +148,86 @@ func runNinjaForBuild(ctx Context, config Config) {
 		}
 	}()
 
+	// Hold back heavy actions while the system is low on memory.
+	throttle := newMemoryThrottle(ctx, config.MemoryThrottleThreshold())
+	cmd.onStart = throttle.start
+	defer throttle.stop()
+
 	ctx.Status.Status("Starting ninja...")
 	cmd.RunAndStreamOrFatal()
 }
 
+// ninjaEnvAllowlist is the list of environment variables that are passed to
+// ninja, as ninja does not rebuild files when environment variables change.
+//
+// Anything listed here must not change the output of rules/actions when the
+// value changes, otherwise incremental builds may be unsafe. Vars explicitly
+// set to stable values elsewhere in soong_ui are fine.
+var ninjaEnvAllowlist = []string{
+	// Set the path to a symbolizer (e.g. llvm-symbolizer) so ASAN-based
+	// tools can symbolize crashes.
+	"ASAN_SYMBOLIZER_PATH",
+	"HOME",
+	"JAVA_HOME",
+	"LANG",
+	"LC_MESSAGES",
+	"OUT_DIR",
+	"PATH",
+	"PWD",
+	// https://docs.python.org/3/using/cmdline.html#envvar-PYTHONDONTWRITEBYTECODE
+	"PYTHONDONTWRITEBYTECODE",
+	"TMPDIR",
+	"USER",
+
+	// TODO: remove these carefully
+	// Options for the address sanitizer.
+	"ASAN_OPTIONS",
+	// The list of Android app modules to be built in an unbundled manner.
+	"TARGET_BUILD_APPS",
+	// The variant of the product being built. e.g. eng, userdebug, debug.
+	"TARGET_BUILD_VARIANT",
+	// The product name of the product being built, e.g. aosp_arm, aosp_flame.
+	"TARGET_PRODUCT",
+	// b/147197813 - used by art-check-debug-apex-gen
+	"EMMA_INSTRUMENT_FRAMEWORK",
+
+	// RBE client
+	"RBE_compare",
+	"RBE_exec_root",
+	"RBE_exec_strategy",
+	"RBE_invocation_id",
+	"RBE_log_dir",
+	"RBE_num_retries_if_mismatched",
+	"RBE_platform",
+	"RBE_remote_accept_cache",
+	"RBE_remote_update_cache",
+	"RBE_server_address",
+	// TODO: remove old FLAG_ variables.
+	"FLAG_compare",
+	"FLAG_exec_root",
+	"FLAG_exec_strategy",
+	"FLAG_invocation_id",
+	"FLAG_log_dir",
+	"FLAG_platform",
+	"FLAG_remote_accept_cache",
+	"FLAG_remote_update_cache",
+	"FLAG_server_address",
+
+	// ccache settings
+	"CCACHE_COMPILERCHECK",
+	"CCACHE_SLOPPINESS",
+	"CCACHE_BASEDIR",
+	"CCACHE_CPP2",
+	"CCACHE_DIR",
+}
+
+// ninjaAllowedEnvVars returns the environment variables that are passed to
+// ninja, the allowlist and the ones the product allows with
+// BUILD_BROKEN_NINJA_USES_ENV_VARS.
+func ninjaAllowedEnvVars(config Config) []string {
+	return append(append([]string(nil), ninjaEnvAllowlist...), config.BuildBrokenNinjaUsesEnvVars()...)
+}
+
 // A simple struct for checking if Ninja gets stuck, using timestamps.
 type ninjaStucknessChecker struct {
 	logPath     string
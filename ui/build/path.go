@@ -15,6 +15,7 @@
 package build
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -150,6 +151,16 @@ func SetupPath(ctx Context, config Config) {
 		ctx.Fatalln("Failed to write original path:", err)
 	}
 
+	// The uses of allowed tools are only reported if SOONG_REPORT_ALL_PATH_TOOLS
+	// is set, as it slows down every tool.
+	if config.Environment().IsEnvTrue("SOONG_REPORT_ALL_PATH_TOOLS") {
+		if err := ioutil.WriteFile(interposer+"_report_all", nil, 0666); err != nil {
+			ctx.Fatalln("Failed to write path report marker:", err)
+		}
+	} else if err := os.Remove(interposer + "_report_all"); err != nil && !os.IsNotExist(err) {
+		ctx.Fatalln("Failed to remove path report marker:", err)
+	}
+
 	// Communication with the path interposer works over log entries. Set up the
 	// listener channel for the log entries here.
 	entries, err := paths.LogListener(ctx.Context, interposer+"_log")
@@ -157,9 +168,18 @@ func SetupPath(ctx Context, config Config) {
 		ctx.Fatalln("Failed to listen for path logs:", err)
 	}
 
+	// Write every use of a logged or disallowed tool, and of allowed tools if
+	// SOONG_REPORT_ALL_PATH_TOOLS is set, to a report in the logs directory.
+	report, err := os.Create(filepath.Join(config.LogsDir(), "path_tools.jsonl"))
+	if err != nil {
+		ctx.Fatalln("Failed to create the PATH tools report:", err)
+	}
+
 	// Loop over all log entry listener channels to validate usage of only
 	// allowed PATH tools at runtime.
 	go func() {
+		defer report.Close()
+		encoder := json.NewEncoder(report)
 		for log := range entries {
 			curPid := os.Getpid()
 			for i, proc := range log.Parents {
@@ -168,11 +188,21 @@ func SetupPath(ctx Context, config Config) {
 					break
 				}
 			}
+			config := paths.GetConfig(log.Basename)
+			encoder.Encode(newPathToolRecord(log, config))
+			if !config.Log && !config.Error {
+				// Allowed tools are only reported.
+				continue
+			}
+
 			// Compute the error message along with the process tree, including
 			// parents, for this log line.
 			procPrints := []string{
 				"See https://android.googlesource.com/platform/build/+/master/Changes.md#PATH_Tools for more information.",
 			}
+			if log.Module.Name != "" {
+				procPrints = append(procPrints, fmt.Sprintf("Used by module %s in the %s phase.", log.Module.Name, log.Phase))
+			}
 			if len(log.Parents) > 0 {
 				procPrints = append(procPrints, "Process tree:")
 				for i, proc := range log.Parents {
@@ -181,14 +211,17 @@ func SetupPath(ctx Context, config Config) {
 			}
 
 			// Validate usage against disallowed or missing PATH tools.
-			config := paths.GetConfig(log.Basename)
-			if config.Error {
+			if config.Error && !log.Allowlisted {
 				ctx.Printf("Disallowed PATH tool %q used: %#v", log.Basename, log.Args)
 				for _, line := range procPrints {
 					ctx.Println(line)
 				}
 			} else {
-				ctx.Verbosef("Unknown PATH tool %q used: %#v", log.Basename, log.Args)
+				kind := "Unknown"
+				if log.Allowlisted {
+					kind = "Allowlisted"
+				}
+				ctx.Verbosef("%s PATH tool %q used: %#v", kind, log.Basename, log.Args)
 				for _, line := range procPrints {
 					ctx.Verboseln(line)
 				}
@@ -224,8 +257,9 @@ func SetupPath(ctx Context, config Config) {
 	// intercepted by the path_interposer binary, and validated with the
 	// LogEntry listener above at build time.
 	for _, name := range execs {
-		if !paths.GetConfig(name).Symlink {
-			// Ignore host tools that shouldn't be symlinked.
+		if !paths.GetConfig(name).Symlink && !paths.HasAllowlistEntry(name) {
+			// Ignore host tools that shouldn't be symlinked, unless some uses
+			// of them are allowlisted.
 			continue
 		}
 
@@ -249,3 +283,48 @@ func SetupPath(ctx Context, config Config) {
 	config.Environment().Set("PATH", myPath)
 	config.pathReplaced = true
 }
+
+// pathToolRecord is a use of a host tool through the path interposer, as
+// written to path_tools.jsonl in the logs directory. Uses of allowed tools are
+// only recorded if SOONG_REPORT_ALL_PATH_TOOLS is set.
+type pathToolRecord struct {
+	Tool string   `json:"tool"`
+	Args []string `json:"args"`
+
+	// How the tool is configured in paths.Configuration: "allowed", "log",
+	// "forbidden" or "missing".
+	Policy      string `json:"policy"`
+	Allowlisted bool   `json:"allowlisted"`
+
+	Phase     string `json:"phase"`
+	ModuleDir string `json:"module_dir,omitempty"`
+	Module    string `json:"module,omitempty"`
+
+	// The command lines of the parent processes, starting from soong_ui.
+	Parents []string `json:"parents"`
+}
+
+func newPathToolRecord(log *paths.LogEntry, config paths.PathConfig) pathToolRecord {
+	record := pathToolRecord{
+		Tool:        log.Basename,
+		Args:        log.Args,
+		Allowlisted: log.Allowlisted,
+		Phase:       log.Phase,
+		ModuleDir:   log.Module.Dir,
+		Module:      log.Module.Name,
+		Parents:     []string{},
+	}
+	if config.Error && config.Symlink {
+		record.Policy = "missing"
+	} else if config.Error {
+		record.Policy = "forbidden"
+	} else if config.Log {
+		record.Policy = "log"
+	} else {
+		record.Policy = "allowed"
+	}
+	for _, proc := range log.Parents {
+		record.Parents = append(record.Parents, proc.Command)
+	}
+	return record
+}
@@ -0,0 +1,170 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paths
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// The phases of the build that host tools are run from.
+const (
+	PhaseSoong    = "soong"
+	PhaseDumpvars = "dumpvars"
+	PhaseKati     = "kati"
+	PhaseNinja    = "ninja"
+	PhaseUnknown  = "unknown"
+)
+
+// LogModule is the module whose action ran a host tool, as far as it can be
+// told from the command lines of the action. Dir is empty for Make modules.
+type LogModule struct {
+	Dir  string
+	Name string
+}
+
+// AllowlistEntry allows a host tool that isn't allowed by Configuration to be
+// used by some of the build. Each criterion that is set must match.
+type AllowlistEntry struct {
+	Tool string
+
+	// The phases the tool may be used in, see PhaseOf.
+	Phases []string
+
+	// The modules in these source directories, or their subdirectories, may
+	// use the tool.
+	Dirs []string
+
+	// The modules with these names may use the tool.
+	Modules []string
+}
+
+// Allowlist lists the existing uses of host tools that are missing from
+// Configuration or forbidden by it, so that no new uses are added while they
+// are removed. Entries should only ever be removed or narrowed.
+var Allowlist = []AllowlistEntry{}
+
+// HasAllowlistEntry returns whether some uses of the tool are allowlisted.
+func HasAllowlistEntry(tool string) bool {
+	for _, entry := range Allowlist {
+		if entry.Tool == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowlisted returns whether the tool may be used by the module in the phase.
+func Allowlisted(tool, phase string, module LogModule) bool {
+	for _, entry := range Allowlist {
+		if entry.Tool == tool && entry.matches(phase, module) {
+			return true
+		}
+	}
+	return false
+}
+
+func (entry AllowlistEntry) matches(phase string, module LogModule) bool {
+	if len(entry.Phases) > 0 && !inList(phase, entry.Phases) {
+		return false
+	}
+	if len(entry.Dirs) == 0 && len(entry.Modules) == 0 {
+		return true
+	}
+	for _, dir := range entry.Dirs {
+		if module.Dir != "" && (module.Dir == dir || strings.HasPrefix(module.Dir, dir+"/")) {
+			return true
+		}
+	}
+	return module.Name != "" && inList(module.Name, entry.Modules)
+}
+
+func inList(s string, list []string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PhaseOf returns the phase of the build that a tool was run from, given its
+// parent processes starting from the outermost one.
+func PhaseOf(parents []LogProcess) string {
+	// Ignore the processes that ran soong_ui.
+	for i := len(parents) - 1; i >= 0; i-- {
+		if commandName(parents[i].Command) == "soong_ui" {
+			parents = parents[i+1:]
+			break
+		}
+	}
+
+	for _, proc := range parents {
+		switch commandName(proc.Command) {
+		case "soong_build", "bpglob", "minibp":
+			return PhaseSoong
+		case "ckati":
+			if strings.Contains(proc.Command, "build/make/core/config.mk") {
+				return PhaseDumpvars
+			}
+			return PhaseKati
+		case "ninja":
+			// soong_ui runs the ninja files of Soong itself before the
+			// combined ninja file of the build.
+			if strings.Contains(proc.Command, "/combined") {
+				return PhaseNinja
+			}
+			return PhaseSoong
+		}
+	}
+	return PhaseUnknown
+}
+
+func commandName(command string) string {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return ""
+	}
+	return filepath.Base(fields[0])
+}
+
+var (
+	// Soong intermediates are in .intermediates/<dir>/<name>/<variant>/.
+	soongIntermediatesRegexp = regexp.MustCompile(`\.intermediates/(?:([^\s'"]+?)/)?([^/\s'"]+)/(?:android|linux|darwin|windows)_[^/\s'"]*/`)
+	// Make intermediates are in obj/<class>/<name>_intermediates/.
+	makeIntermediatesRegexp = regexp.MustCompile(`/obj(?:_[^/\s'"]+)?/[A-Z_]+/([^/\s'"]+)_intermediates/`)
+)
+
+// ModuleOf returns the module whose action ran a tool with args, given its
+// parent processes starting from the outermost one. The module is found from
+// the paths to its intermediates in the command lines, starting from the
+// tool's own.
+func ModuleOf(args []string, parents []LogProcess) LogModule {
+	commands := []string{strings.Join(args, " ")}
+	for i := len(parents) - 1; i >= 0; i-- {
+		commands = append(commands, parents[i].Command)
+	}
+
+	for _, command := range commands {
+		if match := soongIntermediatesRegexp.FindStringSubmatch(command); match != nil {
+			return LogModule{Dir: match[1], Name: match[2]}
+		}
+		if match := makeIntermediatesRegexp.FindStringSubmatch(command); match != nil {
+			return LogModule{Name: match[1]}
+		}
+	}
+	return LogModule{}
+}
@@ -0,0 +1,149 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package paths
+
+import (
+	"testing"
+)
+
+func TestPhaseOf(t *testing.T) {
+	soongUI := LogProcess{Command: "out/soong_ui --make-mode droid"}
+	testCases := []struct {
+		name    string
+		parents []LogProcess
+		want    string
+	}{
+		{
+			name: "ninja action",
+			parents: []LogProcess{
+				// A ninja that runs soong_ui isn't the build's.
+				{Command: "prebuilts/build-tools/linux-x86/bin/ninja -f outer.ninja"},
+				soongUI,
+				{Command: "prebuilts/build-tools/linux-x86/bin/ninja -d keepdepfile -f out/combined-aosp_arm64.ninja"},
+				{Command: "/bin/bash -c python foo.py"},
+			},
+			want: PhaseNinja,
+		},
+		{
+			name: "soong",
+			parents: []LogProcess{
+				soongUI,
+				{Command: "prebuilts/build-tools/linux-x86/bin/ninja -f out/soong/build.ninja"},
+				{Command: "out/soong/.bootstrap/bin/soong_build -o out/soong/build.ninja Android.bp"},
+			},
+			want: PhaseSoong,
+		},
+		{
+			name: "kati",
+			parents: []LogProcess{
+				soongUI,
+				{Command: "prebuilts/build-tools/linux-x86/bin/ckati --ninja -f build/make/core/main.mk"},
+			},
+			want: PhaseKati,
+		},
+		{
+			name: "dumpvars",
+			parents: []LogProcess{
+				soongUI,
+				{Command: "prebuilts/build-tools/linux-x86/bin/ckati -f build/make/core/config.mk dump-many-vars"},
+			},
+			want: PhaseDumpvars,
+		},
+		{
+			name:    "soong_ui",
+			parents: []LogProcess{soongUI},
+			want:    PhaseUnknown,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if g := PhaseOf(tt.parents); g != tt.want {
+				t.Errorf("want %q, got %q", tt.want, g)
+			}
+		})
+	}
+}
+
+func TestModuleOf(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		parents []LogProcess
+		want    LogModule
+	}{
+		{
+			name: "soong module",
+			args: []string{"python", "gen.py", "out/soong/.intermediates/frameworks/base/framework-res/android_common/gen/R.java"},
+			want: LogModule{Dir: "frameworks/base", Name: "framework-res"},
+		},
+		{
+			name: "soong module at the top of the tree",
+			args: []string{"python", "out/soong/.intermediates/libtop/linux_glibc_x86_64_static/x.o"},
+			want: LogModule{Name: "libtop"},
+		},
+		{
+			name: "make module in a parent",
+			args: []string{"python", "gen.py"},
+			parents: []LogProcess{
+				{Command: "/bin/bash -c 'python gen.py > out/target/product/generic/obj/ETC/init.rc_intermediates/init.rc'"},
+			},
+			want: LogModule{Name: "init.rc"},
+		},
+		{
+			name: "unknown",
+			args: []string{"python", "gen.py"},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if g := ModuleOf(tt.args, tt.parents); g != tt.want {
+				t.Errorf("want %+v, got %+v", tt.want, g)
+			}
+		})
+	}
+}
+
+func TestAllowlisted(t *testing.T) {
+	saved := Allowlist
+	defer func() { Allowlist = saved }()
+	Allowlist = []AllowlistEntry{
+		{Tool: "python", Dirs: []string{"external/foo"}, Modules: []string{"libbar"}},
+		{Tool: "perl", Phases: []string{PhaseKati}},
+	}
+
+	testCases := []struct {
+		tool   string
+		phase  string
+		module LogModule
+		want   bool
+	}{
+		{"python", PhaseNinja, LogModule{Dir: "external/foo", Name: "foo"}, true},
+		{"python", PhaseNinja, LogModule{Dir: "external/foo/sub", Name: "sub"}, true},
+		{"python", PhaseNinja, LogModule{Dir: "external/foobar", Name: "foobar"}, false},
+		{"python", PhaseNinja, LogModule{Name: "libbar"}, true},
+		{"python", PhaseNinja, LogModule{}, false},
+		{"perl", PhaseKati, LogModule{}, true},
+		{"perl", PhaseNinja, LogModule{Name: "libbar"}, false},
+		{"ruby", PhaseKati, LogModule{}, false},
+	}
+	for _, tt := range testCases {
+		if g := Allowlisted(tt.tool, tt.phase, tt.module); g != tt.want {
+			t.Errorf("Allowlisted(%q, %q, %+v): want %t, got %t", tt.tool, tt.phase, tt.module, tt.want, g)
+		}
+	}
+	if !HasAllowlistEntry("perl") || HasAllowlistEntry("ruby") {
+		t.Errorf("unexpected HasAllowlistEntry results")
+	}
+}
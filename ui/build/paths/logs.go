@@ -37,6 +37,15 @@ type LogEntry struct {
 	Basename string
 	Args     []string
 	Parents  []LogProcess
+
+	// The phase of the build and the module that ran the tool, see PhaseOf
+	// and ModuleOf.
+	Phase  string
+	Module LogModule
+
+	// Whether the tool isn't allowed by Configuration, but the use was
+	// allowed by Allowlist.
+	Allowlisted bool
 }
 
 const timeoutDuration = time.Duration(100) * time.Millisecond
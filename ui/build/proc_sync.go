@@ -36,7 +36,7 @@ func BecomeSingletonOrFail(ctx Context, config Config) (lock *fileLock) {
 	}
 	lockfilePollDuration := time.Second
 	lockfileTimeout := time.Second * 10
-	if envTimeout := os.Getenv("SOONG_LOCK_TIMEOUT"); envTimeout != "" {
+	if envTimeout, _ := config.Environment().Get("SOONG_LOCK_TIMEOUT"); envTimeout != "" {
 		lockfileTimeout, err = time.ParseDuration(envTimeout)
 		if err != nil {
 			ctx.Logger.Fatalf("failure parsing SOONG_LOCK_TIMEOUT %q: %s", envTimeout, err)
@@ -0,0 +1,250 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements build profiles, JSON files that set the environment
+// variables soong_ui and the build read, so that they don't have to be
+// exported in every shell. For example:
+//
+//   {
+//     "env": {
+//       "TARGET_PRODUCT": "aosp_arm64",
+//       "USE_RBE": "true"
+//     },
+//     "extra_env": {
+//       "MY_TOOL_FLAGS": "--fast"
+//     }
+//   }
+//
+// Settings are taken from, highest precedence first:
+//
+//   1. The environment.
+//   2. The profile named by $SOONG_UI_PROFILE.
+//   3. The user's profile, ~/.soong_ui_profile.json.
+//   4. The tree's profile, .soong_ui_profile.json at the top of the tree.
+//
+// Variables in "env" must be known build settings, so that typos are
+// reported. Other variables can be set with "extra_env".
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	buildProfileEnvVar = "SOONG_UI_PROFILE"
+	buildProfileFile   = ".soong_ui_profile.json"
+
+	settingFromEnvironment = "environment"
+)
+
+// The environment variables that can be set in the "env" section of a build
+// profile. Only variables that are read from the config can be set, not ones
+// read before the config is created, like ANDROID_QUIET_BUILD or WITH_TIDY_ONLY.
+var buildProfileSettings = map[string]string{
+	"ALLOW_MISSING_DEPENDENCIES":           "build with missing dependencies replaced by errors at runtime",
+	"ALLOW_NINJA_ENV":                      "pass the whole environment to ninja",
+	"CCACHE_EXEC":                          "the ccache binary to use with USE_CCACHE",
+	"DISABLE_AUTO_INSTALLCLEAN":            "don't run installclean when the product config changes",
+	"DIST_DIR":                             "the dist directory",
+	"EXPERIMENTAL_USE_OPENJDK11_TOOLCHAIN": "use the OpenJDK 11 toolchain",
+	"FORCE_USE_GOMA":                       "use goma even if it can't be verified",
+	"GOMA_DIR":                             "the goma client directory",
+	"NINJA_ARGS":                           "extra arguments to ninja",
+	"NINJA_EXTRA_ARGS":                     "more extra arguments to ninja",
+	"NINJA_MIN_AVAILABLE_MEMORY_MB":        "the available memory in MB below which heavy actions are held back, unset to not hold them back",
+	"NOSTART_GOMA":                         "don't start the goma compiler proxy",
+	"NOSTART_RBE":                          "don't start the RBE proxy",
+	"NOSTART_REAPI_PROXY":                  "don't start the REAPI proxy",
+	"OUT_DIR":                              "the output directory",
+	"OUT_DIR_COMMON_BASE":                  "the parent of per-tree output directories",
+	"OVERRIDE_ANDROID_JAVA_HOME":           "the JDK to use",
+	"RBE_DIR":                              "the RBE client directory",
+	"REAPI_PROXY":                          "the program that starts and stops a REAPI proxy",
+	"SANITIZE_HOST":                        "sanitizers for host modules",
+	"SANITIZE_TARGET":                      "sanitizers for device modules",
+	"SOONG_COLLECT_CC_DEPS":                "write module_bp_cc_deps.json for IDEs",
+	"SOONG_COLLECT_JAVA_DEPS":              "write module_bp_java_deps.json for IDEs",
+	"SOONG_LOCK_TIMEOUT":                   "how long to wait for another build in the output directory",
+	"SOONG_REPORT_ALL_PATH_TOOLS":          "report the uses of allowed host tools in path_tools.jsonl too",
+	"SOONG_SANDBOX":                        "the sandbox profile of each phase, like strict or kati=strict,ninja=default",
+	"SOONG_SANDBOX_PROFILES":               "a JSON file of more sandbox profiles",
+	"TARGET_BUILD_APPS":                    "build unbundled apps",
+	"TARGET_BUILD_VARIANT":                 "the build variant: user, userdebug or eng",
+	"TARGET_PRODUCT":                       "the product to build",
+	"USE_BAZEL":                            "build with Bazel",
+	"USE_BAZEL_ANALYSIS":                   "use Bazel for the analysis of converted modules",
+	"USE_CCACHE":                           "use ccache for C and C++ compiles",
+	"USE_GOMA":                             "use goma for remote compiles",
+	"USE_RBE":                              "use RBE for remote execution",
+}
+
+// buildProfile is the contents of a build profile file.
+type buildProfile struct {
+	// Known build settings, see buildProfileSettings.
+	Env map[string]string `json:"env"`
+	// Other environment variables, which aren't validated.
+	ExtraEnv map[string]string `json:"extra_env"`
+}
+
+// settingSource is the value of a setting and where it came from: the
+// environment or a profile file.
+type settingSource struct {
+	value  string
+	source string
+}
+
+// buildProfileFiles returns the profiles to load, highest precedence first,
+// and whether each one is required to exist.
+func buildProfileFiles(env *Environment) (files []string, required []bool) {
+	if file, ok := env.Get(buildProfileEnvVar); ok && file != "" {
+		files = append(files, file)
+		required = append(required, true)
+	}
+	if home, ok := env.Get("HOME"); ok && home != "" {
+		files = append(files, filepath.Join(home, buildProfileFile))
+		required = append(required, false)
+	}
+	files = append(files, buildProfileFile)
+	required = append(required, false)
+	return files, required
+}
+
+// loadBuildProfiles sets the variables from the profiles in env, unless they
+// are already set by the environment or by a profile with higher precedence.
+// It returns the value and source of every build setting and every variable
+// set by a profile.
+func loadBuildProfiles(env *Environment, files []string, required []bool) (map[string]settingSource, error) {
+	sources := make(map[string]settingSource)
+	for name := range buildProfileSettings {
+		if value, ok := env.Get(name); ok {
+			sources[name] = settingSource{value, settingFromEnvironment}
+		}
+	}
+
+	for i, file := range files {
+		profile, err := readBuildProfile(file)
+		if os.IsNotExist(err) && !required[i] {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		set := func(vars map[string]string) {
+			for name, value := range vars {
+				if _, ok := env.Get(name); ok {
+					continue
+				}
+				env.Set(name, value)
+				sources[name] = settingSource{value, file}
+			}
+		}
+		set(profile.Env)
+		set(profile.ExtraEnv)
+	}
+	return sources, nil
+}
+
+// readBuildProfile reads a profile file and validates its keys.
+func readBuildProfile(file string) (*buildProfile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &buildProfile{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(profile); err != nil {
+		return nil, fmt.Errorf("build profile %s did not parse correctly: %v", file, err)
+	}
+
+	var unknown []string
+	for name := range profile.Env {
+		if _, ok := buildProfileSettings[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("build profile %s sets unknown settings %s in \"env\", set them in \"extra_env\" instead if they are intended",
+			file, strings.Join(unknown, ", "))
+	}
+	return profile, nil
+}
+
+// DumpEffectiveConfig returns a description of the build settings and where
+// each one came from, followed by the configuration derived from them and
+// the command line.
+func DumpEffectiveConfig(config Config) string {
+	sb := &strings.Builder{}
+
+	files, _ := buildProfileFiles(config.Environment())
+	fmt.Fprintf(sb, "Settings are read from, highest precedence first: the %s, %s\n\n",
+		settingFromEnvironment, strings.Join(files, ", "))
+
+	var names []string
+	for name := range buildProfileSettings {
+		names = append(names, name)
+	}
+	for name := range config.settingSources {
+		if _, ok := buildProfileSettings[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if s, ok := config.settingSources[name]; ok {
+			fmt.Fprintf(sb, "%s=%q (from the %s)\n", name, s.value, describeSettingSource(s.source))
+		} else {
+			fmt.Fprintf(sb, "%s is unset\n", name)
+		}
+		if description, ok := buildProfileSettings[name]; ok {
+			fmt.Fprintf(sb, "    %s\n", description)
+		}
+	}
+
+	fmt.Fprintln(sb)
+	fmt.Fprintln(sb, "Derived configuration:")
+	fmt.Fprintf(sb, "  out dir: %s\n", config.OutDir())
+	fmt.Fprintf(sb, "  dist dir: %s\n", config.DistDir())
+	fmt.Fprintf(sb, "  parallel: %d\n", config.Parallel())
+	fmt.Fprintf(sb, "  keep going: %d\n", config.keepGoing)
+	fmt.Fprintf(sb, "  remote parallel: %d\n", config.RemoteParallel())
+	fmt.Fprintf(sb, "  use RBE: %t\n", config.UseRBE())
+	fmt.Fprintf(sb, "  use goma: %t\n", config.UseGoma())
+	var sandboxes []string
+	for _, phase := range sandboxPhases {
+		sandboxes = append(sandboxes, phase+"="+config.SandboxProfile(phase).Name)
+	}
+	fmt.Fprintf(sb, "  sandbox profiles: %s\n", strings.Join(sandboxes, " "))
+	fmt.Fprintf(sb, "  arguments: %s\n", strings.Join(config.Arguments(), " "))
+
+	return sb.String()
+}
+
+func describeSettingSource(source string) string {
+	if source == settingFromEnvironment {
+		return source
+	}
+	return "profile " + source
+}
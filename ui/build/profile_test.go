@@ -0,0 +1,133 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadBuildProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile := func(name, contents string) string {
+		t.Helper()
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+
+	explicit := writeProfile("explicit.json", `{"env": {"USE_RBE": "true"}}`)
+	user := writeProfile("user.json", `{
+		"env": {"USE_RBE": "false", "TARGET_PRODUCT": "aosp_arm64", "TARGET_BUILD_VARIANT": "eng"},
+		"extra_env": {"MY_FLAGS": "--fast"}
+	}`)
+	tree := writeProfile("tree.json", `{"env": {"TARGET_PRODUCT": "aosp_x86", "NINJA_ARGS": "-d explain"}}`)
+	missing := filepath.Join(dir, "missing.json")
+
+	env := Environment([]string{"TARGET_BUILD_VARIANT=userdebug"})
+	sources, err := loadBuildProfiles(&env,
+		[]string{explicit, user, missing, tree},
+		[]bool{true, false, false, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSources := map[string]settingSource{
+		"TARGET_BUILD_VARIANT": {"userdebug", settingFromEnvironment},
+		"USE_RBE":              {"true", explicit},
+		"TARGET_PRODUCT":       {"aosp_arm64", user},
+		"MY_FLAGS":             {"--fast", user},
+		"NINJA_ARGS":           {"-d explain", tree},
+	}
+	if !reflect.DeepEqual(sources, wantSources) {
+		t.Errorf("want sources %v, got %v", wantSources, sources)
+	}
+	for name, s := range wantSources {
+		if v, _ := env.Get(name); v != s.value {
+			t.Errorf("want %s=%q, got %q", name, s.value, v)
+		}
+	}
+
+	errorTests := []struct {
+		name     string
+		profile  string
+		required bool
+		want     string
+	}{
+		{
+			name:    "unknown setting",
+			profile: writeProfile("typo.json", `{"env": {"USE_RBEE": "true", "TARGET_PRODUCT": "aosp_arm"}}`),
+			want:    "unknown settings USE_RBEE",
+		},
+		{
+			name:    "setting read before the config",
+			profile: writeProfile("quiet.json", `{"env": {"ANDROID_QUIET_BUILD": "true", "WITH_TIDY_ONLY": "true"}}`),
+			want:    "unknown settings ANDROID_QUIET_BUILD, WITH_TIDY_ONLY",
+		},
+		{
+			name:    "unknown section",
+			profile: writeProfile("section.json", `{"envs": {}}`),
+			want:    `unknown field "envs"`,
+		},
+		{
+			name:     "missing required profile",
+			profile:  missing,
+			required: true,
+			want:     "no such file",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := Environment([]string{})
+			_, err := loadBuildProfiles(&env, []string{tt.profile}, []bool{tt.required})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("want error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestDumpEffectiveConfig(t *testing.T) {
+	env := Environment([]string{"OUT_DIR=out", "HOME=/home/user"})
+	config := Config{&configImpl{
+		environ:  &env,
+		distDir:  "out/dist",
+		parallel: 8,
+		settingSources: map[string]settingSource{
+			"TARGET_PRODUCT": {"aosp_arm64", "/home/user/.soong_ui_profile.json"},
+			"OUT_DIR":        {"out", settingFromEnvironment},
+			"MY_FLAGS":       {"--fast", ".soong_ui_profile.json"},
+		},
+	}}
+
+	got := DumpEffectiveConfig(config)
+	for _, want := range []string{
+		"highest precedence first: the environment, /home/user/.soong_ui_profile.json, .soong_ui_profile.json\n",
+		"MY_FLAGS=\"--fast\" (from the profile .soong_ui_profile.json)\n",
+		"OUT_DIR=\"out\" (from the environment)\n    the output directory\n",
+		"TARGET_PRODUCT=\"aosp_arm64\" (from the profile /home/user/.soong_ui_profile.json)\n",
+		"USE_RBE is unset\n",
+		"  parallel: 8\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("want %q in:\n%s", want, got)
+		}
+	}
+}
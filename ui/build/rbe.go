@@ -53,10 +53,10 @@ func rbeCommand(ctx Context, config Config, rbeCmd string) string {
 	return cmdPath
 }
 
-func sockAddr(dir string) (string, error) {
+func sockAddr(dir, prefix string) (string, error) {
 	maxNameLen := len(syscall.RawSockaddrUnix{}.Path)
 	rand.Seed(time.Now().UnixNano())
-	base := fmt.Sprintf("reproxy_%v.sock", rand.Intn(1000))
+	base := fmt.Sprintf("%s_%v.sock", prefix, rand.Intn(1000))
 
 	name := filepath.Join(dir, base)
 	if len(name) < maxNameLen {
@@ -80,7 +80,7 @@ func getRBEVars(ctx Context, config Config) map[string]string {
 		"RBE_output_dir": config.rbeStatsOutputDir(),
 	}
 	if config.StartRBE() {
-		name, err := sockAddr(absPath(ctx, config.TempDir()))
+		name, err := sockAddr(absPath(ctx, config.TempDir()), "reproxy")
 		if err != nil {
 			ctx.Fatalf("Error retrieving socket address: %v", err)
 			return nil
@@ -123,26 +123,40 @@ func stopRBE(ctx Context, config Config) {
 	}
 }
 
-// DumpRBEMetrics creates a metrics protobuf file containing RBE related metrics.
-// The protobuf file is created if RBE is enabled and the proxy service has
-// started. The proxy service is shutdown in order to dump the RBE metrics to the
-// protobuf file.
-func DumpRBEMetrics(ctx Context, config Config, filename string) {
-	ctx.BeginTrace(metrics.RunShutdownTool, "dump_rbe_metrics")
-	defer ctx.EndTrace()
+// rbeBackend runs actions with RBE through reproxy.
+type rbeBackend struct{}
 
-	// Remove the previous metrics file in case there is a failure or RBE has been
-	// disable for this run.
-	os.Remove(filename)
+func (rbeBackend) Name() string {
+	return "RBE"
+}
 
-	// If RBE is not enabled then there are no metrics to generate.
-	// If RBE does not require to start, the RBE proxy maybe started
-	// manually for debugging purpose and can generate the metrics
-	// afterwards.
-	if !config.StartRBE() {
-		return
-	}
+func (rbeBackend) Enabled(config Config) bool {
+	return config.UseRBE()
+}
+
+func (rbeBackend) ShouldStart(config Config) bool {
+	return config.StartRBE()
+}
+
+func (rbeBackend) Env(ctx Context, config Config) map[string]string {
+	return getRBEVars(ctx, config)
+}
+
+func (rbeBackend) Parallel(config Config) int {
+	return defaultRemoteParallel
+}
+
+func (rbeBackend) Start(ctx Context, config Config) {
+	startRBE(ctx, config)
+}
+
+func (rbeBackend) Stop(ctx Context, config Config, metricsFile string) {
+	dumpRBEMetrics(ctx, config, metricsFile)
+}
 
+// dumpRBEMetrics stops the proxy in order to dump the RBE metrics to its
+// output directory, and copies them to filename.
+func dumpRBEMetrics(ctx Context, config Config, filename string) {
 	outputDir := config.rbeStatsOutputDir()
 	if outputDir == "" {
 		ctx.Fatal("RBE output dir variable not defined. Aborting metrics dumping.")
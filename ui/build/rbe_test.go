@@ -62,7 +62,7 @@ func TestDumpRBEMetrics(t *testing.T) {
 			}}
 
 			rbeMetricsFilename := filepath.Join(tmpDir, rbeMetricsPBFilename)
-			DumpRBEMetrics(ctx, config, rbeMetricsFilename)
+			DumpRBEMetrics(ctx, config, tmpDir, "")
 
 			// Validate that the rbe metrics file exists if RBE is enabled.
 			if _, err := os.Stat(rbeMetricsFilename); err == nil {
@@ -117,8 +117,7 @@ func TestDumpRBEMetricsErrors(t *testing.T) {
 				environ: env,
 			}}
 
-			rbeMetricsFilename := filepath.Join(tmpDir, rbeMetricsPBFilename)
-			DumpRBEMetrics(ctx, config, rbeMetricsFilename)
+			DumpRBEMetrics(ctx, config, tmpDir, "")
 			t.Errorf("got nil, expecting %q as a failure", tt.expectedErr)
 		})
 	}
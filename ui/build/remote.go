@@ -0,0 +1,216 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"android/soong/ui/metrics"
+)
+
+// The default number of remote jobs run in parallel by ninja.
+const defaultRemoteParallel = 500
+
+// RemoteBackend is a remote execution service that build actions are sent to,
+// usually through a local proxy that soong_ui starts before the build and
+// stops after it.
+type RemoteBackend interface {
+	// Name returns the name of the backend for logs.
+	Name() string
+
+	// Enabled returns whether the build uses the backend.
+	Enabled(config Config) bool
+
+	// ShouldStart returns whether soong_ui starts and stops the backend's
+	// proxy, rather than the user.
+	ShouldStart(config Config) bool
+
+	// Env returns the environment variables that configure the proxy and the
+	// build actions that talk to it. They are set when the config is created.
+	Env(ctx Context, config Config) map[string]string
+
+	// Parallel returns the number of remote jobs ninja runs in parallel when
+	// NINJA_REMOTE_NUM_JOBS isn't set. Backends whose actions aren't put in
+	// the remote pools by Soong and Kati return 0, and don't change the
+	// parallelism of the build.
+	Parallel(config Config) int
+
+	// Start starts the backend's proxy before the build.
+	Start(ctx Context, config Config)
+
+	// Stop stops the backend's proxy after the build, and writes the metrics
+	// collected by the proxy to metricsFile if it has any. Each backend has a
+	// metrics file of its own, see remoteMetricsFile.
+	Stop(ctx Context, config Config, metricsFile string)
+}
+
+var remoteBackends = []RemoteBackend{
+	rbeBackend{},
+	gomaBackend{},
+	reapiProxyBackend{},
+}
+
+// RegisterRemoteBackend adds a remote execution backend. It must be called
+// before the config is created.
+func RegisterRemoteBackend(backend RemoteBackend) {
+	remoteBackends = append(remoteBackends, backend)
+}
+
+// enabledRemoteBackends returns the remote execution backends used by the
+// build.
+func enabledRemoteBackends(config Config) []RemoteBackend {
+	var ret []RemoteBackend
+	for _, backend := range remoteBackends {
+		if backend.Enabled(config) {
+			ret = append(ret, backend)
+		}
+	}
+	return ret
+}
+
+// startRemoteBackends starts the proxies of the enabled remote execution
+// backends that soong_ui manages.
+func startRemoteBackends(ctx Context, config Config) {
+	for _, backend := range startedRemoteBackends(config) {
+		ctx.Verbosef("Starting the %s remote execution backend", backend.Name())
+		backend.Start(ctx, config)
+	}
+}
+
+// startedRemoteBackends returns the enabled remote execution backends whose
+// proxies soong_ui starts and stops.
+func startedRemoteBackends(config Config) []RemoteBackend {
+	var ret []RemoteBackend
+	for _, backend := range enabledRemoteBackends(config) {
+		if backend.ShouldStart(config) {
+			ret = append(ret, backend)
+		}
+	}
+	return ret
+}
+
+// remoteMetricsFile returns the file in logsDir that the metrics of a remote
+// execution backend are written to. The RBE metrics are written to the RBE
+// metrics protobuf file, and the metrics of other backends to
+// <logsPrefix><name>_metrics, so that they don't overwrite it.
+func remoteMetricsFile(backend RemoteBackend, logsDir, logsPrefix string) string {
+	if _, ok := backend.(rbeBackend); ok {
+		return filepath.Join(logsDir, logsPrefix+rbeMetricsPBFilename)
+	}
+	name := strings.ToLower(strings.Replace(backend.Name(), " ", "_", -1))
+	return filepath.Join(logsDir, logsPrefix+name+"_metrics")
+}
+
+// RemoteMetricsFiles returns the files that DumpRBEMetrics writes the metrics
+// of the remote execution backends other than RBE to, so that they can be
+// uploaded along with the RBE metrics protobuf file.
+func RemoteMetricsFiles(config Config, logsDir, logsPrefix string) []string {
+	var files []string
+	for _, backend := range startedRemoteBackends(config) {
+		if _, ok := backend.(rbeBackend); !ok {
+			files = append(files, remoteMetricsFile(backend, logsDir, logsPrefix))
+		}
+	}
+	return files
+}
+
+// DumpRBEMetrics shuts down the proxies of the remote execution backends
+// started by soong_ui in order to dump their metrics to files in logsDir. The
+// RBE metrics are written to <logsPrefix>rbe_metrics.pb, and the metrics of
+// other backends to the files returned by RemoteMetricsFiles.
+func DumpRBEMetrics(ctx Context, config Config, logsDir, logsPrefix string) {
+	ctx.BeginTrace(metrics.RunShutdownTool, "dump_rbe_metrics")
+	defer ctx.EndTrace()
+
+	// If a backend doesn't require to start, its proxy may have been
+	// started manually for debugging purpose and can generate the metrics
+	// afterwards.
+	for _, backend := range remoteBackends {
+		metricsFile := remoteMetricsFile(backend, logsDir, logsPrefix)
+		// Remove the previous metrics file in case there is a failure or the
+		// backend has been disabled for this run.
+		os.Remove(metricsFile)
+		if backend.Enabled(config) && backend.ShouldStart(config) {
+			backend.Stop(ctx, config, metricsFile)
+		}
+	}
+}
+
+// reapiProxyBackend runs actions through a generic REAPI compatible proxy. The
+// proxy is controlled by the program in $REAPI_PROXY, which is run as
+// "$REAPI_PROXY start" before the build and "$REAPI_PROXY stop" after it,
+// unless NOSTART_REAPI_PROXY is set. The proxy listens on
+// $REAPI_PROXY_SOCKET, writes its logs to $REAPI_PROXY_LOG_DIR, and its
+// metrics to $REAPI_PROXY_METRICS_FILE when it stops, which is
+// reapi_proxy_metrics in the logs directory rather than the RBE metrics file.
+//
+// Actions are sent to the proxy by wrappers set up by the product or the
+// environment, such as CC_WRAPPER, so they aren't in the remote pools and
+// the parallelism of the build isn't changed.
+type reapiProxyBackend struct{}
+
+func (reapiProxyBackend) Name() string {
+	return "REAPI proxy"
+}
+
+func (reapiProxyBackend) Enabled(config Config) bool {
+	proxy, ok := config.Environment().Get("REAPI_PROXY")
+	return ok && proxy != ""
+}
+
+func (reapiProxyBackend) ShouldStart(config Config) bool {
+	return !config.Environment().IsEnvTrue("NOSTART_REAPI_PROXY")
+}
+
+func (reapiProxyBackend) Env(ctx Context, config Config) map[string]string {
+	vars := map[string]string{
+		"REAPI_PROXY_LOG_DIR": config.LogsDir(),
+	}
+	if _, ok := config.Environment().Get("REAPI_PROXY_SOCKET"); !ok {
+		name, err := sockAddr(absPath(ctx, config.TempDir()), "reapi_proxy")
+		if err != nil {
+			ctx.Fatalf("Error retrieving socket address: %v", err)
+		}
+		vars["REAPI_PROXY_SOCKET"] = fmt.Sprintf("unix://%v", name)
+	}
+	return vars
+}
+
+func (reapiProxyBackend) Parallel(config Config) int {
+	return 0
+}
+
+func (reapiProxyBackend) Start(ctx Context, config Config) {
+	ctx.BeginTrace(metrics.RunSetupTool, "reapi_proxy_start")
+	defer ctx.EndTrace()
+
+	proxy, _ := config.Environment().Get("REAPI_PROXY")
+	cmd := Command(ctx, config, "REAPI proxy start", proxy, "start")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		ctx.Fatalf("Unable to start the REAPI proxy\nFAILED: %s start failed with: %v\n%s\n", proxy, err, output)
+	}
+}
+
+func (reapiProxyBackend) Stop(ctx Context, config Config, metricsFile string) {
+	proxy, _ := config.Environment().Get("REAPI_PROXY")
+	cmd := Command(ctx, config, "REAPI proxy stop", proxy, "stop")
+	cmd.Environment.Set("REAPI_PROXY_METRICS_FILE", metricsFile)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		ctx.Fatalf("%s stop failed with: %v\n%s\n", proxy, err, output)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeBackend is a remote execution backend that records its calls.
+type fakeBackend struct {
+	name     string
+	parallel int
+	calls    *[]string
+}
+
+func (f fakeBackend) Name() string {
+	return f.name
+}
+
+func (f fakeBackend) Enabled(config Config) bool {
+	return config.Environment().IsEnvTrue("USE_" + f.name)
+}
+
+func (f fakeBackend) ShouldStart(config Config) bool {
+	return !config.Environment().IsEnvTrue("NOSTART_" + f.name)
+}
+
+func (f fakeBackend) Env(ctx Context, config Config) map[string]string {
+	return map[string]string{f.name + "_SOCKET": "unix:///tmp/" + f.name}
+}
+
+func (f fakeBackend) Parallel(config Config) int {
+	return f.parallel
+}
+
+func (f fakeBackend) Start(ctx Context, config Config) {
+	*f.calls = append(*f.calls, "start "+f.name)
+}
+
+func (f fakeBackend) Stop(ctx Context, config Config, metricsFile string) {
+	*f.calls = append(*f.calls, "stop "+f.name+" "+metricsFile)
+}
+
+func withFakeBackends(t *testing.T, backends ...RemoteBackend) {
+	saved := remoteBackends
+	remoteBackends = backends
+	t.Cleanup(func() { remoteBackends = saved })
+}
+
+func TestRemoteBackends(t *testing.T) {
+	ctx := testContext()
+	var calls []string
+	withFakeBackends(t,
+		fakeBackend{name: "FAST", parallel: 100, calls: &calls},
+		fakeBackend{name: "WIDE", parallel: 1000, calls: &calls},
+		fakeBackend{name: "WRAPPED", calls: &calls})
+
+	tests := []struct {
+		description    string
+		env            []string
+		remoteParallel int
+		calls          []string
+	}{
+		{
+			description: "no backends",
+		},
+		{
+			description:    "one backend",
+			env:            []string{"USE_FAST=true"},
+			remoteParallel: 100,
+			calls:          []string{"start FAST", "stop FAST logs/fast_metrics"},
+		},
+		{
+			description:    "largest parallelism",
+			env:            []string{"USE_FAST=true", "USE_WIDE=true", "NOSTART_WIDE=true"},
+			remoteParallel: 1000,
+			calls:          []string{"start FAST", "stop FAST logs/fast_metrics"},
+		},
+		{
+			description:    "NINJA_REMOTE_NUM_JOBS",
+			env:            []string{"USE_WIDE=true", "NINJA_REMOTE_NUM_JOBS=10"},
+			remoteParallel: 10,
+			calls:          []string{"start WIDE", "stop WIDE logs/wide_metrics"},
+		},
+		{
+			description: "backend outside of the remote pools",
+			env:         []string{"USE_WRAPPED=true"},
+			calls:       []string{"start WRAPPED", "stop WRAPPED logs/wrapped_metrics"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			calls = nil
+			env := Environment(tt.env)
+			config := Config{&configImpl{environ: &env}}
+
+			if g, w := config.UseRemoteBuild(), tt.remoteParallel > 0; g != w {
+				t.Errorf("want UseRemoteBuild() %t, got %t", w, g)
+			}
+			if g, w := config.RemoteParallel(), tt.remoteParallel; g != w {
+				t.Errorf("want RemoteParallel() %d, got %d", w, g)
+			}
+
+			startRemoteBackends(ctx, config)
+			DumpRBEMetrics(ctx, config, "logs", "")
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("want calls %q, got %q", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestREAPIProxyBackend(t *testing.T) {
+	ctx := testContext()
+	tmpDir := t.TempDir()
+	withFakeBackends(t, reapiProxyBackend{})
+
+	proxy := filepath.Join(tmpDir, "proxy")
+	log := filepath.Join(tmpDir, "proxy.log")
+	script := "#!/bin/bash\n" +
+		"echo \"$1 $REAPI_PROXY_SOCKET\" >> " + log + "\n" +
+		"if [[ $1 == stop ]]; then echo metrics > $REAPI_PROXY_METRICS_FILE; fi\n"
+	if err := ioutil.WriteFile(proxy, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	env := Environment([]string{
+		"REAPI_PROXY=" + proxy,
+		"REAPI_PROXY_SOCKET=unix:///tmp/proxy.sock",
+		"OUT_DIR=" + tmpDir,
+	})
+	config := Config{&configImpl{environ: &env}}
+
+	if config.UseRemoteBuild() {
+		t.Errorf("the REAPI proxy shouldn't use the remote pools")
+	}
+	wantEnv := map[string]string{"REAPI_PROXY_LOG_DIR": tmpDir}
+	if g := (reapiProxyBackend{}).Env(ctx, config); !reflect.DeepEqual(g, wantEnv) {
+		t.Errorf("want env %v, got %v", wantEnv, g)
+	}
+
+	startRemoteBackends(ctx, config)
+	DumpRBEMetrics(ctx, config, tmpDir, "test_")
+
+	data, err := ioutil.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := string(data), "start unix:///tmp/proxy.sock\nstop unix:///tmp/proxy.sock\n"; g != w {
+		t.Errorf("want proxy calls %q, got %q", w, g)
+	}
+	metricsFile := filepath.Join(tmpDir, "test_reapi_proxy_metrics")
+	if data, err := ioutil.ReadFile(metricsFile); err != nil || strings.TrimSpace(string(data)) != "metrics" {
+		t.Errorf("want the proxy's metrics in %s, got %q, %v", metricsFile, data, err)
+	}
+	if g, w := RemoteMetricsFiles(config, tmpDir, "test_"), []string{metricsFile}; !reflect.DeepEqual(g, w) {
+		t.Errorf("want metrics files %q, got %q", w, g)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "test_"+rbeMetricsPBFilename)); !os.IsNotExist(err) {
+		t.Errorf("want no RBE metrics file, got %v", err)
+	}
+}
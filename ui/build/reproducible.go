@@ -0,0 +1,546 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/ui/metrics"
+)
+
+// The directories of the product out directory that hold installed files,
+// which are compared between the two builds.
+var reproducibleInstallDirs = []string{
+	"data",
+	"debug_ramdisk",
+	"odm",
+	"odm_dlkm",
+	"oem",
+	"product",
+	"ramdisk",
+	"recovery",
+	"root",
+	"system",
+	"system_ext",
+	"system_other",
+	"vendor",
+	"vendor_debug_ramdisk",
+	"vendor_dlkm",
+	"vendor_ramdisk",
+}
+
+// The directories of the output directory that hold intermediate files, which
+// are compared between the two builds too, along with PRODUCT_OUT/obj.
+var reproducibleIntermediateDirs = []string{
+	"soong/.intermediates",
+}
+
+// The allowlists of known differences between builds, shared with
+// diff_target_files.
+var reproducibleAllowlists = []string{
+	"build/soong/cmd/diff_target_files/known_nondeterminism.whitelist",
+	"build/soong/cmd/diff_target_files/props.whitelist",
+}
+
+// The number of files queried from ninja at once.
+const ninjaQueryBatch = 200
+
+// reproducibleDiff is the list of files that differ between the builds, as
+// printed by diff_target_files -json.
+type reproducibleDiff struct {
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+	Added    []string `json:"added"`
+}
+
+// ninjaNode is an output file in the ninja graph, with the rule that
+// produces it and its explicit and implicit inputs.
+type ninjaNode struct {
+	rule   string
+	inputs []string
+}
+
+// VerifyReproducible builds targets twice, in two output directories under the
+// output directory and with a different build date, user and host, compares
+// the installed and intermediate files of the builds with diff_target_files
+// and its allowlists, and reports the first actions whose outputs differed for
+// each file that differs.
+func VerifyReproducible(ctx Context, config Config, targets, allowlists []string) {
+	ctx.BeginTrace(metrics.RunSetupTool, "verify_reproducible")
+	defer ctx.EndTrace()
+
+	if len(targets) == 0 {
+		targets = []string{"droid"}
+	}
+
+	soongUI, err := os.Executable()
+	if err != nil {
+		ctx.Fatalf("Unable to locate soong_ui: %v", err)
+	}
+
+	vars, err := DumpMakeVars(ctx, config, nil, []string{"PRODUCT_OUT"})
+	if err != nil {
+		ctx.Fatal(err)
+	}
+	productOut, err := filepath.Rel(config.OutDir(), vars["PRODUCT_OUT"])
+	if err != nil {
+		ctx.Fatalf("PRODUCT_OUT %q is not in the output directory: %v", vars["PRODUCT_OUT"], err)
+	}
+
+	dir := filepath.Join(config.OutDir(), "reproducible")
+	outDirs := [2]string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	buildDateTime := time.Now().Unix()
+	for i, outDir := range outDirs {
+		name := filepath.Base(outDir)
+		args := append(append([]string{"--make-mode"}, targets...), "diff_target_files")
+		cmd := Command(ctx, config, "build "+name, soongUI, args...)
+		cmd.Environment = OsEnvironment()
+		cmd.Environment.Unset("DIST_DIR")
+		cmd.Environment.Set("OUT_DIR", outDir)
+		// Perturb the inputs of the build that are expected not to change
+		// its outputs, except where the allowlists say so.
+		cmd.Environment.Set("BUILD_DATETIME", strconv.FormatInt(buildDateTime+int64(i)*(25*60*60), 10))
+		cmd.Environment.Set("BUILD_USERNAME", "reproducible-"+name)
+		cmd.Environment.Set("BUILD_HOSTNAME", "reproducible-"+name+".example.com")
+
+		ctx.Printf("Building %s in %s\n", strings.Join(targets, " "), outDir)
+		cmd.RunAndStreamOrFatal()
+	}
+
+	// diff_target_files is built by both builds to keep them the same.
+	diffTool := filepath.Join(outDirs[1], "host", config.HostPrebuiltTag(), "bin", "diff_target_files")
+
+	var zips [2]string
+	for i, outDir := range outDirs {
+		zips[i] = filepath.Join(dir, filepath.Base(outDir)+"-installed.zip")
+		zipInstalledFiles(ctx, filepath.Join(outDir, productOut), zips[i])
+	}
+	installedDiff := diffReproducible(ctx, config, diffTool, zips, allowlists)
+
+	// The intermediates are too large to zip, so they are compared directly
+	// and only the files that differ are zipped, for diff_target_files to
+	// apply the allowlists to them.
+	intermediateDirs := append([]string{filepath.Join(productOut, "obj")}, reproducibleIntermediateDirs...)
+	differing := differingIntermediates(ctx, outDirs, intermediateDirs)
+	for i, outDir := range outDirs {
+		zips[i] = filepath.Join(dir, filepath.Base(outDir)+"-intermediates.zip")
+		zipIntermediates(ctx, outDir, differing, zips[i])
+	}
+	intermediatesDiff := diffReproducible(ctx, config, diffTool, zips, allowlists)
+
+	// Find the first actions that produced different outputs from the ninja
+	// graph of the first build.
+	query := func(files []string) map[string]ninjaNode {
+		return queryNinja(ctx, config, outDirs[0], files)
+	}
+	same := func(file string) bool {
+		return sameFile(file, outDirs[0], filepath.Join(outDirs[1], strings.TrimPrefix(file, outDirs[0])), outDirs[1])
+	}
+	installedCulprits := make(map[string][]string)
+	for _, name := range installedDiff.Modified {
+		installed := filepath.Join(outDirs[0], productOut, name)
+		installedCulprits[name] = firstDifferingActions(installed, outDirs[0], query, same)
+	}
+	intermediateCulprits := make(map[string][]string)
+	for _, name := range intermediatesDiff.Modified {
+		intermediate := filepath.Join(outDirs[0], name)
+		intermediateCulprits[name] = firstDifferingActions(intermediate, outDirs[0], query, same)
+	}
+
+	report := formatReproducibleReport(installedDiff, intermediatesDiff, installedCulprits, intermediateCulprits, outDirs[0])
+	reportFile := filepath.Join(config.LogsDir(), "reproducible.txt")
+	if err := ioutil.WriteFile(reportFile, []byte(report), 0666); err != nil {
+		ctx.Fatalf("Failed to write %s: %v", reportFile, err)
+	}
+	fmt.Fprint(ctx.Writer, report)
+
+	if !installedDiff.empty() || !intermediatesDiff.empty() {
+		ctx.Fatalf("The builds are not reproducible, see %s", reportFile)
+	}
+}
+
+func (d reproducibleDiff) empty() bool {
+	return len(d.Modified) == 0 && len(d.Removed) == 0 && len(d.Added) == 0
+}
+
+// diffReproducible compares the files in two zip files with diff_target_files
+// and the default and extra allowlists.
+func diffReproducible(ctx Context, config Config, diffTool string, zips [2]string, allowlists []string) reproducibleDiff {
+	args := []string{"-json", "-artifact", "installed"}
+	for _, allowlist := range append(append([]string(nil), reproducibleAllowlists...), allowlists...) {
+		args = append(args, "-allowlist_file", allowlist)
+	}
+	args = append(args, zips[0], zips[1])
+	cmd := Command(ctx, config, "diff_target_files", diffTool, args...)
+	// diff_target_files exits with an error if there are differences.
+	output, _ := cmd.Output()
+	var diff reproducibleDiff
+	if err := json.Unmarshal(output, &diff); err != nil {
+		ctx.Fatalf("Failed to compare the builds with diff_target_files: %v\n%s", err, output)
+	}
+	return diff
+}
+
+// zipInstalledFiles stores the installed files in productOut in a zip file,
+// named after their paths in productOut. Symlinks are stored as the paths
+// they point to.
+func zipInstalledFiles(ctx Context, productOut, out string) {
+	f, err := os.Create(out)
+	if err != nil {
+		ctx.Fatalf("Failed to create %s: %v", out, err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, dir := range reproducibleInstallDirs {
+		err := filepath.Walk(filepath.Join(productOut, dir), func(path string, info os.FileInfo, err error) error {
+			if os.IsNotExist(err) && path == filepath.Join(productOut, dir) {
+				return nil
+			} else if err != nil {
+				return err
+			} else if info.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(productOut, path)
+			if err != nil {
+				return err
+			}
+			return zipInstalledFile(w, path, rel, info)
+		})
+		if err != nil {
+			ctx.Fatalf("Failed to zip the installed files of %s: %v", productOut, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		ctx.Fatalf("Failed to write %s: %v", out, err)
+	}
+}
+
+func zipInstalledFile(w *zip.Writer, path, name string, info os.FileInfo) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Store}
+	header.SetMode(info.Mode())
+	entry, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		dest, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(entry, dest)
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(entry, f)
+	return err
+}
+
+// queryNinja returns the rules and inputs of files in the ninja graph of the
+// build in outDir. Files that aren't in the graph are left out.
+func queryNinja(ctx Context, config Config, outDir string, files []string) map[string]ninjaNode {
+	ninjaFiles, _ := filepath.Glob(filepath.Join(outDir, "combined*.ninja"))
+	if len(ninjaFiles) == 0 {
+		ctx.Fatalf("No combined ninja file in %s", outDir)
+	}
+	sort.Slice(ninjaFiles, func(i, j int) bool {
+		return fileModTime(ninjaFiles[i]).After(fileModTime(ninjaFiles[j]))
+	})
+
+	ret := make(map[string]ninjaNode)
+	for len(files) > 0 {
+		batch := files
+		if len(batch) > ninjaQueryBatch {
+			batch = batch[:ninjaQueryBatch]
+		}
+		files = files[len(batch):]
+
+		args := append([]string{"-f", ninjaFiles[0], "-t", "query"}, batch...)
+		output, err := Command(ctx, config, "ninja query", config.PrebuiltBuildTool("ninja"), args...).Output()
+		if err != nil && len(batch) > 1 {
+			// One of the files isn't in the graph, query them one at a time.
+			for _, file := range batch {
+				for k, v := range queryNinja(ctx, config, outDir, []string{file}) {
+					ret[k] = v
+				}
+			}
+			continue
+		} else if err != nil {
+			continue
+		}
+		for k, v := range parseNinjaQuery(output) {
+			ret[k] = v
+		}
+	}
+	return ret
+}
+
+func fileModTime(file string) time.Time {
+	if info, err := os.Stat(file); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// parseNinjaQuery parses the output of ninja -t query, which is of the form:
+//
+//	out/foo.o:
+//	  input: cc
+//	    foo.c
+//	    | foo.h
+//	    || out/order_only
+//	  outputs:
+//	    out/foo.a
+//
+// Order-only inputs don't change the output of a rule, so they are left out.
+func parseNinjaQuery(output []byte) map[string]ninjaNode {
+	ret := make(map[string]ninjaNode)
+	var file string
+	var node ninjaNode
+	inInputs := false
+	flush := func() {
+		if file != "" {
+			ret[file] = node
+		}
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		switch {
+		case line == "":
+		case !strings.HasPrefix(line, " "):
+			flush()
+			file = strings.TrimSuffix(line, ":")
+			node = ninjaNode{}
+			inInputs = false
+		case strings.HasPrefix(line, "  input: "):
+			node.rule = strings.TrimPrefix(line, "  input: ")
+			inInputs = true
+		case strings.HasPrefix(line, "    ") && inInputs:
+			input := strings.TrimSpace(line)
+			if strings.HasPrefix(input, "|| ") {
+				continue
+			}
+			node.inputs = append(node.inputs, strings.TrimPrefix(input, "| "))
+		default:
+			inInputs = false
+		}
+	}
+	flush()
+	return ret
+}
+
+// firstDifferingActions returns the outputs of the actions that an installed
+// file was produced from that produced different outputs in the two builds,
+// even though all of their inputs in outDir were the same. They are described
+// as "<output> (<rule>)".
+func firstDifferingActions(installed, outDir string, query func([]string) map[string]ninjaNode,
+	same func(string) bool) []string {
+
+	var ret []string
+	visited := map[string]bool{installed: true}
+	queue := []string{installed}
+	for len(queue) > 0 {
+		nodes := query(queue)
+		var next []string
+		for _, file := range queue {
+			node, ok := nodes[file]
+			if !ok {
+				continue
+			}
+			differingInputs := 0
+			for _, input := range node.inputs {
+				if !strings.HasPrefix(input, outDir+"/") {
+					// Source files are the same in both builds.
+					continue
+				}
+				if same(input) {
+					continue
+				}
+				differingInputs++
+				if !visited[input] {
+					visited[input] = true
+					next = append(next, input)
+				}
+			}
+			if differingInputs == 0 && node.rule != "phony" {
+				ret = append(ret, fmt.Sprintf("%s (%s)", file, node.rule))
+			}
+		}
+		queue = next
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// sameFile returns whether two files in the output directories of the builds
+// have the same contents apart from the paths of the output directories, or
+// both don't exist.
+func sameFile(a, outDirA, b, outDirB string) bool {
+	dataA, errA := readOutputFile(a, outDirA)
+	dataB, errB := readOutputFile(b, outDirB)
+	if errA != nil || errB != nil {
+		return os.IsNotExist(errA) && os.IsNotExist(errB)
+	}
+	return bytes.Equal(dataA, dataB)
+}
+
+// readOutputFile returns the contents of a file in outDir, with the paths of
+// outDir replaced by $OUT_DIR, as they differ between the builds by design.
+// Directories have no contents.
+func readOutputFile(file, outDir string) ([]byte, error) {
+	if info, err := os.Stat(file); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if abs, err := filepath.Abs(outDir); err == nil {
+		data = bytes.Replace(data, []byte(abs), []byte("$OUT_DIR"), -1)
+	}
+	return bytes.Replace(data, []byte(outDir), []byte("$OUT_DIR"), -1), nil
+}
+
+// differingIntermediates returns the files in dirs, relative to the output
+// directories of the builds, that differ between the builds or are only in one
+// of them.
+func differingIntermediates(ctx Context, outDirs [2]string, dirs []string) []string {
+	files := make(map[string]bool)
+	for _, outDir := range outDirs {
+		for _, dir := range dirs {
+			err := filepath.Walk(filepath.Join(outDir, dir), func(path string, info os.FileInfo, err error) error {
+				if os.IsNotExist(err) && path == filepath.Join(outDir, dir) {
+					return nil
+				} else if err != nil {
+					return err
+				} else if info.IsDir() {
+					return nil
+				}
+				rel, err := filepath.Rel(outDir, path)
+				if err != nil {
+					return err
+				}
+				files[rel] = true
+				return nil
+			})
+			if err != nil {
+				ctx.Fatalf("Failed to list the intermediates of %s: %v", outDir, err)
+			}
+		}
+	}
+
+	var ret []string
+	for file := range files {
+		if !sameFile(filepath.Join(outDirs[0], file), outDirs[0], filepath.Join(outDirs[1], file), outDirs[1]) {
+			ret = append(ret, file)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// zipIntermediates stores the files in outDir, named after their paths in
+// outDir, in a zip file, with the paths of outDir replaced as in
+// readOutputFile. Files that don't exist in outDir are left out.
+func zipIntermediates(ctx Context, outDir string, files []string, out string) {
+	f, err := os.Create(out)
+	if err != nil {
+		ctx.Fatalf("Failed to create %s: %v", out, err)
+	}
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, file := range files {
+		data, err := readOutputFile(filepath.Join(outDir, file), outDir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			ctx.Fatalf("Failed to read %s: %v", file, err)
+		}
+		entry, err := w.CreateHeader(&zip.FileHeader{Name: file, Method: zip.Store})
+		if err == nil {
+			_, err = entry.Write(data)
+		}
+		if err != nil {
+			ctx.Fatalf("Failed to write %s: %v", out, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		ctx.Fatalf("Failed to write %s: %v", out, err)
+	}
+}
+
+// formatReproducibleReport returns the text report of the differences between
+// the builds.
+func formatReproducibleReport(installed, intermediates reproducibleDiff,
+	installedCulprits, intermediateCulprits map[string][]string, outDir string) string {
+
+	sb := &strings.Builder{}
+	if installed.empty() && intermediates.empty() {
+		fmt.Fprintln(sb, "The installed and intermediate files of both builds are the same.")
+		return sb.String()
+	}
+	formatReproducibleDiff(sb, "installed files", installed, installedCulprits, outDir)
+	formatReproducibleDiff(sb, "intermediate files", intermediates, intermediateCulprits, outDir)
+	return sb.String()
+}
+
+func formatReproducibleDiff(sb *strings.Builder, kind string, diff reproducibleDiff,
+	culprits map[string][]string, outDir string) {
+
+	if len(diff.Modified) > 0 {
+		fmt.Fprintf(sb, "%d %s differ between the builds:\n", len(diff.Modified), kind)
+		for _, name := range diff.Modified {
+			fmt.Fprintf(sb, "  %s\n", name)
+			if len(culprits[name]) == 0 {
+				fmt.Fprintln(sb, "    no action found in the ninja graph")
+			}
+			for _, culprit := range culprits[name] {
+				fmt.Fprintf(sb, "    first differing action: %s\n", strings.TrimPrefix(culprit, outDir+"/"))
+			}
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(sb, "%d %s are only in the first build:\n", len(diff.Removed), kind)
+		for _, name := range diff.Removed {
+			fmt.Fprintf(sb, "  %s\n", name)
+		}
+	}
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(sb, "%d %s are only in the second build:\n", len(diff.Added), kind)
+		for _, name := range diff.Added {
+			fmt.Fprintf(sb, "  %s\n", name)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNinjaQuery(t *testing.T) {
+	output := strings.Join([]string{
+		"out/target/product/generic/system/lib/libfoo.so:",
+		"  input: Cp",
+		"    out/soong/.intermediates/foo/libfoo/android_arm_armv7-a-neon_shared/libfoo.so",
+		"    | out/host/linux-x86/bin/acp",
+		"    || out/soong/.intermediates/foo/libfoo/deps",
+		"  outputs:",
+		"    droid",
+		"out/soong/.intermediates/foo/libfoo/android_arm_armv7-a-neon_shared/libfoo.so:",
+		"  input: ld",
+		"    foo/foo.c",
+		"  outputs:",
+		"",
+	}, "\n")
+
+	want := map[string]ninjaNode{
+		"out/target/product/generic/system/lib/libfoo.so": {
+			rule: "Cp",
+			inputs: []string{
+				"out/soong/.intermediates/foo/libfoo/android_arm_armv7-a-neon_shared/libfoo.so",
+				"out/host/linux-x86/bin/acp",
+			},
+		},
+		"out/soong/.intermediates/foo/libfoo/android_arm_armv7-a-neon_shared/libfoo.so": {
+			rule:   "ld",
+			inputs: []string{"foo/foo.c"},
+		},
+	}
+	if g := parseNinjaQuery([]byte(output)); !reflect.DeepEqual(g, want) {
+		t.Errorf("want %v, got %v", want, g)
+	}
+}
+
+func TestFirstDifferingActions(t *testing.T) {
+	graph := map[string]ninjaNode{
+		"out/system/app.apk":         {rule: "Cp", inputs: []string{"out/obj/app.apk"}},
+		"out/obj/app.apk":            {rule: "zip", inputs: []string{"out/obj/classes.dex", "out/obj/res.zip"}},
+		"out/obj/classes.dex":        {rule: "d8", inputs: []string{"out/obj/classes.jar"}},
+		"out/obj/classes.jar":        {rule: "javac", inputs: []string{"app/Foo.java", "out/obj/build_date.txt"}},
+		"out/obj/build_date.txt":     {rule: "phony"},
+		"out/obj/res.zip":            {rule: "aapt2", inputs: []string{"app/res/values.xml"}},
+		"out/system/unrelated.apk":   {rule: "Cp", inputs: []string{"out/obj/unrelated.apk"}},
+		"out/obj/unrelated.apk":      {rule: "zip", inputs: []string{"app/unrelated.txt"}},
+		"out/obj/never_queried.file": {rule: "touch"},
+	}
+	differs := map[string]bool{
+		"out/system/app.apk":     true,
+		"out/obj/app.apk":        true,
+		"out/obj/classes.dex":    true,
+		"out/obj/classes.jar":    true,
+		"out/obj/res.zip":        true,
+		"out/obj/build_date.txt": false,
+	}
+
+	var queries int
+	query := func(files []string) map[string]ninjaNode {
+		queries++
+		ret := make(map[string]ninjaNode)
+		for _, file := range files {
+			if node, ok := graph[file]; ok {
+				ret[file] = node
+			}
+		}
+		return ret
+	}
+	same := func(file string) bool {
+		return !differs[file]
+	}
+
+	got := firstDifferingActions("out/system/app.apk", "out", query, same)
+	want := []string{"out/obj/classes.jar (javac)", "out/obj/res.zip (aapt2)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+	// One query for each level of the graph.
+	if queries != 4 {
+		t.Errorf("want 4 queries, got %d", queries)
+	}
+}
+
+func TestFormatReproducibleReport(t *testing.T) {
+	installed := reproducibleDiff{
+		Modified: []string{"system/app.apk", "system/lib/libbar.so"},
+		Added:    []string{"system/new.txt"},
+	}
+	intermediates := reproducibleDiff{
+		Removed: []string{"soong/.intermediates/foo/foo.d"},
+	}
+	culprits := map[string][]string{
+		"system/app.apk": {"out/obj/classes.jar (javac)"},
+	}
+	want := strings.Join([]string{
+		"2 installed files differ between the builds:",
+		"  system/app.apk",
+		"    first differing action: obj/classes.jar (javac)",
+		"  system/lib/libbar.so",
+		"    no action found in the ninja graph",
+		"1 installed files are only in the second build:",
+		"  system/new.txt",
+		"1 intermediate files are only in the first build:",
+		"  soong/.intermediates/foo/foo.d",
+		"",
+	}, "\n")
+	if g := formatReproducibleReport(installed, intermediates, culprits, nil, "out"); g != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, g)
+	}
+
+	if g := formatReproducibleReport(reproducibleDiff{}, reproducibleDiff{}, nil, nil, "out"); !strings.Contains(g, "same") {
+		t.Errorf("want a report that the builds are the same, got %q", g)
+	}
+}
+
+func TestDifferingIntermediates(t *testing.T) {
+	ctx := testContext()
+	tmpDir := t.TempDir()
+	outDirs := [2]string{filepath.Join(tmpDir, "a"), filepath.Join(tmpDir, "b")}
+
+	files := map[string][2]string{
+		// The paths of the output directories are expected to differ.
+		"soong/.intermediates/foo/foo.d": {outDirs[0] + "/foo.o: foo.c", outDirs[1] + "/foo.o: foo.c"},
+		"soong/.intermediates/foo/foo.o": {"same", "same"},
+		"soong/.intermediates/bar/bar.o": {"date 1", "date 2"},
+		"obj/baz/baz.o":                  {"baz", ""},
+		"other/qux.o":                    {"qux 1", "qux 2"},
+	}
+	for name, contents := range files {
+		for i, outDir := range outDirs {
+			if i == 1 && contents[i] == "" {
+				continue
+			}
+			file := filepath.Join(outDir, name)
+			if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(file, []byte(contents[i]), 0666); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	got := differingIntermediates(ctx, outDirs, []string{"obj", "soong/.intermediates", "missing"})
+	want := []string{"obj/baz/baz.o", "soong/.intermediates/bar/bar.o"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
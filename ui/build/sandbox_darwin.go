@@ -37,6 +37,15 @@ func init() {
 	}
 }
 
+// sandboxWorks returns whether sandbox-exec is available, and why not if it
+// isn't.
+func sandboxWorks(ctx Context, config Config) (bool, string) {
+	if sandboxExecPath == "" {
+		return false, "sandbox-exec not found"
+	}
+	return true, ""
+}
+
 func (c *Cmd) sandboxSupported() bool {
 	if c.Sandbox == "" {
 		return false
@@ -16,10 +16,12 @@ package build
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -29,22 +31,33 @@ type Sandbox struct {
 	DisableWhenUsingGoma bool
 
 	AllowBuildBrokenUsesNetwork bool
+
+	// The phase of the build, which selects the sandbox profile, see
+	// sandbox_profile.go.
+	Phase string
 }
 
 var (
-	noSandbox    = Sandbox{}
-	basicSandbox = Sandbox{
+	noSandbox = Sandbox{}
+
+	dumpvarsSandbox = Sandbox{
 		Enabled: true,
+		Phase:   sandboxPhaseDumpvars,
 	}
-
-	dumpvarsSandbox = basicSandbox
-	katiSandbox     = basicSandbox
-	soongSandbox    = basicSandbox
-	ninjaSandbox    = Sandbox{
+	katiSandbox = Sandbox{
+		Enabled: true,
+		Phase:   sandboxPhaseKati,
+	}
+	soongSandbox = Sandbox{
+		Enabled: true,
+		Phase:   sandboxPhaseSoong,
+	}
+	ninjaSandbox = Sandbox{
 		Enabled:              true,
 		DisableWhenUsingGoma: true,
 
 		AllowBuildBrokenUsesNetwork: true,
+		Phase:                       sandboxPhaseNinja,
 	}
 )
 
@@ -54,10 +67,16 @@ var sandboxConfig struct {
 	once sync.Once
 
 	working bool
+	// Why the nsjail probe failed, if it did.
+	failure string
 	group   string
 	srcDir  string
 	outDir  string
 	distDir string
+
+	// Why the probe failed for the selected sandbox profiles other than
+	// the default one, by name.
+	profileFailures map[string]string
 }
 
 func (c *Cmd) sandboxSupported() bool {
@@ -70,6 +89,18 @@ func (c *Cmd) sandboxSupported() bool {
 		return false
 	}
 
+	sandboxWorks(c.ctx, c.config)
+	profile := c.config.SandboxProfile(c.Sandbox.Phase)
+	if failure, ok := sandboxConfig.profileFailures[profile.Name]; ok {
+		// Don't silently run without the sandbox that was asked for.
+		c.ctx.Fatalf("The %q sandbox profile selected for %s doesn't work: %s", profile.Name, c.Sandbox.Phase, failure)
+	}
+	return sandboxConfig.working
+}
+
+// sandboxWorks returns whether nsjail works on this machine, and why not if
+// it doesn't. nsjail is probed the first time this is called.
+func sandboxWorks(ctx Context, config Config) (bool, string) {
 	sandboxConfig.once.Do(func() {
 		sandboxConfig.group = "nogroup"
 		if _, err := user.LookupGroup(sandboxConfig.group); err != nil {
@@ -78,68 +109,146 @@ func (c *Cmd) sandboxSupported() bool {
 
 		// These directories will be bind mounted
 		// so we need full non-symlink paths
-		sandboxConfig.srcDir = absPath(c.ctx, ".")
+		sandboxConfig.srcDir = absPath(ctx, ".")
 		if derefPath, err := filepath.EvalSymlinks(sandboxConfig.srcDir); err == nil {
-			sandboxConfig.srcDir = absPath(c.ctx, derefPath)
+			sandboxConfig.srcDir = absPath(ctx, derefPath)
 		}
-		sandboxConfig.outDir = absPath(c.ctx, c.config.OutDir())
+		sandboxConfig.outDir = absPath(ctx, config.OutDir())
 		if derefPath, err := filepath.EvalSymlinks(sandboxConfig.outDir); err == nil {
-			sandboxConfig.outDir = absPath(c.ctx, derefPath)
+			sandboxConfig.outDir = absPath(ctx, derefPath)
 		}
-		sandboxConfig.distDir = absPath(c.ctx, c.config.DistDir())
+		sandboxConfig.distDir = absPath(ctx, config.DistDir())
 		if derefPath, err := filepath.EvalSymlinks(sandboxConfig.distDir); err == nil {
-			sandboxConfig.distDir = absPath(c.ctx, derefPath)
+			sandboxConfig.distDir = absPath(ctx, derefPath)
 		}
 
-		sandboxArgs := []string{
-			"-H", "android-build",
-			"-e",
-			"-u", "nobody",
-			"-g", sandboxConfig.group,
-			"-R", "/",
-			"-B", sandboxConfig.srcDir,
-			"-B", "/tmp",
-			"-B", sandboxConfig.outDir,
+		defaultProfile := config.SandboxProfile("")
+		// Probe the default profile like before sandbox profiles existed, so
+		// that the build still runs without a sandbox if nsjail doesn't work.
+		sandboxConfig.working, sandboxConfig.failure = probeSandbox(ctx, config, defaultProfile)
+		if !sandboxConfig.working {
+			ctx.Println("Build sandboxing disabled due to nsjail error.")
+			ctx.Verboseln(sandboxConfig.failure)
 		}
 
-		if _, err := os.Stat(sandboxConfig.distDir); !os.IsNotExist(err) {
-			//Mount dist dir as read-write if it already exists
-			sandboxArgs = append(sandboxArgs, "-B",
-				sandboxConfig.distDir)
+		// Check the other profiles that were selected, so that a profile that
+		// can't work is reported before the build starts.
+		sandboxConfig.profileFailures = make(map[string]string)
+		probed := map[string]bool{defaultProfile.Name: true}
+		for _, phase := range sandboxPhases {
+			profile := config.SandboxProfile(phase)
+			if probed[profile.Name] {
+				continue
+			}
+			probed[profile.Name] = true
+			if !sandboxConfig.working {
+				sandboxConfig.profileFailures[profile.Name] = sandboxConfig.failure
+			} else if working, failure := probeSandbox(ctx, config, profile); !working {
+				sandboxConfig.profileFailures[profile.Name] = failure
+			}
 		}
+	})
 
-		sandboxArgs = append(sandboxArgs,
-			"--disable_clone_newcgroup",
-			"--",
-			"/bin/bash", "-c", `if [ $(hostname) == "android-build" ]; then echo "Android" "Success"; else echo Failure; fi`)
+	if sandboxConfig.working && len(sandboxConfig.profileFailures) > 0 {
+		var failures []string
+		for name, failure := range sandboxConfig.profileFailures {
+			failures = append(failures, fmt.Sprintf("profile %q: %s", name, failure))
+		}
+		sort.Strings(failures)
+		return false, strings.Join(failures, "; ")
+	}
+	return sandboxConfig.working, sandboxConfig.failure
+}
+
+// probeSandbox runs a command in an nsjail sandbox set up by profile, and
+// returns whether it worked, and why not if it didn't.
+func probeSandbox(ctx Context, config Config, profile SandboxProfile) (bool, string) {
+	sandboxArgs := []string{
+		"-H", "android-build",
+		"-e",
+		"-u", "nobody",
+		"-g", sandboxConfig.group,
+	}
+	sandboxArgs = append(sandboxArgs, nsjailMountArgs(profile)...)
+	if profile.Network {
+		sandboxArgs = append(sandboxArgs, "-N")
+	}
+	sandboxArgs = append(sandboxArgs,
+		"--disable_clone_newcgroup",
+		"--",
+		"/bin/bash", "-c", `if [ $(hostname) == "android-build" ]; then echo "Android" "Success"; else echo Failure; fi`)
 
-		cmd := exec.CommandContext(c.ctx.Context, nsjailPath, sandboxArgs...)
+	cmd := exec.CommandContext(ctx.Context, nsjailPath, sandboxArgs...)
 
-		cmd.Env = c.config.Environment().Environ()
+	cmd.Env = config.Environment().Environ()
 
-		c.ctx.Verboseln(cmd.Args)
-		data, err := cmd.CombinedOutput()
-		if err == nil && bytes.Contains(data, []byte("Android Success")) {
-			sandboxConfig.working = true
-			return
-		}
+	ctx.Verboseln(cmd.Args)
+	data, err := cmd.CombinedOutput()
+	if err == nil && bytes.Contains(data, []byte("Android Success")) {
+		return true, ""
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		ctx.Verboseln(line)
+	}
 
-		c.ctx.Println("Build sandboxing disabled due to nsjail error.")
+	if err == nil {
+		return false, "nsjail exited successfully, but without the correct output"
+	} else if e, ok := err.(*exec.ExitError); ok {
+		return false, fmt.Sprintf("nsjail failed with %v", e.ProcessState.String())
+	}
+	return false, fmt.Sprintf("nsjail failed with %v", err)
+}
 
-		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
-			c.ctx.Verboseln(line)
+// nsjailMountArgs returns the nsjail arguments that mount the filesystem as
+// described by profile. Later mounts are mounted over earlier ones.
+func nsjailMountArgs(profile SandboxProfile) []string {
+	// For now, just map everything. Make most things readonly.
+	args := []string{"-R", "/"}
+
+	if profile.TmpfsTmp {
+		// Mount an empty, writable tmp dir. -T would limit it to 4MiB.
+		size := profile.TmpfsSize
+		if size == "" {
+			size = defaultTmpfsSize
 		}
+		args = append(args, "-m", "none:/tmp:tmpfs:size="+size)
+	} else {
+		// Mount a writable tmp dir
+		args = append(args, "-B", "/tmp")
+	}
 
-		if err == nil {
-			c.ctx.Verboseln("nsjail exited successfully, but without the correct output")
-		} else if e, ok := err.(*exec.ExitError); ok {
-			c.ctx.Verbosef("nsjail failed with %v", e.ProcessState.String())
-		} else {
-			c.ctx.Verbosef("nsjail failed with %v", err)
+	if profile.ReadOnlySource {
+		args = append(args, "-R", sandboxConfig.srcDir)
+		for _, path := range profile.WritablePaths {
+			args = append(args, "-B", filepath.Join(sandboxConfig.srcDir, path))
 		}
-	})
+	} else {
+		// Mount source are read-write
+		args = append(args, "-B", sandboxConfig.srcDir)
+	}
 
-	return sandboxConfig.working
+	//Mount out dir as read-write
+	args = append(args, "-B", sandboxConfig.outDir)
+
+	if _, err := os.Stat(sandboxConfig.distDir); !os.IsNotExist(err) {
+		//Mount dist dir as read-write if it already exists
+		args = append(args, "-B", sandboxConfig.distDir)
+	}
+
+	mount := func(flag, mount string) {
+		if !filepath.IsAbs(mount) {
+			mount = filepath.Join(sandboxConfig.srcDir, mount)
+		}
+		args = append(args, flag, mount)
+	}
+	for _, m := range profile.ReadOnlyMounts {
+		mount("-R", m)
+	}
+	for _, m := range profile.WritableMounts {
+		mount("-B", m)
+	}
+	return args
 }
 
 func (c *Cmd) wrapSandbox() {
@@ -178,18 +287,6 @@ func (c *Cmd) wrapSandbox() {
 		"--rlimit_fsize", "soft",
 		"--rlimit_nofile", "soft",
 
-		// For now, just map everything. Make most things readonly.
-		"-R", "/",
-
-		// Mount a writable tmp dir
-		"-B", "/tmp",
-
-		// Mount source are read-write
-		"-B", sandboxConfig.srcDir,
-
-		//Mount out dir as read-write
-		"-B", sandboxConfig.outDir,
-
 		// Disable newcgroup for now, since it may require newer kernels
 		// TODO: try out cgroups
 		"--disable_clone_newcgroup",
@@ -198,12 +295,12 @@ func (c *Cmd) wrapSandbox() {
 		"-q",
 	}
 
-	if _, err := os.Stat(sandboxConfig.distDir); !os.IsNotExist(err) {
-		//Mount dist dir as read-write if it already exists
-		sandboxArgs = append(sandboxArgs, "-B", sandboxConfig.distDir)
-	}
+	profile := c.config.SandboxProfile(c.Sandbox.Phase)
+	sandboxArgs = append(sandboxArgs, nsjailMountArgs(profile)...)
 
-	if c.Sandbox.AllowBuildBrokenUsesNetwork && c.config.BuildBrokenUsesNetwork() {
+	if profile.Network {
+		sandboxArgs = append(sandboxArgs, "-N")
+	} else if c.Sandbox.AllowBuildBrokenUsesNetwork && c.config.BuildBrokenUsesNetwork() {
 		c.ctx.Printf("AllowBuildBrokenUsesNetwork: %v", c.Sandbox.AllowBuildBrokenUsesNetwork)
 		c.ctx.Printf("BuildBrokenUsesNetwork: %v", c.config.BuildBrokenUsesNetwork())
 		sandboxArgs = append(sandboxArgs, "-N")
@@ -213,7 +310,7 @@ func (c *Cmd) wrapSandbox() {
 		sandboxArgs = append(sandboxArgs, "-N")
 	}
 
-	if ccacheExec := os.Getenv("CCACHE_EXEC"); ccacheExec != "" {
+	if ccacheExec, _ := c.config.Environment().Get("CCACHE_EXEC"); ccacheExec != "" {
 		bytes, err := exec.Command(ccacheExec, "-k", "cache_dir").Output()
 		if err == nil {
 			sandboxArgs = append(sandboxArgs, "-B", strings.TrimSpace(string(bytes)))
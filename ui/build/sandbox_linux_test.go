@@ -0,0 +1,165 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setSandboxDirs sets the directories that the sandbox mounts for the
+// duration of a test.
+func setSandboxDirs(t *testing.T, srcDir, outDir, distDir string) {
+	oldSrcDir, oldOutDir, oldDistDir := sandboxConfig.srcDir, sandboxConfig.outDir, sandboxConfig.distDir
+	sandboxConfig.srcDir, sandboxConfig.outDir, sandboxConfig.distDir = srcDir, outDir, distDir
+	t.Cleanup(func() {
+		sandboxConfig.srcDir, sandboxConfig.outDir, sandboxConfig.distDir = oldSrcDir, oldOutDir, oldDistDir
+	})
+}
+
+func TestNsjailMountArgs(t *testing.T) {
+	dir := t.TempDir()
+	distDir := filepath.Join(dir, "dist")
+	setSandboxDirs(t, "/src", "/src/out", distDir)
+
+	testCases := []struct {
+		description string
+		profile     SandboxProfile
+		distExists  bool
+		want        []string
+	}{
+		{
+			description: "default",
+			profile:     builtinSandboxProfiles[defaultSandboxProfile],
+			want:        []string{"-R", "/", "-B", "/tmp", "-B", "/src", "-B", "/src/out"},
+		},
+		{
+			description: "default with dist dir",
+			profile:     builtinSandboxProfiles[defaultSandboxProfile],
+			distExists:  true,
+			want:        []string{"-R", "/", "-B", "/tmp", "-B", "/src", "-B", "/src/out", "-B", distDir},
+		},
+		{
+			description: "strict",
+			profile:     builtinSandboxProfiles["strict"],
+			want:        []string{"-R", "/", "-m", "none:/tmp:tmpfs:size=50%", "-R", "/src", "-B", "/src/out"},
+		},
+		{
+			description: "tmpfs size",
+			profile:     SandboxProfile{TmpfsTmp: true, TmpfsSize: "8g"},
+			want:        []string{"-R", "/", "-m", "none:/tmp:tmpfs:size=8g", "-B", "/src", "-B", "/src/out"},
+		},
+		{
+			description: "custom",
+			profile: SandboxProfile{
+				ReadOnlySource: true,
+				WritablePaths:  []string{"external/foo/generated"},
+				ReadOnlyMounts: []string{"prebuilts/cache:/cache", "/opt/toolchain"},
+				WritableMounts: []string{"/var/cache/kati:/kati"},
+			},
+			want: []string{
+				"-R", "/",
+				"-B", "/tmp",
+				"-R", "/src",
+				"-B", "/src/external/foo/generated",
+				"-B", "/src/out",
+				"-R", "/src/prebuilts/cache:/cache",
+				"-R", "/opt/toolchain",
+				"-B", "/var/cache/kati:/kati",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if tc.distExists {
+				if err := os.MkdirAll(distDir, 0777); err != nil {
+					t.Fatal(err)
+				}
+				defer os.RemoveAll(distDir)
+			}
+			if g := nsjailMountArgs(tc.profile); !reflect.DeepEqual(g, tc.want) {
+				t.Errorf("want %q, got %q", tc.want, g)
+			}
+		})
+	}
+}
+
+func TestWrapSandbox(t *testing.T) {
+	dir := t.TempDir()
+	setSandboxDirs(t, "/src", "/src/out", filepath.Join(dir, "dist"))
+
+	env := Environment([]string{"SOONG_SANDBOX=kati=strict,ninja=network", "USER=me"})
+	profiles, err := loadSandboxProfiles(&env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{&configImpl{environ: &env, sandboxProfiles: profiles}}
+
+	testCases := []struct {
+		sandbox Sandbox
+		want    []string
+		notWant []string
+	}{
+		{
+			sandbox: katiSandbox,
+			want:    []string{"-m none:/tmp:tmpfs:size=50%", "-R /src -B /src/out"},
+			notWant: []string{"-N"},
+		},
+		{
+			sandbox: ninjaSandbox,
+			want:    []string{"-B /tmp", "-B /src -B /src/out", "-N"},
+		},
+		{
+			sandbox: soongSandbox,
+			want:    []string{"-B /tmp", "-B /src -B /src/out"},
+			notWant: []string{"-N", "-m"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.sandbox.Phase, func(t *testing.T) {
+			cmd := Command(testContext(), config, "test", "/bin/ckati", "--ninja")
+			cmd.Sandbox = tc.sandbox
+			cmd.Env = cmd.Environment.Environ()
+			cmd.wrapSandbox()
+
+			if cmd.Path != nsjailPath {
+				t.Errorf("want %q to be run, got %q", nsjailPath, cmd.Path)
+			}
+			args := strings.Join(cmd.Args, " ")
+			if !strings.HasPrefix(args, "-x /bin/ckati ") || !strings.HasSuffix(args, " -- --ninja") {
+				t.Errorf("want nsjail to run /bin/ckati --ninja, got %q", args)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(args, " "+w+" ") {
+					t.Errorf("want %q in %q", w, args)
+				}
+			}
+			for _, w := range tc.notWant {
+				if strings.Contains(args, " "+w+" ") {
+					t.Errorf("don't want %q in %q", w, args)
+				}
+			}
+			sandboxEnv := Environment(cmd.Env)
+			if user, _ := sandboxEnv.Get("USER"); user != "nobody" {
+				t.Errorf("want USER=nobody in the sandbox, got %q", user)
+			}
+		})
+	}
+}
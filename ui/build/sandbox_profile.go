@@ -0,0 +1,220 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+// This file implements sandbox profiles, which describe the nsjail sandbox
+// that each phase of the build runs in on Linux. $SOONG_SANDBOX selects the
+// profile of every phase, or of some of them:
+//
+//	SOONG_SANDBOX=strict
+//	SOONG_SANDBOX=default,kati=strict,soong=strict
+//
+// Besides the built-in profiles, more can be defined in the JSON file named
+// by $SOONG_SANDBOX_PROFILES:
+//
+//	{
+//	  "kati-ccache": {
+//	    "read_only_source": true,
+//	    "writable_paths": ["external/foo/generated"],
+//	    "tmpfs_tmp": true,
+//	    "tmpfs_size": "16g",
+//	    "read_only_mounts": ["/opt/toolchain"],
+//	    "writable_mounts": ["/var/cache/ccache:/ccache"]
+//	  }
+//	}
+//
+// The selected profiles are checked with the nsjail probe before the first
+// sandboxed command runs.
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+const (
+	sandboxEnvVar         = "SOONG_SANDBOX"
+	sandboxProfilesEnvVar = "SOONG_SANDBOX_PROFILES"
+
+	defaultSandboxProfile = "default"
+)
+
+// The phases of the build that run in a sandbox.
+const (
+	sandboxPhaseDumpvars = "dumpvars"
+	sandboxPhaseSoong    = "soong"
+	sandboxPhaseKati     = "kati"
+	sandboxPhaseNinja    = "ninja"
+)
+
+var sandboxPhases = []string{
+	sandboxPhaseDumpvars,
+	sandboxPhaseSoong,
+	sandboxPhaseKati,
+	sandboxPhaseNinja,
+}
+
+// SandboxProfile describes the mounts and network access of the sandbox. The
+// output directory, and the dist directory if it exists, are always writable,
+// and the rest of the filesystem is always read-only.
+type SandboxProfile struct {
+	Name string `json:"-"`
+
+	// Mount the source tree read-only instead of read-write.
+	ReadOnlySource bool `json:"read_only_source"`
+
+	// Paths in the source tree that are writable even though it is mounted
+	// read-only, relative to the top of the tree.
+	WritablePaths []string `json:"writable_paths"`
+
+	// Allow access to the network.
+	Network bool `json:"network"`
+
+	// Mount an empty tmpfs on /tmp instead of the host's /tmp.
+	TmpfsTmp bool `json:"tmpfs_tmp"`
+
+	// The maximum size of the tmpfs on /tmp, as given to the size option of
+	// tmpfs, like "8g" or "25%" of the RAM. Defaults to defaultTmpfsSize.
+	TmpfsSize string `json:"tmpfs_size"`
+
+	// More paths to mount read-only or read-write, as "<path>" or
+	// "<path>:<path in the sandbox>". Relative paths are relative to the top
+	// of the tree.
+	ReadOnlyMounts []string `json:"read_only_mounts"`
+	WritableMounts []string `json:"writable_mounts"`
+}
+
+// The default size of the tmpfs on /tmp, which is only a limit, memory is used
+// as files are written. nsjail's own default of 4MiB is too small for the
+// actions that spill to /tmp, like javac, soong_zip and R8.
+const defaultTmpfsSize = "50%"
+
+var builtinSandboxProfiles = map[string]SandboxProfile{
+	// The sandbox the build has always used.
+	defaultSandboxProfile: {},
+	// Keeps the build from writing to the source tree or sharing files
+	// through /tmp.
+	"strict": {
+		ReadOnlySource: true,
+		TmpfsTmp:       true,
+	},
+	// The default sandbox with access to the network.
+	"network": {
+		Network: true,
+	},
+}
+
+// loadSandboxProfiles returns the sandbox profile of each phase of the build
+// selected by $SOONG_SANDBOX.
+func loadSandboxProfiles(env *Environment) (map[string]SandboxProfile, error) {
+	profiles := make(map[string]SandboxProfile)
+	for name, profile := range builtinSandboxProfiles {
+		profile.Name = name
+		profiles[name] = profile
+	}
+	if file, ok := env.Get(sandboxProfilesEnvVar); ok && file != "" {
+		custom, err := readSandboxProfiles(file)
+		if err != nil {
+			return nil, err
+		}
+		for name, profile := range custom {
+			if _, ok := profiles[name]; ok {
+				return nil, fmt.Errorf("%s redefines the built-in sandbox profile %q", file, name)
+			}
+			profile.Name = name
+			profiles[name] = profile
+		}
+	}
+
+	ret := make(map[string]SandboxProfile)
+	for _, phase := range sandboxPhases {
+		ret[phase] = profiles[defaultSandboxProfile]
+	}
+
+	selection, _ := env.Get(sandboxEnvVar)
+	for _, item := range strings.Split(selection, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		phase, name := "", item
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			phase, name = item[:i], item[i+1:]
+			if !inList(phase, sandboxPhases) {
+				return nil, fmt.Errorf("unknown build phase %q in $%s, expected one of %s",
+					phase, sandboxEnvVar, strings.Join(sandboxPhases, ", "))
+			}
+		}
+
+		profile, ok := profiles[name]
+		if !ok {
+			var names []string
+			for name := range profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown sandbox profile %q in $%s, expected one of %s",
+				name, sandboxEnvVar, strings.Join(names, ", "))
+		}
+
+		if phase == "" {
+			for _, phase := range sandboxPhases {
+				ret[phase] = profile
+			}
+		} else {
+			ret[phase] = profile
+		}
+	}
+	return ret, nil
+}
+
+// readSandboxProfiles reads a JSON file of sandbox profiles by name.
+func readSandboxProfiles(file string) (map[string]SandboxProfile, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles map[string]SandboxProfile
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("sandbox profiles %s did not parse correctly: %v", file, err)
+	}
+
+	for name, profile := range profiles {
+		for _, path := range profile.WritablePaths {
+			if path == "" || strings.HasPrefix(path, "/") || strings.HasPrefix(path, "../") || path == ".." {
+				return nil, fmt.Errorf("sandbox profile %q in %s: writable path %q is not in the source tree",
+					name, file, path)
+			}
+		}
+		if strings.ContainsAny(profile.TmpfsSize, ":,") {
+			return nil, fmt.Errorf("sandbox profile %q in %s: tmpfs size %q is not a size",
+				name, file, profile.TmpfsSize)
+		}
+		for _, mount := range append(append([]string(nil), profile.ReadOnlyMounts...), profile.WritableMounts...) {
+			if mount == "" || strings.HasPrefix(mount, ":") || strings.Count(mount, ":") > 1 {
+				return nil, fmt.Errorf("sandbox profile %q in %s: mount %q is not <path> or <path>:<path in the sandbox>",
+					name, file, mount)
+			}
+		}
+	}
+	return profiles, nil
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package build
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadSandboxProfiles(t *testing.T) {
+	dir := t.TempDir()
+	profilesFile := filepath.Join(dir, "profiles.json")
+	err := ioutil.WriteFile(profilesFile, []byte(`{
+		"kati-cache": {
+			"read_only_source": true,
+			"writable_paths": ["external/foo/generated"],
+			"writable_mounts": ["/var/cache/kati:/kati"]
+		}
+	}`), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		description string
+		env         []string
+		want        map[string]string
+		wantErr     string
+	}{
+		{
+			description: "defaults",
+			want: map[string]string{
+				"dumpvars": "default",
+				"soong":    "default",
+				"kati":     "default",
+				"ninja":    "default",
+			},
+		},
+		{
+			description: "all phases",
+			env:         []string{"SOONG_SANDBOX=strict"},
+			want: map[string]string{
+				"dumpvars": "strict",
+				"soong":    "strict",
+				"kati":     "strict",
+				"ninja":    "strict",
+			},
+		},
+		{
+			description: "per phase",
+			env:         []string{"SOONG_SANDBOX=strict, ninja=network,kati=kati-cache", "SOONG_SANDBOX_PROFILES=" + profilesFile},
+			want: map[string]string{
+				"dumpvars": "strict",
+				"soong":    "strict",
+				"kati":     "kati-cache",
+				"ninja":    "network",
+			},
+		},
+		{
+			description: "unknown profile",
+			env:         []string{"SOONG_SANDBOX=kati=kati-cache"},
+			wantErr:     `unknown sandbox profile "kati-cache"`,
+		},
+		{
+			description: "unknown phase",
+			env:         []string{"SOONG_SANDBOX=bazel=strict"},
+			wantErr:     `unknown build phase "bazel"`,
+		},
+		{
+			description: "missing profiles file",
+			env:         []string{"SOONG_SANDBOX_PROFILES=" + filepath.Join(dir, "missing.json")},
+			wantErr:     "no such file or directory",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			env := Environment(tc.env)
+			profiles, err := loadSandboxProfiles(&env)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make(map[string]string)
+			for phase, profile := range profiles {
+				got[phase] = profile.Name
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestReadSandboxProfilesErrors(t *testing.T) {
+	testCases := []struct {
+		profiles string
+		wantErr  string
+	}{
+		{`{"a": {"network": true, "tmpfs": true}}`, `unknown field "tmpfs"`},
+		{`{"a": {"writable_paths": ["/etc"]}}`, `writable path "/etc" is not in the source tree`},
+		{`{"a": {"writable_paths": ["../out"]}}`, `writable path "../out" is not in the source tree`},
+		{`{"a": {"read_only_mounts": ["/a:/b:/c"]}}`, `mount "/a:/b:/c" is not`},
+		{`{"a": {"tmpfs_tmp": true, "tmpfs_size": "1g,mode=777"}}`, `tmpfs size "1g,mode=777" is not a size`},
+	}
+
+	dir := t.TempDir()
+	for i, tc := range testCases {
+		file := filepath.Join(dir, strings.Repeat("x", i+1)+".json")
+		if err := ioutil.WriteFile(file, []byte(tc.profiles), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readSandboxProfiles(file); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: want error containing %q, got %v", tc.profiles, tc.wantErr, err)
+		}
+	}
+}
+
+func TestLoadSandboxProfilesRedefined(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "profiles.json")
+	if err := ioutil.WriteFile(file, []byte(`{"strict": {}}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	env := Environment([]string{"SOONG_SANDBOX_PROFILES=" + file})
+	if _, err := loadSandboxProfiles(&env); err == nil || !strings.Contains(err.Error(), `redefines the built-in sandbox profile "strict"`) {
+		t.Errorf("want an error about redefining strict, got %v", err)
+	}
+}
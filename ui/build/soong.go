@@ -232,11 +232,21 @@ func runSoong(ctx Context, config Config) {
 		ctx.Fatalf("failed to write environment file %s: %s", envFile, err)
 	}
 
+	// Record the environment variables used by soong_build, and the
+	// soong_build binary itself, to explain why it reran.
+	explainInputs := newRebuildStepInputs()
+	explainInputs.addFiles(filepath.Join(config.SoongOutDir(), ".bootstrap", "bin", "soong_build"))
+
 	func() {
 		ctx.BeginTrace(metrics.RunSoong, "environment check")
 		defer ctx.EndTrace()
 
 		soongBuildEnvFile := filepath.Join(config.SoongOutDir(), usedEnvFile)
+		if usedEnv, err := shared.EnvFromFile(soongBuildEnvFile); err == nil {
+			for k := range usedEnv {
+				explainInputs.Env[k], _ = soongBuildEnv.Get(k)
+			}
+		}
 		checkEnvironmentFile(soongBuildEnv, soongBuildEnvFile)
 
 		if integratedBp2Build {
@@ -281,6 +291,11 @@ func runSoong(ctx Context, config Config) {
 			"--frontend_file", fifo,
 			"-f", filepath.Join(config.SoongOutDir(), file))
 
+		if config.ExplainRebuild() {
+			cmd.Args = append(cmd.Args, "-d", "explain")
+			cmd.outputFilter = config.explainer.ninjaExplainFilter("soong_build")
+		}
+
 		var ninjaEnv Environment
 
 		// This is currently how the command line to invoke soong_build finds the
@@ -291,9 +306,14 @@ func runSoong(ctx Context, config Config) {
 		cmd.Sandbox = soongSandbox
 		cmd.RunAndStreamOrFatal()
 	}
+	finishExplainStep := config.explainer.beginStep("soong_build", explainInputs, config.SoongNinjaFile(),
+		"an Android.bp file or a directory read by a glob changed")
+
 	// This build generates <builddir>/build.ninja, which is used later by build/soong/ui/build/build.go#Build().
 	ninja("bootstrap", ".bootstrap/build.ninja")
 
+	finishExplainStep()
+
 	var soongBuildMetrics *soong_metrics_proto.SoongBuildMetrics
 	if shouldCollectBuildSoongMetrics(config) {
 		soongBuildMetrics := loadSoongBuildMetrics(ctx, config)
@@ -55,6 +55,10 @@ type event struct {
 
 	// The list of process resource information that was executed.
 	procResInfo []*soong_metrics_proto.ProcessResourceInfo
+
+	// The resource usage over time of the processes sampled during the
+	// event.
+	procResSamples []*soong_metrics_proto.ProcessResourceSamples
 }
 
 // newEvent returns an event with start populated with the now time.
@@ -69,11 +73,12 @@ func newEvent(name, desc string) *event {
 func (e event) perfInfo() soong_metrics_proto.PerfInfo {
 	realTime := uint64(_now().Sub(e.start).Nanoseconds())
 	return soong_metrics_proto.PerfInfo{
-		Desc:                  proto.String(e.desc),
-		Name:                  proto.String(e.name),
-		StartTime:             proto.Uint64(uint64(e.start.UnixNano())),
-		RealTime:              proto.Uint64(realTime),
-		ProcessesResourceInfo: e.procResInfo,
+		Desc:                     proto.String(e.desc),
+		Name:                     proto.String(e.name),
+		StartTime:                proto.Uint64(uint64(e.start.UnixNano())),
+		RealTime:                 proto.Uint64(realTime),
+		ProcessesResourceInfo:    e.procResInfo,
+		ProcessesResourceSamples: e.procResSamples,
 	}
 }
 
@@ -152,7 +152,7 @@ func (x *ModuleTypeInfo_BuildSystem) UnmarshalJSON(data []byte) error {
 }
 
 func (ModuleTypeInfo_BuildSystem) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_6039342a2ba47b72, []int{5, 0}
+	return fileDescriptor_6039342a2ba47b72, []int{7, 0}
 }
 
 type MetricsBase struct {
@@ -551,9 +551,11 @@ type PerfInfo struct {
 	MemoryUse *uint64 `protobuf:"varint,5,opt,name=memory_use,json=memoryUse" json:"memory_use,omitempty"` // Deprecated: Do not use.
 	// The resource information of each executed process.
 	ProcessesResourceInfo []*ProcessResourceInfo `protobuf:"bytes,6,rep,name=processes_resource_info,json=processesResourceInfo" json:"processes_resource_info,omitempty"`
-	XXX_NoUnkeyedLiteral  struct{}               `json:"-"`
-	XXX_unrecognized      []byte                 `json:"-"`
-	XXX_sizecache         int32                  `json:"-"`
+	// The resource usage of each sampled process over time.
+	ProcessesResourceSamples []*ProcessResourceSamples `protobuf:"bytes,7,rep,name=processes_resource_samples,json=processesResourceSamples" json:"processes_resource_samples,omitempty"`
+	XXX_NoUnkeyedLiteral     struct{}                  `json:"-"`
+	XXX_unrecognized         []byte                    `json:"-"`
+	XXX_sizecache            int32                     `json:"-"`
 }
 
 func (m *PerfInfo) Reset()         { *m = PerfInfo{} }
@@ -624,6 +626,13 @@ func (m *PerfInfo) GetProcessesResourceInfo() []*ProcessResourceInfo {
 	return nil
 }
 
+func (m *PerfInfo) GetProcessesResourceSamples() []*ProcessResourceSamples {
+	if m != nil {
+		return m.ProcessesResourceSamples
+	}
+	return nil
+}
+
 type ProcessResourceInfo struct {
 	// The name of the process for identification.
 	Name *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
@@ -745,6 +754,132 @@ func (m *ProcessResourceInfo) GetInvoluntaryContextSwitches() uint64 {
 	return 0
 }
 
+type ProcessResourceSamples struct {
+	// The name of the process for identification.
+	Name *string `protobuf:"bytes,1,opt,name=name" json:"name,omitempty"`
+	// The samples, in the order they were taken.
+	Samples              []*ProcessResourceSample `protobuf:"bytes,2,rep,name=samples" json:"samples,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *ProcessResourceSamples) Reset()         { *m = ProcessResourceSamples{} }
+func (m *ProcessResourceSamples) String() string { return proto.CompactTextString(m) }
+func (*ProcessResourceSamples) ProtoMessage()    {}
+func (*ProcessResourceSamples) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{5}
+}
+
+func (m *ProcessResourceSamples) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProcessResourceSamples.Unmarshal(m, b)
+}
+func (m *ProcessResourceSamples) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProcessResourceSamples.Marshal(b, m, deterministic)
+}
+func (m *ProcessResourceSamples) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProcessResourceSamples.Merge(m, src)
+}
+func (m *ProcessResourceSamples) XXX_Size() int {
+	return xxx_messageInfo_ProcessResourceSamples.Size(m)
+}
+func (m *ProcessResourceSamples) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProcessResourceSamples.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProcessResourceSamples proto.InternalMessageInfo
+
+func (m *ProcessResourceSamples) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *ProcessResourceSamples) GetSamples() []*ProcessResourceSample {
+	if m != nil {
+		return m.Samples
+	}
+	return nil
+}
+
+type ProcessResourceSample struct {
+	// The time of the sample.
+	// The number of nanoseconds elapsed since the start_time of the PerfInfo.
+	RelativeTime *uint64 `protobuf:"varint,1,opt,name=relative_time,json=relativeTime" json:"relative_time,omitempty"`
+	// The resident set size memory in kilobytes.
+	RssKb *uint64 `protobuf:"varint,2,opt,name=rss_kb,json=rssKb" json:"rss_kb,omitempty"`
+	// The total amount of time spent executing in user space in microseconds.
+	UserTimeMicros *uint64 `protobuf:"varint,3,opt,name=user_time_micros,json=userTimeMicros" json:"user_time_micros,omitempty"`
+	// The total amount of time spent executing in kernel mode in microseconds.
+	SystemTimeMicros *uint64 `protobuf:"varint,4,opt,name=system_time_micros,json=systemTimeMicros" json:"system_time_micros,omitempty"`
+	// The number of threads.
+	Threads              *uint32  `protobuf:"varint,5,opt,name=threads" json:"threads,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ProcessResourceSample) Reset()         { *m = ProcessResourceSample{} }
+func (m *ProcessResourceSample) String() string { return proto.CompactTextString(m) }
+func (*ProcessResourceSample) ProtoMessage()    {}
+func (*ProcessResourceSample) Descriptor() ([]byte, []int) {
+	return fileDescriptor_6039342a2ba47b72, []int{6}
+}
+
+func (m *ProcessResourceSample) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ProcessResourceSample.Unmarshal(m, b)
+}
+func (m *ProcessResourceSample) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ProcessResourceSample.Marshal(b, m, deterministic)
+}
+func (m *ProcessResourceSample) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ProcessResourceSample.Merge(m, src)
+}
+func (m *ProcessResourceSample) XXX_Size() int {
+	return xxx_messageInfo_ProcessResourceSample.Size(m)
+}
+func (m *ProcessResourceSample) XXX_DiscardUnknown() {
+	xxx_messageInfo_ProcessResourceSample.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ProcessResourceSample proto.InternalMessageInfo
+
+func (m *ProcessResourceSample) GetRelativeTime() uint64 {
+	if m != nil && m.RelativeTime != nil {
+		return *m.RelativeTime
+	}
+	return 0
+}
+
+func (m *ProcessResourceSample) GetRssKb() uint64 {
+	if m != nil && m.RssKb != nil {
+		return *m.RssKb
+	}
+	return 0
+}
+
+func (m *ProcessResourceSample) GetUserTimeMicros() uint64 {
+	if m != nil && m.UserTimeMicros != nil {
+		return *m.UserTimeMicros
+	}
+	return 0
+}
+
+func (m *ProcessResourceSample) GetSystemTimeMicros() uint64 {
+	if m != nil && m.SystemTimeMicros != nil {
+		return *m.SystemTimeMicros
+	}
+	return 0
+}
+
+func (m *ProcessResourceSample) GetThreads() uint32 {
+	if m != nil && m.Threads != nil {
+		return *m.Threads
+	}
+	return 0
+}
+
 type ModuleTypeInfo struct {
 	// The build system, eg. Soong or Make.
 	BuildSystem *ModuleTypeInfo_BuildSystem `protobuf:"varint,1,opt,name=build_system,json=buildSystem,enum=soong_build_metrics.ModuleTypeInfo_BuildSystem,def=0" json:"build_system,omitempty"`
@@ -761,7 +896,7 @@ func (m *ModuleTypeInfo) Reset()         { *m = ModuleTypeInfo{} }
 func (m *ModuleTypeInfo) String() string { return proto.CompactTextString(m) }
 func (*ModuleTypeInfo) ProtoMessage()    {}
 func (*ModuleTypeInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_6039342a2ba47b72, []int{5}
+	return fileDescriptor_6039342a2ba47b72, []int{7}
 }
 
 func (m *ModuleTypeInfo) XXX_Unmarshal(b []byte) error {
@@ -819,7 +954,7 @@ func (m *CriticalUserJourneyMetrics) Reset()         { *m = CriticalUserJourneyM
 func (m *CriticalUserJourneyMetrics) String() string { return proto.CompactTextString(m) }
 func (*CriticalUserJourneyMetrics) ProtoMessage()    {}
 func (*CriticalUserJourneyMetrics) Descriptor() ([]byte, []int) {
-	return fileDescriptor_6039342a2ba47b72, []int{6}
+	return fileDescriptor_6039342a2ba47b72, []int{8}
 }
 
 func (m *CriticalUserJourneyMetrics) XXX_Unmarshal(b []byte) error {
@@ -866,7 +1001,7 @@ func (m *CriticalUserJourneysMetrics) Reset()         { *m = CriticalUserJourney
 func (m *CriticalUserJourneysMetrics) String() string { return proto.CompactTextString(m) }
 func (*CriticalUserJourneysMetrics) ProtoMessage()    {}
 func (*CriticalUserJourneysMetrics) Descriptor() ([]byte, []int) {
-	return fileDescriptor_6039342a2ba47b72, []int{7}
+	return fileDescriptor_6039342a2ba47b72, []int{9}
 }
 
 func (m *CriticalUserJourneysMetrics) XXX_Unmarshal(b []byte) error {
@@ -914,7 +1049,7 @@ func (m *SoongBuildMetrics) Reset()         { *m = SoongBuildMetrics{} }
 func (m *SoongBuildMetrics) String() string { return proto.CompactTextString(m) }
 func (*SoongBuildMetrics) ProtoMessage()    {}
 func (*SoongBuildMetrics) Descriptor() ([]byte, []int) {
-	return fileDescriptor_6039342a2ba47b72, []int{8}
+	return fileDescriptor_6039342a2ba47b72, []int{10}
 }
 
 func (m *SoongBuildMetrics) XXX_Unmarshal(b []byte) error {
@@ -979,6 +1114,8 @@ func init() {
 	proto.RegisterType((*SystemResourceInfo)(nil), "soong_build_metrics.SystemResourceInfo")
 	proto.RegisterType((*PerfInfo)(nil), "soong_build_metrics.PerfInfo")
 	proto.RegisterType((*ProcessResourceInfo)(nil), "soong_build_metrics.ProcessResourceInfo")
+	proto.RegisterType((*ProcessResourceSamples)(nil), "soong_build_metrics.ProcessResourceSamples")
+	proto.RegisterType((*ProcessResourceSample)(nil), "soong_build_metrics.ProcessResourceSample")
 	proto.RegisterType((*ModuleTypeInfo)(nil), "soong_build_metrics.ModuleTypeInfo")
 	proto.RegisterType((*CriticalUserJourneyMetrics)(nil), "soong_build_metrics.CriticalUserJourneyMetrics")
 	proto.RegisterType((*CriticalUserJourneysMetrics)(nil), "soong_build_metrics.CriticalUserJourneysMetrics")
@@ -990,92 +1127,98 @@ func init() {
 }
 
 var fileDescriptor_6039342a2ba47b72 = []byte{
-	// 1380 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xef, 0x52, 0x1b, 0x37,
-	0x10, 0x8f, 0xc1, 0x60, 0x7b, 0xfd, 0x07, 0x23, 0xa0, 0x5c, 0x48, 0xd2, 0x52, 0xb7, 0x49, 0x99,
-	0x4e, 0x43, 0x32, 0x34, 0xc3, 0x64, 0x98, 0x4c, 0xa7, 0xe0, 0xd0, 0x34, 0x65, 0xc0, 0x8c, 0x08,
-	0x69, 0xda, 0x7e, 0x50, 0xe5, 0xb3, 0x0c, 0x97, 0xdc, 0x9d, 0x6e, 0x24, 0x1d, 0xc5, 0x79, 0xb3,
-	0x7e, 0xee, 0x4b, 0xf4, 0x05, 0xfa, 0x04, 0x7d, 0x81, 0x8e, 0x56, 0x77, 0xe6, 0x20, 0x6e, 0xc2,
-	0xe4, 0xdb, 0xe9, 0xb7, 0xbf, 0xdf, 0x6a, 0xb5, 0xd2, 0xee, 0xda, 0xd0, 0x8c, 0x84, 0x51, 0x81,
-	0xaf, 0xd7, 0x13, 0x25, 0x8d, 0x24, 0x0b, 0x5a, 0xca, 0xf8, 0x84, 0xf5, 0xd3, 0x20, 0x1c, 0xb0,
-	0xcc, 0xd4, 0xf9, 0xbb, 0x01, 0xf5, 0x7d, 0xf7, 0xbd, 0xc3, 0xb5, 0x20, 0x0f, 0x61, 0xd1, 0x11,
-	0x06, 0xdc, 0x08, 0x66, 0x82, 0x48, 0x68, 0xc3, 0xa3, 0xc4, 0x2b, 0xad, 0x96, 0xd6, 0xa6, 0x29,
-	0x41, 0xdb, 0x53, 0x6e, 0xc4, 0x8b, 0xdc, 0x42, 0x6e, 0x42, 0xd5, 0x29, 0x82, 0x81, 0x37, 0xb5,
-	0x5a, 0x5a, 0xab, 0xd1, 0x0a, 0xae, 0x9f, 0x0f, 0xc8, 0x16, 0xdc, 0x4c, 0x42, 0x6e, 0x86, 0x52,
-	0x45, 0xec, 0x4c, 0x28, 0x1d, 0xc8, 0x98, 0xf9, 0x72, 0x20, 0x62, 0x1e, 0x09, 0x6f, 0x1a, 0xb9,
-	0xcb, 0x39, 0xe1, 0xa5, 0xb3, 0x77, 0x33, 0x33, 0xb9, 0x0b, 0x2d, 0xc3, 0xd5, 0x89, 0x30, 0x2c,
-	0x51, 0x72, 0x90, 0xfa, 0xc6, 0x2b, 0xa3, 0xa0, 0xe9, 0xd0, 0x43, 0x07, 0x92, 0x01, 0x2c, 0x66,
-	0x34, 0x17, 0xc4, 0x19, 0x57, 0x01, 0x8f, 0x8d, 0x37, 0xb3, 0x5a, 0x5a, 0x6b, 0x6d, 0xdc, 0x5f,
-	0x9f, 0x70, 0xe6, 0xf5, 0xc2, 0x79, 0xd7, 0x77, 0xac, 0xe5, 0xa5, 0x13, 0x6d, 0x4d, 0xef, 0x1e,
-	0x3c, 0xa3, 0xc4, 0xf9, 0x2b, 0x1a, 0x48, 0x0f, 0xea, 0xd9, 0x2e, 0x5c, 0xf9, 0xa7, 0xde, 0x2c,
-	0x3a, 0xbf, 0xfb, 0x41, 0xe7, 0xdb, 0xca, 0x3f, 0xdd, 0xaa, 0x1c, 0x1f, 0xec, 0x1d, 0xf4, 0x7e,
-	0x3e, 0xa0, 0xe0, 0x5c, 0x58, 0x90, 0xac, 0xc3, 0x42, 0xc1, 0xe1, 0x38, 0xea, 0x0a, 0x1e, 0x71,
-	0xfe, 0x82, 0x98, 0x07, 0xf0, 0x0d, 0x64, 0x61, 0x31, 0x3f, 0x49, 0xc7, 0xf4, 0x2a, 0xd2, 0xdb,
-	0xce, 0xd2, 0x4d, 0xd2, 0x9c, 0xbd, 0x07, 0xb5, 0x53, 0xa9, 0xb3, 0x60, 0x6b, 0x1f, 0x15, 0x6c,
-	0xd5, 0x3a, 0xc0, 0x50, 0x29, 0x34, 0xd1, 0xd9, 0x46, 0x3c, 0x70, 0x0e, 0xe1, 0xa3, 0x1c, 0xd6,
-	0xad, 0x93, 0x8d, 0x78, 0x80, 0x3e, 0x97, 0xa1, 0x82, 0x3e, 0xa5, 0xf6, 0xea, 0x78, 0x86, 0x59,
-	0xbb, 0xec, 0x69, 0xd2, 0xc9, 0x36, 0x93, 0x9a, 0x89, 0x73, 0xa3, 0xb8, 0xd7, 0x40, 0x73, 0xdd,
-	0x99, 0x77, 0x2d, 0x34, 0xe6, 0xf8, 0x4a, 0x6a, 0x6d, 0x5d, 0x34, 0x2f, 0x38, 0x5d, 0x8b, 0xf5,
-	0x34, 0xb9, 0x07, 0x73, 0x05, 0x0e, 0x86, 0xdd, 0x72, 0xcf, 0x67, 0xcc, 0xc2, 0x40, 0xee, 0xc3,
-	0x42, 0x81, 0x37, 0x3e, 0xe2, 0x9c, 0x4b, 0xec, 0x98, 0x5b, 0x88, 0x5b, 0xa6, 0x86, 0x0d, 0x02,
-	0xe5, 0xb5, 0x5d, 0xdc, 0x32, 0x35, 0x4f, 0x03, 0x45, 0xbe, 0x83, 0xba, 0x16, 0x26, 0x4d, 0x98,
-	0x91, 0x32, 0xd4, 0xde, 0xfc, 0xea, 0xf4, 0x5a, 0x7d, 0xe3, 0xce, 0xc4, 0x14, 0x1d, 0x0a, 0x35,
-	0x7c, 0x1e, 0x0f, 0x25, 0x05, 0x54, 0xbc, 0xb0, 0x02, 0xb2, 0x05, 0xb5, 0x37, 0xdc, 0x04, 0x4c,
-	0xa5, 0xb1, 0xf6, 0xc8, 0x75, 0xd4, 0x55, 0xcb, 0xa7, 0x69, 0xac, 0xc9, 0x13, 0x00, 0xc7, 0x44,
-	0xf1, 0xc2, 0x75, 0xc4, 0x35, 0xb4, 0xe6, 0xea, 0x38, 0x88, 0x5f, 0x73, 0xa7, 0x5e, 0xbc, 0x96,
-	0x1a, 0x05, 0xa8, 0xfe, 0x16, 0x66, 0x8c, 0x34, 0x3c, 0xf4, 0x96, 0x56, 0x4b, 0x1f, 0x16, 0x3a,
-	0x2e, 0x79, 0x09, 0x93, 0x5a, 0x91, 0xf7, 0x09, 0xba, 0xb8, 0x37, 0xd1, 0xc5, 0x91, 0xc5, 0xb0,
-	0x24, 0xb3, 0x17, 0x46, 0xe7, 0xf5, 0x55, 0x88, 0x74, 0xa1, 0xe1, 0x54, 0xbe, 0x8c, 0x87, 0xc1,
-	0x89, 0xb7, 0x8c, 0x0e, 0x57, 0x27, 0x3a, 0x44, 0x61, 0x17, 0x79, 0xb4, 0xde, 0xbf, 0x58, 0x90,
-	0x15, 0xc0, 0xa7, 0x8f, 0x2d, 0xca, 0xc3, 0x3b, 0x1e, 0xaf, 0xc9, 0x2f, 0xb0, 0xa8, 0x47, 0xda,
-	0x88, 0x88, 0x29, 0xa1, 0x65, 0xaa, 0x7c, 0xc1, 0x82, 0x78, 0x28, 0xbd, 0x9b, 0xb8, 0xd1, 0x57,
-	0x93, 0x23, 0x47, 0x01, 0xcd, 0xf8, 0x98, 0x06, 0xa2, 0xdf, 0xc1, 0xc8, 0x17, 0xd0, 0xcc, 0x63,
-	0x8f, 0x22, 0x1e, 0x0f, 0xbc, 0x15, 0xdc, 0xbb, 0x91, 0x85, 0x86, 0x98, 0xbd, 0xab, 0x3e, 0x7f,
-	0x2b, 0x42, 0x77, 0x57, 0xb7, 0xae, 0x75, 0x57, 0x28, 0xb0, 0x77, 0xd5, 0x79, 0x08, 0x8d, 0x4b,
-	0x4d, 0xad, 0x0a, 0xe5, 0xe3, 0xa3, 0x5d, 0xda, 0xbe, 0x41, 0x9a, 0x50, 0xb3, 0x5f, 0x4f, 0x77,
-	0x77, 0x8e, 0x9f, 0xb5, 0x4b, 0xa4, 0x02, 0xb6, 0x11, 0xb6, 0xa7, 0x3a, 0x4f, 0xa0, 0x8c, 0xcf,
-	0xbe, 0x0e, 0x79, 0x19, 0xb7, 0x6f, 0x58, 0xeb, 0x36, 0xdd, 0x6f, 0x97, 0x48, 0x0d, 0x66, 0xb6,
-	0xe9, 0xfe, 0xe6, 0xa3, 0xf6, 0x94, 0xc5, 0x5e, 0x3d, 0xde, 0x6c, 0x4f, 0x13, 0x80, 0xd9, 0x57,
-	0x8f, 0x37, 0xd9, 0xe6, 0xa3, 0x76, 0xb9, 0x73, 0x02, 0xf5, 0x42, 0x96, 0xed, 0x9c, 0x48, 0xb5,
-	0x60, 0x27, 0x32, 0xe2, 0x38, 0x4d, 0xaa, 0xb4, 0x92, 0x6a, 0xf1, 0x4c, 0x46, 0xdc, 0x96, 0x95,
-	0x35, 0xa9, 0xbe, 0xc0, 0x09, 0x52, 0xa5, 0xb3, 0xa9, 0x16, 0xb4, 0x2f, 0xc8, 0x97, 0xd0, 0x1a,
-	0x4a, 0x9b, 0xe6, 0xb1, 0x72, 0x1a, 0xed, 0x0d, 0x44, 0x8f, 0x9d, 0xbc, 0x23, 0x81, 0xbc, 0x9b,
-	0x65, 0xb2, 0x01, 0x4b, 0xf8, 0xdc, 0x58, 0x72, 0x3a, 0xd2, 0x81, 0xcf, 0x43, 0x16, 0x89, 0x48,
-	0xaa, 0x11, 0x6e, 0x5e, 0xa6, 0x0b, 0x68, 0x3c, 0xcc, 0x6c, 0xfb, 0x68, 0xb2, 0x43, 0x87, 0x9f,
-	0xf1, 0x20, 0xe4, 0xfd, 0x50, 0xd8, 0x4e, 0xab, 0x31, 0x9e, 0x19, 0xda, 0x1c, 0xa3, 0xdd, 0x24,
-	0xd5, 0x9d, 0x7f, 0x4b, 0x50, 0xcd, 0x33, 0x4c, 0x08, 0x94, 0x07, 0x42, 0xfb, 0xe8, 0xb6, 0x46,
-	0xf1, 0xdb, 0x62, 0xf8, 0x80, 0xdc, 0x3c, 0xc4, 0x6f, 0x72, 0x07, 0x40, 0x1b, 0xae, 0x0c, 0x0e,
-	0x55, 0x3c, 0x47, 0x99, 0xd6, 0x10, 0xb1, 0xb3, 0x94, 0xdc, 0x82, 0x9a, 0x12, 0x3c, 0x74, 0xd6,
-	0x32, 0x5a, 0xab, 0x16, 0x40, 0xe3, 0xe7, 0x00, 0x2e, 0x78, 0x9b, 0x08, 0x9c, 0x6d, 0xe5, 0x9d,
-	0x29, 0xaf, 0x44, 0x6b, 0x0e, 0x3d, 0xd6, 0x82, 0xfc, 0x0e, 0xcb, 0x89, 0x92, 0xbe, 0xd0, 0x5a,
-	0xe8, 0x2b, 0xcf, 0x73, 0x16, 0x1f, 0xca, 0xda, 0xe4, 0x87, 0xe2, 0x34, 0x97, 0xde, 0xe7, 0xd2,
-	0xd8, 0x51, 0x11, 0xee, 0xfc, 0x39, 0x0d, 0x0b, 0x13, 0xe8, 0xe3, 0xc3, 0x96, 0x0a, 0x87, 0x5d,
-	0x83, 0x76, 0xaa, 0x85, 0xc2, 0xd3, 0xb0, 0x28, 0xb0, 0xed, 0x15, 0x93, 0x51, 0xa6, 0x2d, 0x8b,
-	0xdb, 0x43, 0xed, 0x23, 0x6a, 0x27, 0x5b, 0x56, 0x53, 0x45, 0xae, 0x4b, 0x4f, 0xdb, 0x59, 0x0a,
-	0xec, 0xdb, 0x00, 0x11, 0x3f, 0x67, 0x4a, 0x6b, 0xf6, 0xa6, 0x9f, 0xa7, 0x29, 0xe2, 0xe7, 0x54,
-	0xeb, 0xbd, 0x3e, 0xf9, 0x1a, 0xe6, 0xa3, 0x20, 0x96, 0x8a, 0x25, 0xfc, 0x44, 0xb0, 0x21, 0x4f,
-	0x43, 0xa3, 0x5d, 0xb6, 0xe8, 0x1c, 0x1a, 0x0e, 0xf9, 0x89, 0xf8, 0x01, 0x61, 0xe4, 0xf2, 0xd7,
-	0x57, 0xb8, 0xb3, 0x19, 0xd7, 0x1a, 0x0a, 0xdc, 0x4f, 0xa1, 0x1e, 0x48, 0x16, 0xc4, 0x49, 0x6a,
-	0xec, 0xb6, 0x15, 0x77, 0x77, 0x81, 0x7c, 0x6e, 0x91, 0xbd, 0x3e, 0x59, 0x85, 0x46, 0x20, 0x99,
-	0x4c, 0x4d, 0x46, 0xa8, 0x22, 0x01, 0x02, 0xd9, 0x43, 0x68, 0xaf, 0x4f, 0x9e, 0xc0, 0xca, 0x99,
-	0x0c, 0xd3, 0xd8, 0x70, 0x35, 0xb2, 0xed, 0xc9, 0x88, 0x73, 0xc3, 0xf4, 0x1f, 0x81, 0xf1, 0x4f,
-	0x85, 0xc6, 0x11, 0x5d, 0xa6, 0xde, 0x98, 0xd1, 0x75, 0x84, 0xa3, 0xcc, 0x4e, 0xbe, 0x87, 0xdb,
-	0x41, 0xfc, 0x1e, 0x3d, 0xa0, 0x7e, 0xa5, 0xc0, 0xb9, 0xe2, 0xa1, 0xf3, 0x4f, 0x09, 0x5a, 0xfb,
-	0x72, 0x90, 0x86, 0xe2, 0xc5, 0x28, 0x71, 0xd7, 0xf6, 0x5b, 0xde, 0x2d, 0x5d, 0x92, 0xf1, 0xfa,
-	0x5a, 0x1b, 0x0f, 0x26, 0x8f, 0xf5, 0x4b, 0x52, 0xd7, 0x3c, 0x5d, 0xc9, 0x15, 0x06, 0x7c, 0xff,
-	0x02, 0x25, 0x9f, 0x41, 0x3d, 0x42, 0x0d, 0x33, 0xa3, 0x24, 0xaf, 0x03, 0x88, 0xc6, 0x6e, 0x6c,
-	0x65, 0xc7, 0x69, 0xc4, 0xe4, 0x90, 0x39, 0xd0, 0x5d, 0x79, 0x93, 0x36, 0xe2, 0x34, 0xea, 0x0d,
-	0xdd, 0x7e, 0xba, 0xf3, 0x20, 0x6b, 0x21, 0x99, 0xd7, 0x4b, 0x7d, 0xa8, 0x06, 0x33, 0x47, 0xbd,
-	0xde, 0x81, 0x6d, 0x58, 0x55, 0x28, 0xef, 0x6f, 0xef, 0xed, 0xb6, 0xa7, 0x3a, 0x21, 0xac, 0x74,
-	0x55, 0x60, 0x6c, 0x49, 0x1f, 0x6b, 0xa1, 0x7e, 0x92, 0xa9, 0x8a, 0xc5, 0x28, 0x1f, 0x10, 0x93,
-	0x5e, 0xea, 0x16, 0x54, 0xf2, 0x01, 0x34, 0xf5, 0x9e, 0x79, 0x51, 0xf8, 0x61, 0x43, 0x73, 0x41,
-	0xa7, 0x0f, 0xb7, 0x26, 0xec, 0xa6, 0x2f, 0xe6, 0x51, 0xd9, 0x4f, 0x5f, 0x6b, 0xaf, 0x84, 0xf5,
-	0x37, 0x39, 0xb3, 0xff, 0x1f, 0x2d, 0x45, 0x71, 0xe7, 0xaf, 0x12, 0xcc, 0xbf, 0x33, 0xfd, 0x88,
-	0x07, 0x95, 0x3c, 0x6f, 0x25, 0xcc, 0x5b, 0xbe, 0xb4, 0xf3, 0x2b, 0xfb, 0x79, 0xe8, 0x0e, 0xd4,
-	0xa4, 0xe3, 0xb5, 0x7d, 0xf3, 0xae, 0x25, 0xf2, 0x30, 0x94, 0x3e, 0xf3, 0x65, 0x1a, 0x9b, 0xac,
-	0xd4, 0xe6, 0xd0, 0xb0, 0x6d, 0xf1, 0xae, 0x85, 0x6d, 0x05, 0x17, 0xb9, 0x3a, 0x78, 0x9b, 0xb7,
-	0xa5, 0xd6, 0x05, 0xf5, 0x28, 0x78, 0x2b, 0xec, 0xef, 0x31, 0x5b, 0x93, 0xa7, 0x82, 0x27, 0x8e,
-	0xe6, 0x2a, 0xae, 0x1e, 0xf1, 0xf3, 0x1f, 0x05, 0x4f, 0x2c, 0x67, 0x67, 0xe9, 0xd7, 0x6c, 0xe4,
-	0x67, 0xe7, 0x66, 0xf8, 0x97, 0xe4, 0xbf, 0x00, 0x00, 0x00, 0xff, 0xff, 0xcb, 0xfb, 0x8e, 0xf5,
-	0xa2, 0x0c, 0x00, 0x00,
+	// 1486 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0x5f, 0x53, 0x1b, 0x47,
+	0x12, 0xb7, 0xfe, 0x80, 0xa4, 0xd6, 0x1f, 0xc4, 0x00, 0x66, 0x8d, 0xed, 0x3b, 0x6e, 0xef, 0xec,
+	0xa3, 0x7c, 0x67, 0xec, 0xe2, 0x5c, 0x94, 0x8b, 0x72, 0x5d, 0x1d, 0x08, 0xce, 0x71, 0x28, 0x10,
+	0x35, 0x18, 0xc7, 0x49, 0x1e, 0x36, 0xa3, 0xd5, 0x08, 0x16, 0xef, 0xee, 0x6c, 0xcd, 0xcc, 0x12,
+	0xf0, 0x37, 0xcb, 0x73, 0x2a, 0xdf, 0x21, 0x4f, 0x79, 0xcb, 0xf7, 0x48, 0x4d, 0xcf, 0xae, 0x10,
+	0x66, 0x6d, 0x13, 0xbf, 0x69, 0x7e, 0xfd, 0xfb, 0xf5, 0xf4, 0xf4, 0xf4, 0x76, 0x8f, 0xa0, 0x1d,
+	0x71, 0x2d, 0x03, 0x5f, 0xad, 0x26, 0x52, 0x68, 0x41, 0xe6, 0x94, 0x10, 0xf1, 0xb1, 0x37, 0x48,
+	0x83, 0x70, 0xe8, 0x65, 0x26, 0xf7, 0xd7, 0x16, 0x34, 0xf7, 0xec, 0xef, 0x2d, 0xa6, 0x38, 0x79,
+	0x0a, 0xf3, 0x96, 0x30, 0x64, 0x9a, 0x7b, 0x3a, 0x88, 0xb8, 0xd2, 0x2c, 0x4a, 0x9c, 0xd2, 0x72,
+	0x69, 0xa5, 0x42, 0x09, 0xda, 0xb6, 0x99, 0xe6, 0xaf, 0x73, 0x0b, 0xb9, 0x03, 0x75, 0xab, 0x08,
+	0x86, 0x4e, 0x79, 0xb9, 0xb4, 0xd2, 0xa0, 0x35, 0x5c, 0xbf, 0x1a, 0x92, 0x0d, 0xb8, 0x93, 0x84,
+	0x4c, 0x8f, 0x84, 0x8c, 0xbc, 0x33, 0x2e, 0x55, 0x20, 0x62, 0xcf, 0x17, 0x43, 0x1e, 0xb3, 0x88,
+	0x3b, 0x15, 0xe4, 0x2e, 0xe6, 0x84, 0x37, 0xd6, 0xde, 0xcb, 0xcc, 0xe4, 0x01, 0x74, 0x34, 0x93,
+	0xc7, 0x5c, 0x7b, 0x89, 0x14, 0xc3, 0xd4, 0xd7, 0x4e, 0x15, 0x05, 0x6d, 0x8b, 0x1e, 0x58, 0x90,
+	0x0c, 0x61, 0x3e, 0xa3, 0xd9, 0x20, 0xce, 0x98, 0x0c, 0x58, 0xac, 0x9d, 0xa9, 0xe5, 0xd2, 0x4a,
+	0x67, 0xed, 0xf1, 0x6a, 0xc1, 0x99, 0x57, 0x27, 0xce, 0xbb, 0xba, 0x65, 0x2c, 0x6f, 0xac, 0x68,
+	0xa3, 0xb2, 0xb3, 0xff, 0x92, 0x12, 0xeb, 0x6f, 0xd2, 0x40, 0xfa, 0xd0, 0xcc, 0x76, 0x61, 0xd2,
+	0x3f, 0x71, 0xa6, 0xd1, 0xf9, 0x83, 0xcf, 0x3a, 0xdf, 0x94, 0xfe, 0xc9, 0x46, 0xed, 0x68, 0x7f,
+	0x77, 0xbf, 0xff, 0xcd, 0x3e, 0x05, 0xeb, 0xc2, 0x80, 0x64, 0x15, 0xe6, 0x26, 0x1c, 0x8e, 0xa3,
+	0xae, 0xe1, 0x11, 0x67, 0x2f, 0x89, 0x79, 0x00, 0xff, 0x86, 0x2c, 0x2c, 0xcf, 0x4f, 0xd2, 0x31,
+	0xbd, 0x8e, 0xf4, 0xae, 0xb5, 0xf4, 0x92, 0x34, 0x67, 0xef, 0x42, 0xe3, 0x44, 0xa8, 0x2c, 0xd8,
+	0xc6, 0x17, 0x05, 0x5b, 0x37, 0x0e, 0x30, 0x54, 0x0a, 0x6d, 0x74, 0xb6, 0x16, 0x0f, 0xad, 0x43,
+	0xf8, 0x22, 0x87, 0x4d, 0xe3, 0x64, 0x2d, 0x1e, 0xa2, 0xcf, 0x45, 0xa8, 0xa1, 0x4f, 0xa1, 0x9c,
+	0x26, 0x9e, 0x61, 0xda, 0x2c, 0xfb, 0x8a, 0xb8, 0xd9, 0x66, 0x42, 0x79, 0xfc, 0x5c, 0x4b, 0xe6,
+	0xb4, 0xd0, 0xdc, 0xb4, 0xe6, 0x1d, 0x03, 0x8d, 0x39, 0xbe, 0x14, 0x4a, 0x19, 0x17, 0xed, 0x4b,
+	0x4e, 0xcf, 0x60, 0x7d, 0x45, 0x1e, 0xc2, 0xcc, 0x04, 0x07, 0xc3, 0xee, 0xd8, 0xf2, 0x19, 0xb3,
+	0x30, 0x90, 0xc7, 0x30, 0x37, 0xc1, 0x1b, 0x1f, 0x71, 0xc6, 0x26, 0x76, 0xcc, 0x9d, 0x88, 0x5b,
+	0xa4, 0xda, 0x1b, 0x06, 0xd2, 0xe9, 0xda, 0xb8, 0x45, 0xaa, 0xb7, 0x03, 0x49, 0xfe, 0x0b, 0x4d,
+	0xc5, 0x75, 0x9a, 0x78, 0x5a, 0x88, 0x50, 0x39, 0xb3, 0xcb, 0x95, 0x95, 0xe6, 0xda, 0xfd, 0xc2,
+	0x14, 0x1d, 0x70, 0x39, 0x7a, 0x15, 0x8f, 0x04, 0x05, 0x54, 0xbc, 0x36, 0x02, 0xb2, 0x01, 0x8d,
+	0x77, 0x4c, 0x07, 0x9e, 0x4c, 0x63, 0xe5, 0x90, 0x9b, 0xa8, 0xeb, 0x86, 0x4f, 0xd3, 0x58, 0x91,
+	0x17, 0x00, 0x96, 0x89, 0xe2, 0xb9, 0x9b, 0x88, 0x1b, 0x68, 0xcd, 0xd5, 0x71, 0x10, 0x9f, 0x32,
+	0xab, 0x9e, 0xbf, 0x91, 0x1a, 0x05, 0xa8, 0xfe, 0x0f, 0x4c, 0x69, 0xa1, 0x59, 0xe8, 0x2c, 0x2c,
+	0x97, 0x3e, 0x2f, 0xb4, 0x5c, 0xf2, 0x06, 0x8a, 0x5a, 0x91, 0x73, 0x1b, 0x5d, 0x3c, 0x2c, 0x74,
+	0x71, 0x68, 0x30, 0xfc, 0x24, 0xb3, 0x0a, 0xa3, 0xb3, 0xea, 0x43, 0x88, 0xf4, 0xa0, 0x65, 0x55,
+	0xbe, 0x88, 0x47, 0xc1, 0xb1, 0xb3, 0x88, 0x0e, 0x97, 0x0b, 0x1d, 0xa2, 0xb0, 0x87, 0x3c, 0xda,
+	0x1c, 0x5c, 0x2e, 0xc8, 0x12, 0x60, 0xe9, 0x63, 0x8b, 0x72, 0xf0, 0x8e, 0xc7, 0x6b, 0xf2, 0x2d,
+	0xcc, 0xab, 0x0b, 0xa5, 0x79, 0xe4, 0x49, 0xae, 0x44, 0x2a, 0x7d, 0xee, 0x05, 0xf1, 0x48, 0x38,
+	0x77, 0x70, 0xa3, 0x7f, 0x16, 0x47, 0x8e, 0x02, 0x9a, 0xf1, 0x31, 0x0d, 0x44, 0x5d, 0xc3, 0xc8,
+	0xdf, 0xa1, 0x9d, 0xc7, 0x1e, 0x45, 0x2c, 0x1e, 0x3a, 0x4b, 0xb8, 0x77, 0x2b, 0x0b, 0x0d, 0x31,
+	0x73, 0x57, 0x03, 0xf6, 0x9e, 0x87, 0xf6, 0xae, 0xee, 0xde, 0xe8, 0xae, 0x50, 0x60, 0xee, 0xca,
+	0x7d, 0x0a, 0xad, 0x2b, 0x4d, 0xad, 0x0e, 0xd5, 0xa3, 0xc3, 0x1d, 0xda, 0xbd, 0x45, 0xda, 0xd0,
+	0x30, 0xbf, 0xb6, 0x77, 0xb6, 0x8e, 0x5e, 0x76, 0x4b, 0xa4, 0x06, 0xa6, 0x11, 0x76, 0xcb, 0xee,
+	0x0b, 0xa8, 0x62, 0xd9, 0x37, 0x21, 0xff, 0x8c, 0xbb, 0xb7, 0x8c, 0x75, 0x93, 0xee, 0x75, 0x4b,
+	0xa4, 0x01, 0x53, 0x9b, 0x74, 0x6f, 0xfd, 0x59, 0xb7, 0x6c, 0xb0, 0xb7, 0xcf, 0xd7, 0xbb, 0x15,
+	0x02, 0x30, 0xfd, 0xf6, 0xf9, 0xba, 0xb7, 0xfe, 0xac, 0x5b, 0x75, 0x8f, 0xa1, 0x39, 0x91, 0x65,
+	0x33, 0x27, 0x52, 0xc5, 0xbd, 0x63, 0x11, 0x31, 0x9c, 0x26, 0x75, 0x5a, 0x4b, 0x15, 0x7f, 0x29,
+	0x22, 0x66, 0x3e, 0x2b, 0x63, 0x92, 0x03, 0x8e, 0x13, 0xa4, 0x4e, 0xa7, 0x53, 0xc5, 0xe9, 0x80,
+	0x93, 0x7f, 0x40, 0x67, 0x24, 0x4c, 0x9a, 0xc7, 0xca, 0x0a, 0xda, 0x5b, 0x88, 0x1e, 0x59, 0xb9,
+	0x2b, 0x80, 0x5c, 0xcf, 0x32, 0x59, 0x83, 0x05, 0x2c, 0x37, 0x2f, 0x39, 0xb9, 0x50, 0x81, 0xcf,
+	0x42, 0x2f, 0xe2, 0x91, 0x90, 0x17, 0xb8, 0x79, 0x95, 0xce, 0xa1, 0xf1, 0x20, 0xb3, 0xed, 0xa1,
+	0xc9, 0x0c, 0x1d, 0x76, 0xc6, 0x82, 0x90, 0x0d, 0x42, 0x6e, 0x3a, 0xad, 0xc2, 0x78, 0xa6, 0x68,
+	0x7b, 0x8c, 0xf6, 0x92, 0x54, 0xb9, 0xbf, 0x95, 0xa1, 0x9e, 0x67, 0x98, 0x10, 0xa8, 0x0e, 0xb9,
+	0xf2, 0xd1, 0x6d, 0x83, 0xe2, 0x6f, 0x83, 0x61, 0x01, 0xd9, 0x79, 0x88, 0xbf, 0xc9, 0x7d, 0x00,
+	0xa5, 0x99, 0xd4, 0x38, 0x54, 0xf1, 0x1c, 0x55, 0xda, 0x40, 0xc4, 0xcc, 0x52, 0x72, 0x17, 0x1a,
+	0x92, 0xb3, 0xd0, 0x5a, 0xab, 0x68, 0xad, 0x1b, 0x00, 0x8d, 0x7f, 0x03, 0xb0, 0xc1, 0x9b, 0x44,
+	0xe0, 0x6c, 0xab, 0x6e, 0x95, 0x9d, 0x12, 0x6d, 0x58, 0xf4, 0x48, 0x71, 0xf2, 0x03, 0x2c, 0x26,
+	0x52, 0xf8, 0x5c, 0x29, 0xae, 0x3e, 0x28, 0xcf, 0x69, 0x2c, 0x94, 0x95, 0xe2, 0x42, 0xb1, 0x9a,
+	0x2b, 0xf5, 0xb9, 0x30, 0x76, 0x74, 0x25, 0xa1, 0x01, 0x2c, 0x15, 0xec, 0xa0, 0x58, 0x94, 0x84,
+	0x5c, 0x39, 0x35, 0xdc, 0xe4, 0x5f, 0x37, 0xd9, 0xe4, 0xd0, 0x4a, 0xa8, 0x73, 0x6d, 0x9f, 0xcc,
+	0xe2, 0xfe, 0x54, 0x81, 0xb9, 0x82, 0xc8, 0xc6, 0x79, 0x2d, 0x4d, 0xe4, 0x75, 0x05, 0xba, 0xa9,
+	0xe2, 0x12, 0x13, 0xe7, 0x45, 0x81, 0xe9, 0xe4, 0x98, 0xf7, 0x2a, 0xed, 0x18, 0xdc, 0xe4, 0x6f,
+	0x0f, 0x51, 0x33, 0x44, 0xb3, 0xcf, 0x77, 0x92, 0x6b, 0x6f, 0xa2, 0x6b, 0x2d, 0x13, 0xec, 0x7b,
+	0x00, 0x11, 0x3b, 0xf7, 0xa4, 0x52, 0xde, 0xbb, 0x41, 0x7e, 0x23, 0x11, 0x3b, 0xa7, 0x4a, 0xed,
+	0x0e, 0xc8, 0x23, 0x98, 0x8d, 0x82, 0x58, 0x48, 0x2f, 0x61, 0xc7, 0xdc, 0x1b, 0xb1, 0x34, 0xd4,
+	0xca, 0x5e, 0x0c, 0x9d, 0x41, 0xc3, 0x01, 0x3b, 0xe6, 0xff, 0x47, 0x18, 0xb9, 0xec, 0xf4, 0x03,
+	0xee, 0x74, 0xc6, 0x65, 0xa7, 0x57, 0xb8, 0x7f, 0x81, 0x66, 0x20, 0xbc, 0x20, 0x4e, 0x52, 0x6d,
+	0xb6, 0xad, 0xd9, 0x32, 0x09, 0xc4, 0x2b, 0x83, 0xec, 0x0e, 0xc8, 0x32, 0xb4, 0x02, 0xe1, 0x89,
+	0x54, 0x67, 0x84, 0x3a, 0x12, 0x20, 0x10, 0x7d, 0x84, 0x76, 0x07, 0xe4, 0x05, 0x2c, 0x9d, 0x89,
+	0x30, 0x8d, 0x35, 0x93, 0x17, 0xa6, 0x13, 0x6a, 0x7e, 0xae, 0x3d, 0xf5, 0x63, 0xa0, 0xfd, 0x13,
+	0xae, 0xf0, 0x35, 0x50, 0xa5, 0xce, 0x98, 0xd1, 0xb3, 0x84, 0xc3, 0xcc, 0x4e, 0xfe, 0x07, 0xf7,
+	0x82, 0xf8, 0x13, 0x7a, 0x40, 0xfd, 0x52, 0x10, 0x7f, 0xcc, 0x83, 0x2b, 0xe1, 0x76, 0xf1, 0x7d,
+	0x17, 0xde, 0xde, 0x36, 0xd4, 0xf2, 0x0a, 0x2a, 0x63, 0x05, 0x3d, 0xba, 0x79, 0x05, 0xd1, 0x5c,
+	0xea, 0xfe, 0x52, 0x82, 0x85, 0x42, 0x8a, 0xe9, 0xab, 0x92, 0x87, 0x4c, 0x07, 0x67, 0xf6, 0x35,
+	0x9b, 0x7d, 0xfd, 0xad, 0x1c, 0xc4, 0xcf, 0x6b, 0x01, 0xa6, 0xb3, 0x6b, 0xb6, 0x85, 0x33, 0x25,
+	0xf1, 0x8e, 0x8b, 0x2a, 0xab, 0xf2, 0x27, 0x2a, 0xab, 0xfa, 0x91, 0xca, 0x72, 0xa0, 0xa6, 0x4f,
+	0x24, 0x67, 0x43, 0x5b, 0x31, 0x6d, 0x9a, 0x2f, 0xdd, 0xdf, 0x4b, 0xd0, 0xd9, 0x13, 0xc3, 0x34,
+	0xe4, 0xaf, 0x2f, 0x12, 0x5b, 0xf2, 0xdf, 0xe7, 0x43, 0xcd, 0xba, 0xc1, 0xf8, 0x3b, 0x6b, 0x4f,
+	0x8a, 0x5f, 0x5f, 0x57, 0xa4, 0x76, 0xc6, 0xd9, 0xce, 0x38, 0xf1, 0x0e, 0x1b, 0x5c, 0xa2, 0xe4,
+	0xaf, 0xd0, 0x8c, 0x50, 0xe3, 0xe9, 0x8b, 0x24, 0x6f, 0x57, 0x10, 0x8d, 0xdd, 0x98, 0x06, 0x1c,
+	0xa7, 0x91, 0x27, 0x46, 0x9e, 0x05, 0x6d, 0x02, 0xda, 0xb4, 0x15, 0xa7, 0x51, 0x7f, 0x64, 0xf7,
+	0x53, 0xee, 0x93, 0xac, 0xd3, 0x67, 0x5e, 0xaf, 0x8c, 0x8b, 0x06, 0x4c, 0x1d, 0xf6, 0xfb, 0xfb,
+	0x66, 0xae, 0xd4, 0xa1, 0xba, 0xb7, 0xb9, 0xbb, 0xd3, 0x2d, 0xbb, 0x21, 0x2c, 0xf5, 0x64, 0xa0,
+	0x4d, 0xe7, 0x3d, 0x52, 0x5c, 0x7e, 0x2d, 0x52, 0x19, 0xf3, 0x8b, 0x7c, 0x8e, 0x17, 0xd5, 0xc9,
+	0x06, 0xd4, 0xf2, 0x77, 0x42, 0xf9, 0x13, 0x63, 0x7d, 0xe2, 0xfd, 0x49, 0x73, 0x81, 0x3b, 0x80,
+	0xbb, 0x05, 0xbb, 0xa9, 0xcb, 0x67, 0x43, 0xd5, 0x4f, 0x4f, 0x95, 0x53, 0xc2, 0xfa, 0x2b, 0xce,
+	0xec, 0xc7, 0xa3, 0xa5, 0x28, 0x76, 0x7f, 0x2e, 0xc1, 0xec, 0xb5, 0x47, 0x8a, 0xb9, 0xe9, 0x3c,
+	0x6f, 0x25, 0x7b, 0xd3, 0xd9, 0xd2, 0x3c, 0x33, 0xb2, 0x57, 0xbc, 0x3d, 0x50, 0x9b, 0x8e, 0xd7,
+	0xa6, 0x5f, 0xd8, 0xc9, 0xc5, 0xc2, 0x50, 0xf8, 0x9e, 0x2f, 0xd2, 0x58, 0x67, 0x85, 0x37, 0x83,
+	0x86, 0x4d, 0x83, 0xf7, 0x0c, 0x6c, 0x6a, 0x74, 0x92, 0xab, 0x82, 0xf7, 0xf9, 0xf4, 0xe8, 0x5c,
+	0x52, 0x0f, 0x83, 0xf7, 0xdc, 0x3c, 0x9b, 0x4d, 0x3f, 0x3b, 0xe1, 0x2c, 0xb1, 0x34, 0xdb, 0xad,
+	0x9a, 0x11, 0x3b, 0xff, 0x8a, 0xb3, 0xc4, 0x70, 0xb6, 0x16, 0xbe, 0xcb, 0x5e, 0x66, 0xd9, 0xb9,
+	0x3d, 0xfc, 0xe7, 0xf8, 0xc7, 0x00, 0x6f, 0xc7, 0x07, 0x22, 0x49, 0x0e, 0x00, 0x00,
 }
@@ -0,0 +1,36 @@
+package proc
+
+// MemInfo holds information regarding the memory usage of the system.
+type MemInfo struct {
+	// Total usable RAM.
+	MemTotal uint64
+
+	// Free RAM.
+	MemFree uint64
+
+	// An estimate of the memory available for starting new processes
+	// without swapping.
+	MemAvailable uint64
+
+	// Total swap space.
+	SwapTotal uint64
+
+	// Unused swap space.
+	SwapFree uint64
+}
+
+func fillMemInfo(m *MemInfo, key, value string) {
+	v := strToUint64(value)
+	switch key {
+	case "MemTotal":
+		m.MemTotal = v
+	case "MemFree":
+		m.MemFree = v
+	case "MemAvailable":
+		m.MemAvailable = v
+	case "SwapTotal":
+		m.SwapTotal = v
+	case "SwapFree":
+		m.SwapFree = v
+	}
+}
@@ -0,0 +1,13 @@
+package proc
+
+import (
+	"errors"
+
+	"android/soong/finder/fs"
+)
+
+// NewMemInfo returns an error as /proc/meminfo is not available for darwin
+// distribution based.
+func NewMemInfo(_ fs.FileSystem) (*MemInfo, error) {
+	return &MemInfo{}, errors.New("/proc/meminfo is not supported on darwin")
+}
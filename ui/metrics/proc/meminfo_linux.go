@@ -0,0 +1,40 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"android/soong/finder/fs"
+)
+
+// NewMemInfo returns the memory usage of the system from /proc/meminfo.
+func NewMemInfo(fileSystem fs.FileSystem) (*MemInfo, error) {
+	const meminfoFname = "/proc/meminfo"
+	r, err := fileSystem.Open(meminfoFname)
+	if err != nil {
+		return &MemInfo{}, err
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return &MemInfo{}, err
+	}
+
+	m := &MemInfo{}
+	for _, l := range strings.Split(string(data), "\n") {
+		// Lines are of the form "MemTotal:       16318440 kB".
+		kv := strings.SplitN(l, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		fillMemInfo(m, strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+
+	// MemAvailable is missing from kernels older than 3.14.
+	if m.MemTotal == 0 || !strings.Contains(string(data), "MemAvailable:") {
+		return m, fmt.Errorf("MemTotal or MemAvailable missing from %s", meminfoFname)
+	}
+	return m, nil
+}
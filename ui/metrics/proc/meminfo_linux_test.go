@@ -0,0 +1,54 @@
+package proc
+
+import (
+	"reflect"
+	"testing"
+
+	"android/soong/finder/fs"
+)
+
+func TestNewMemInfo(t *testing.T) {
+	fs := fs.NewMockFs(nil)
+
+	if err := fs.MkDirs("/proc"); err != nil {
+		t.Fatalf("failed to create /proc: %v", err)
+	}
+	if err := fs.WriteFile("/proc/meminfo", meminfoData, 0644); err != nil {
+		t.Fatalf("failed to write /proc/meminfo: %v", err)
+	}
+
+	m, err := NewMemInfo(fs)
+	if err != nil {
+		t.Fatalf("got %v, want nil for error", err)
+	}
+
+	if !reflect.DeepEqual(m, expectedMemInfo) {
+		t.Errorf("got %v, expecting %v for MemInfo", m, expectedMemInfo)
+	}
+
+	if err := fs.WriteFile("/proc/meminfo", []byte("MemTotal:       16318440 kB\n"), 0644); err != nil {
+		t.Fatalf("failed to write /proc/meminfo: %v", err)
+	}
+	if _, err := NewMemInfo(fs); err == nil {
+		t.Errorf("got nil, want an error for a missing MemAvailable")
+	}
+}
+
+var meminfoData = []byte(`MemTotal:       16318440 kB
+MemFree:          826104 kB
+MemAvailable:    9203812 kB
+Buffers:          529344 kB
+Cached:          8017032 kB
+SwapCached:        12016 kB
+SwapTotal:       2097148 kB
+SwapFree:        1904636 kB
+HugePages_Total:       0
+`)
+
+var expectedMemInfo = &MemInfo{
+	MemTotal:     16318440 * 1024,
+	MemFree:      826104 * 1024,
+	MemAvailable: 9203812 * 1024,
+	SwapTotal:    2097148 * 1024,
+	SwapFree:     1904636 * 1024,
+}
@@ -0,0 +1,29 @@
+package proc
+
+import (
+	"time"
+)
+
+// ProcStat holds information regarding the CPU usage and the parent of an
+// executing process.
+type ProcStat struct {
+	// Process PID.
+	pid int
+
+	// The file name of the executable, truncated to 15 characters.
+	Command string
+
+	// Parent process PID.
+	Ppid int
+
+	// Time spent executing in user mode.
+	UserTime time.Duration
+
+	// Time spent executing in kernel mode.
+	SystemTime time.Duration
+
+	// Time spent in user and kernel mode by the children of the process
+	// that it waited for, including their own waited for children.
+	ChildrenUserTime   time.Duration
+	ChildrenSystemTime time.Duration
+}
@@ -0,0 +1,11 @@
+package proc
+
+import (
+	"android/soong/finder/fs"
+)
+
+// NewProcStat returns a zero filled value of ProcStat as it
+// is not supported for darwin distribution based.
+func NewProcStat(pid int, _ fs.FileSystem) (*ProcStat, error) {
+	return &ProcStat{}, nil
+}
@@ -0,0 +1,80 @@
+package proc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"android/soong/finder/fs"
+)
+
+// The unit of the times in /proc/<pid>/stat. USER_HZ is 100 on all supported
+// architectures.
+const clockTick = time.Second / 100
+
+// NewProcStat returns an instance of the ProcStat that contains CPU usage
+// information of the process. The information is extracted from the
+// "/proc/<pid>/stat" text file. This is only available for Linux
+// distribution that supports /proc.
+func NewProcStat(pid int, fileSystem fs.FileSystem) (*ProcStat, error) {
+	statFname := filepath.Join("/proc", strconv.Itoa(pid), "stat")
+	r, err := fileSystem.Open(statFname)
+	if err != nil {
+		return &ProcStat{}, err
+	}
+	defer r.Close()
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return &ProcStat{}, err
+	}
+
+	// The second field is the command name in parentheses, which may contain
+	// spaces and parentheses itself, so start after the last ')'.
+	first := strings.IndexByte(string(data), '(')
+	i := strings.LastIndexByte(string(data), ')')
+	if first < 0 || i < first {
+		return &ProcStat{}, fmt.Errorf("malformed %s", statFname)
+	}
+	// The fields after the command name start at field 3 (state), ppid is
+	// field 4, utime and stime are fields 14 and 15, cutime and cstime are
+	// fields 16 and 17.
+	fields := strings.Fields(string(data[i+1:]))
+	if len(fields) < 15 {
+		return &ProcStat{}, fmt.Errorf("malformed %s", statFname)
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return &ProcStat{}, fmt.Errorf("malformed ppid in %s: %v", statFname, err)
+	}
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return &ProcStat{}, fmt.Errorf("malformed utime in %s: %v", statFname, err)
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return &ProcStat{}, fmt.Errorf("malformed stime in %s: %v", statFname, err)
+	}
+
+	cutime, err := strconv.ParseUint(fields[13], 10, 64)
+	if err != nil {
+		return &ProcStat{}, fmt.Errorf("malformed cutime in %s: %v", statFname, err)
+	}
+	cstime, err := strconv.ParseUint(fields[14], 10, 64)
+	if err != nil {
+		return &ProcStat{}, fmt.Errorf("malformed cstime in %s: %v", statFname, err)
+	}
+
+	return &ProcStat{
+		pid:                pid,
+		Command:            string(data[first+1 : i]),
+		Ppid:               ppid,
+		UserTime:           time.Duration(utime) * clockTick,
+		SystemTime:         time.Duration(stime) * clockTick,
+		ChildrenUserTime:   time.Duration(cutime) * clockTick,
+		ChildrenSystemTime: time.Duration(cstime) * clockTick,
+	}, nil
+}
@@ -0,0 +1,49 @@
+package proc
+
+import (
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"android/soong/finder/fs"
+)
+
+func TestNewProcStat(t *testing.T) {
+	fs := fs.NewMockFs(nil)
+
+	pid := 4032827
+	procDir := filepath.Join("/proc", strconv.Itoa(pid))
+	if err := fs.MkDirs(procDir); err != nil {
+		t.Fatalf("failed to create proc pid dir %s: %v", procDir, err)
+	}
+	statFilename := filepath.Join(procDir, "stat")
+
+	if err := fs.WriteFile(statFilename, statData, 0644); err != nil {
+		t.Fatalf("failed to write proc file %s: %v", statFilename, err)
+	}
+
+	stat, err := NewProcStat(pid, fs)
+	if err != nil {
+		t.Fatalf("got %v, want nil for error", err)
+	}
+
+	if !reflect.DeepEqual(stat, expectedStat) {
+		t.Errorf("got %v, expecting %v for ProcStat", stat, expectedStat)
+	}
+}
+
+// The command name contains spaces and parentheses to check that it is
+// skipped correctly.
+var statData = []byte("4032827 (fake (process) 1) S 1 4032827 4032827 0 -1 4194560 25713 0 0 0 1234 567 89 10 20 0 46 0 12345 750829568 17289 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n")
+
+var expectedStat = &ProcStat{
+	pid:                4032827,
+	Command:            "fake (process) 1",
+	Ppid:               1,
+	UserTime:           12340 * time.Millisecond,
+	SystemTime:         5670 * time.Millisecond,
+	ChildrenUserTime:   890 * time.Millisecond,
+	ChildrenSystemTime: 100 * time.Millisecond,
+}
@@ -60,6 +60,9 @@ type ProcStatus struct {
 
 	// Size of hugetlb memory page size.
 	HugetlbPages uint64
+
+	// Number of threads.
+	Threads uint64
 }
 
 // fillProcStatus takes the key and value, converts the value
@@ -99,6 +102,8 @@ func fillProcStatus(s *ProcStatus, key, value string) {
 		s.VmSwap = v
 	case "HugetlbPages":
 		s.HugetlbPages = v
+	case "Threads":
+		s.Threads = v
 	}
 }
 
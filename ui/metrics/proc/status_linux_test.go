@@ -109,4 +109,5 @@ var expectedStatus = &ProcStatus{
 	VmPTE:        233472,
 	VmSwap:       10240,
 	HugetlbPages: 22528,
+	Threads:      46,
 }
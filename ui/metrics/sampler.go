@@ -0,0 +1,220 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+// This file contains a background sampler of the resource usage of a process,
+// such as kati or ninja, and all of its descendants, while a build event is
+// active. The descendants are included so that processes started by another
+// one are covered too, such as soong_build, which is started by the bootstrap
+// ninja. The samples are stored in the PerfInfo of the event as a time series,
+// and written to the trace as counters.
+
+import (
+	"strconv"
+	"time"
+
+	"android/soong/finder/fs"
+	"android/soong/ui/metrics/metrics_proto"
+	"android/soong/ui/metrics/proc"
+	"android/soong/ui/tracer"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// The initial time between samples. Declared as a variable for unit testing
+// purpose.
+var resourceSampleInterval = time.Second
+
+// The maximum number of samples kept for a process. Once reached, every other
+// sample is dropped and the interval is doubled, so that long running
+// processes are covered from start to end.
+const maxResourceSamples = 1024
+
+type resourceSampler struct {
+	name       string
+	pid        int
+	start      time.Time
+	fileSystem fs.FileSystem
+	tracer     tracer.Tracer
+
+	interval time.Duration
+	samples  []*soong_metrics_proto.ProcessResourceSample
+
+	// The time and total CPU time of the previous sample, used to compute
+	// the CPU utilization written to the trace.
+	lastTime time.Time
+	lastCpu  time.Duration
+
+	stop chan bool
+	done chan bool
+}
+
+// StartSampling starts sampling the total RSS, CPU time and thread count of the
+// process pid and its descendants in the background, until the returned
+// function is called. The samples are added to the active event, and written
+// as counters to trace if it isn't nil. Sampling also stops once the process
+// exits.
+func (t *EventTracer) StartSampling(name string, pid int, trace tracer.Tracer) func() {
+	var e *event
+	start := _now()
+	if !t.empty() {
+		e = t.peek()
+		start = e.start
+	}
+
+	s := newResourceSampler(name, pid, start, fs.OsFs, trace)
+	go s.run()
+
+	return func() {
+		samples := s.finish()
+		if e != nil && len(samples.Samples) > 0 {
+			e.procResSamples = append(e.procResSamples, samples)
+		}
+	}
+}
+
+func newResourceSampler(name string, pid int, start time.Time, fileSystem fs.FileSystem, trace tracer.Tracer) *resourceSampler {
+	return &resourceSampler{
+		name:       name,
+		pid:        pid,
+		start:      start,
+		fileSystem: fileSystem,
+		tracer:     trace,
+		interval:   resourceSampleInterval,
+		stop:       make(chan bool),
+		done:       make(chan bool),
+	}
+}
+
+func (s *resourceSampler) run() {
+	defer close(s.done)
+
+	for s.sample() {
+		select {
+		case <-s.stop:
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// finish stops the sampling and returns the samples taken.
+func (s *resourceSampler) finish() *soong_metrics_proto.ProcessResourceSamples {
+	close(s.stop)
+	<-s.done
+
+	return &soong_metrics_proto.ProcessResourceSamples{
+		Name:    proto.String(s.name),
+		Samples: s.samples,
+	}
+}
+
+// sample takes a single sample of the process and its descendants, returning
+// false if the process can no longer be sampled.
+func (s *resourceSampler) sample() bool {
+	status, err := proc.NewProcStatus(s.pid, s.fileSystem)
+	if err != nil || status.VmRss == 0 {
+		// The process has exited, or /proc isn't supported.
+		return false
+	}
+	stat, err := proc.NewProcStat(s.pid, s.fileSystem)
+	if err != nil {
+		return false
+	}
+
+	// The CPU time of the descendants that have exited is included in the
+	// children times of the processes that waited for them.
+	rss, threads := status.VmRss, status.Threads
+	userTime := stat.UserTime + stat.ChildrenUserTime
+	systemTime := stat.SystemTime + stat.ChildrenSystemTime
+	for _, pid := range descendants(s.fileSystem, s.pid) {
+		status, err := proc.NewProcStatus(pid, s.fileSystem)
+		if err != nil {
+			// The process exited while reading /proc.
+			continue
+		}
+		stat, err := proc.NewProcStat(pid, s.fileSystem)
+		if err != nil {
+			continue
+		}
+		rss += status.VmRss
+		threads += status.Threads
+		userTime += stat.UserTime + stat.ChildrenUserTime
+		systemTime += stat.SystemTime + stat.ChildrenSystemTime
+	}
+
+	now := _now()
+	cpu := userTime + systemTime
+
+	if len(s.samples) == maxResourceSamples {
+		for i := 0; i < maxResourceSamples/2; i++ {
+			s.samples[i] = s.samples[2*i]
+		}
+		s.samples = s.samples[:maxResourceSamples/2]
+		s.interval *= 2
+	}
+	s.samples = append(s.samples, &soong_metrics_proto.ProcessResourceSample{
+		RelativeTime:     proto.Uint64(uint64(now.Sub(s.start).Nanoseconds())),
+		RssKb:            proto.Uint64(rss / 1024),
+		UserTimeMicros:   proto.Uint64(uint64(userTime.Microseconds())),
+		SystemTimeMicros: proto.Uint64(uint64(systemTime.Microseconds())),
+		Threads:          proto.Uint32(uint32(threads)),
+	})
+
+	if s.tracer != nil {
+		s.tracer.Counter(s.name+" rss (bytes)", int64(rss))
+		s.tracer.Counter(s.name+" threads", int64(threads))
+		// The total drops if a descendant exits before it is waited for.
+		if !s.lastTime.IsZero() && now.After(s.lastTime) && cpu >= s.lastCpu {
+			s.tracer.Counter(s.name+" cpu (%)", int64(100*(cpu-s.lastCpu)/now.Sub(s.lastTime)))
+		}
+	}
+	s.lastTime = now
+	s.lastCpu = cpu
+
+	return true
+}
+
+// descendants returns the processes under root in /proc.
+func descendants(fileSystem fs.FileSystem, root int) []int {
+	entries, err := fileSystem.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		stat, err := proc.NewProcStat(pid, fileSystem)
+		if err != nil {
+			// The process exited while reading /proc.
+			continue
+		}
+		children[stat.Ppid] = append(children[stat.Ppid], pid)
+	}
+
+	var ret []int
+	queue := []int{root}
+	for len(queue) > 0 {
+		pid := queue[0]
+		queue = queue[1:]
+		ret = append(ret, children[pid]...)
+		queue = append(queue, children[pid]...)
+	}
+	return ret
+}
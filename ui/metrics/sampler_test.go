@@ -0,0 +1,198 @@
+// Copyright 2021 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//   http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"android/soong/finder/fs"
+	"android/soong/ui/tracer"
+)
+
+type counterTracer struct {
+	tracer.Tracer
+	counters map[string][]int64
+}
+
+func (c *counterTracer) Counter(name string, value int64) {
+	c.counters[name] = append(c.counters[name], value)
+}
+
+func writeProcFiles(t *testing.T, fileSystem *fs.MockFs, rssKB, utime int) {
+	t.Helper()
+
+	status := []byte("Name:   fake_process\nVmRSS:  " + strconv.Itoa(rssKB) + " kB\nThreads:  4\n")
+	stat := []byte("42 (fake_process) S 1 42 42 0 -1 4194560 0 0 0 0 " + strconv.Itoa(utime) + " 0 0 0 20 0 4 0\n")
+	if err := fileSystem.WriteFile("/proc/42/status", status, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileSystem.WriteFile("/proc/42/stat", stat, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceSampler(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only supported on linux")
+	}
+
+	fileSystem := fs.NewMockFs(nil)
+	if err := fileSystem.MkDirs("/proc/42"); err != nil {
+		t.Fatal(err)
+	}
+
+	startTime := time.Date(2020, time.July, 13, 13, 0, 0, 0, time.UTC)
+	now := startTime
+	initialNow := _now
+	_now = func() time.Time { return now }
+	defer func() { _now = initialNow }()
+
+	trace := &counterTracer{counters: map[string][]int64{}}
+	s := newResourceSampler("ninja", 42, startTime, fileSystem, trace)
+
+	writeProcFiles(t, fileSystem, 1024, 0)
+	now = startTime.Add(time.Second)
+	if !s.sample() {
+		t.Fatal("expected first sample to succeed")
+	}
+
+	writeProcFiles(t, fileSystem, 2048, 50)
+	now = startTime.Add(2 * time.Second)
+	if !s.sample() {
+		t.Fatal("expected second sample to succeed")
+	}
+
+	if err := fileSystem.Remove("/proc/42/status"); err != nil {
+		t.Fatal(err)
+	}
+	if s.sample() {
+		t.Error("expected sampling to stop once the process is gone")
+	}
+
+	if len(s.samples) != 2 {
+		t.Fatalf("got %d, want 2 samples", len(s.samples))
+	}
+	second := s.samples[1]
+	if g, w := second.GetRelativeTime(), uint64(2*time.Second); g != w {
+		t.Errorf("got %d, want %d relative time", g, w)
+	}
+	if g, w := second.GetRssKb(), uint64(2048); g != w {
+		t.Errorf("got %d, want %d rss", g, w)
+	}
+	if g, w := second.GetUserTimeMicros(), uint64(500000); g != w {
+		t.Errorf("got %d, want %d user time", g, w)
+	}
+	if g, w := second.GetThreads(), uint32(4); g != w {
+		t.Errorf("got %d, want %d threads", g, w)
+	}
+
+	// 500ms of CPU time over one second.
+	if cpu := trace.counters["ninja cpu (%)"]; len(cpu) != 1 || cpu[0] != 50 {
+		t.Errorf("got %v, want [50] cpu counter", cpu)
+	}
+	if rss := trace.counters["ninja rss (bytes)"]; len(rss) != 2 || rss[1] != 2048*1024 {
+		t.Errorf("got %v, want 2 rss counters", rss)
+	}
+}
+
+func writeProcTreeFiles(t *testing.T, fileSystem *fs.MockFs, pid, ppid, rssKB, utime, cutime int) {
+	t.Helper()
+
+	dir := "/proc/" + strconv.Itoa(pid)
+	status := []byte("Name:   fake_process\nVmRSS:  " + strconv.Itoa(rssKB) + " kB\nThreads:  2\n")
+	stat := []byte(strconv.Itoa(pid) + " (fake_process) S " + strconv.Itoa(ppid) +
+		" 42 42 0 -1 4194560 0 0 0 0 " + strconv.Itoa(utime) + " 0 " + strconv.Itoa(cutime) + " 0 20 0 2 0\n")
+	if err := fileSystem.MkDirs(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileSystem.WriteFile(dir+"/status", status, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fileSystem.WriteFile(dir+"/stat", stat, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResourceSamplerDescendants(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only supported on linux")
+	}
+
+	fileSystem := fs.NewMockFs(nil)
+	// ninja (42), which waited for an action that used 100 ticks, runs
+	// soong_build (44) through a shell (43).
+	writeProcTreeFiles(t, fileSystem, 42, 1, 1024, 10, 100)
+	writeProcTreeFiles(t, fileSystem, 43, 42, 512, 1, 0)
+	writeProcTreeFiles(t, fileSystem, 44, 43, 4096, 200, 0)
+	// Unrelated processes aren't included.
+	writeProcTreeFiles(t, fileSystem, 50, 1, 8192, 1000, 0)
+
+	s := newResourceSampler("soong bootstrap", 42, _now(), fileSystem, nil)
+	if !s.sample() {
+		t.Fatal("expected sample to succeed")
+	}
+
+	sample := s.samples[0]
+	if g, w := sample.GetRssKb(), uint64(1024+512+4096); g != w {
+		t.Errorf("got %d, want %d rss", g, w)
+	}
+	if g, w := sample.GetUserTimeMicros(), uint64(311*10000); g != w {
+		t.Errorf("got %d, want %d user time", g, w)
+	}
+	if g, w := sample.GetThreads(), uint32(6); g != w {
+		t.Errorf("got %d, want %d threads", g, w)
+	}
+}
+
+func TestResourceSamplerDownsample(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("/proc is only supported on linux")
+	}
+
+	fileSystem := fs.NewMockFs(nil)
+	if err := fileSystem.MkDirs("/proc/42"); err != nil {
+		t.Fatal(err)
+	}
+	writeProcFiles(t, fileSystem, 1024, 0)
+
+	s := newResourceSampler("kati", 42, _now(), fileSystem, nil)
+	for i := 0; i < maxResourceSamples+1; i++ {
+		s.sample()
+	}
+
+	if g, w := len(s.samples), maxResourceSamples/2+1; g != w {
+		t.Errorf("got %d, want %d samples", g, w)
+	}
+	if g, w := s.interval, 2*resourceSampleInterval; g != w {
+		t.Errorf("got %s, want %s interval", g, w)
+	}
+}
+
+func TestStartSampling(t *testing.T) {
+	et := &EventTracer{}
+	et.Begin("test", "test", tracer.Thread(0))
+
+	// A pid that can't exist, sampling stops immediately.
+	stop := et.StartSampling("none", -1, nil)
+	stop()
+
+	perf := et.End(tracer.Thread(0))
+	if len(perf.GetProcessesResourceSamples()) != 0 {
+		t.Errorf("got %v, want no samples", perf.GetProcessesResourceSamples())
+	}
+}
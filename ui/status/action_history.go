@@ -0,0 +1,312 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+const (
+	// The number of previous durations kept for each action.
+	actionHistorySamples = 5
+
+	// Actions that haven't run in this many builds are dropped from the
+	// history.
+	actionHistoryMaxAge = 50
+
+	// Actions faster than this aren't recorded, they are too noisy to
+	// compare and would make up most of the history.
+	actionHistoryMinDuration = 100 * time.Millisecond
+
+	// An action has regressed if it took at least actionRegressionFactor
+	// times its median previous duration, and at least
+	// actionRegressionMinDelta longer.
+	actionRegressionFactor   = 2.0
+	actionRegressionMinDelta = 5 * time.Second
+
+	// The number of previous durations required before an action is
+	// checked for regressions.
+	actionRegressionMinSamples = 3
+
+	actionHistoryVersion = 1
+)
+
+// actionHistoryEntry is the recent durations of a single action, identified by
+// its first output.
+type actionHistoryEntry struct {
+	// DurationsMs are the most recent durations in milliseconds, oldest
+	// first.
+	DurationsMs []int64 `json:"durations_ms"`
+
+	// LastBuild is the build number in which the action last ran.
+	LastBuild int `json:"last_build"`
+}
+
+type actionHistoryData struct {
+	Version int                            `json:"version"`
+	Builds  int                            `json:"builds"`
+	Actions map[string]*actionHistoryEntry `json:"actions"`
+}
+
+// ActionRegression describes an action that took significantly longer than it
+// did in previous builds.
+type ActionRegression struct {
+	Output      string
+	Description string
+	Duration    time.Duration
+	Baseline    time.Duration
+
+	// Samples is the number of previous durations that Baseline is the
+	// median of.
+	Samples int
+}
+
+// ActionHistory is a StatusOutput that records the duration of each action
+// and compares it against the durations of the same action in previous builds.
+type ActionHistory struct {
+	log      logger.Logger
+	filename string
+	clock    clock
+
+	// Protects everything below, since Report may be called outside of
+	// the Status lock.
+	lock     sync.Mutex
+	running  map[*Action]time.Time
+	finished map[string]*actionHistoryResult
+}
+
+type actionHistoryResult struct {
+	description string
+	duration    time.Duration
+}
+
+// NewActionHistory returns an ActionHistory that keeps its database of
+// previous action durations in filename.
+func NewActionHistory(log logger.Logger, filename string) *ActionHistory {
+	return &ActionHistory{
+		log:      log,
+		filename: filename,
+		clock:    osClock{},
+		running:  make(map[*Action]time.Time),
+		finished: make(map[string]*actionHistoryResult),
+	}
+}
+
+var _ StatusOutput = (*ActionHistory)(nil)
+
+func (h *ActionHistory) StartAction(action *Action, counts Counts) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.running[action] = h.clock.Now()
+}
+
+func (h *ActionHistory) FinishAction(result ActionResult, counts Counts) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	start, ok := h.running[result.Action]
+	if !ok {
+		return
+	}
+	delete(h.running, result.Action)
+
+	// Failed actions may have stopped early, and actions without outputs
+	// can't be matched up between builds.
+	if result.Error != nil || len(result.Outputs) == 0 {
+		return
+	}
+
+	h.finished[result.Outputs[0]] = &actionHistoryResult{
+		description: result.Description,
+		duration:    h.clock.Now().Sub(start),
+	}
+}
+
+func (h *ActionHistory) Message(level MsgLevel, message string) {}
+
+func (h *ActionHistory) Flush() {}
+
+func (h *ActionHistory) Write(p []byte) (int, error) {
+	// Discard writes
+	return len(p), nil
+}
+
+// Report compares the durations of the actions run in this build against the
+// history, writes the actions that regressed to reportFile and prints a
+// summary to tool, then adds this build's durations to the history. It should
+// be called once, after all actions have finished.
+func (h *ActionHistory) Report(tool ToolStatus, reportFile string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	data, err := h.read()
+	if err != nil {
+		h.log.Verbosef("Discarding action history %s: %v", h.filename, err)
+		data = &actionHistoryData{Actions: make(map[string]*actionHistoryEntry)}
+	}
+
+	regressions := h.regressions(data)
+
+	os.Remove(reportFile)
+	if len(regressions) > 0 {
+		if err := writeActionRegressions(regressions, reportFile); err != nil {
+			h.log.Printf("Failed to write file %s: %v\n", reportFile, err)
+		}
+		tool.Print(fmt.Sprintf("warning: %d actions took significantly longer than in previous builds, see %s",
+			len(regressions), reportFile))
+	}
+
+	h.update(data)
+	if err := h.write(data); err != nil {
+		h.log.Printf("Failed to write file %s: %v\n", h.filename, err)
+	}
+}
+
+// regressions returns the actions that ran in this build that regressed
+// compared to their history, sorted by decreasing increase in duration.
+func (h *ActionHistory) regressions(data *actionHistoryData) []*ActionRegression {
+	var ret []*ActionRegression
+	for output, result := range h.finished {
+		entry := data.Actions[output]
+		if entry == nil || len(entry.DurationsMs) < actionRegressionMinSamples {
+			continue
+		}
+
+		baseline := time.Duration(medianInt64(entry.DurationsMs)) * time.Millisecond
+		if float64(result.duration) >= float64(baseline)*actionRegressionFactor &&
+			result.duration-baseline >= actionRegressionMinDelta {
+			ret = append(ret, &ActionRegression{
+				Output:      output,
+				Description: result.description,
+				Duration:    result.duration,
+				Baseline:    baseline,
+				Samples:     len(entry.DurationsMs),
+			})
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		di := ret[i].Duration - ret[i].Baseline
+		dj := ret[j].Duration - ret[j].Baseline
+		if di != dj {
+			return di > dj
+		}
+		return ret[i].Output < ret[j].Output
+	})
+	return ret
+}
+
+// update adds the durations from this build to the history and drops actions
+// that haven't run recently.
+func (h *ActionHistory) update(data *actionHistoryData) {
+	data.Version = actionHistoryVersion
+	data.Builds++
+
+	for output, result := range h.finished {
+		if result.duration < actionHistoryMinDuration {
+			continue
+		}
+		entry := data.Actions[output]
+		if entry == nil {
+			entry = &actionHistoryEntry{}
+			data.Actions[output] = entry
+		}
+		entry.DurationsMs = append(entry.DurationsMs, result.duration.Milliseconds())
+		if len(entry.DurationsMs) > actionHistorySamples {
+			entry.DurationsMs = entry.DurationsMs[len(entry.DurationsMs)-actionHistorySamples:]
+		}
+		entry.LastBuild = data.Builds
+	}
+
+	for output, entry := range data.Actions {
+		if data.Builds-entry.LastBuild >= actionHistoryMaxAge {
+			delete(data.Actions, output)
+		}
+	}
+}
+
+func (h *ActionHistory) read() (*actionHistoryData, error) {
+	ret := &actionHistoryData{Actions: make(map[string]*actionHistoryEntry)}
+
+	buf, err := ioutil.ReadFile(h.filename)
+	if os.IsNotExist(err) {
+		return ret, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(buf, ret); err != nil {
+		return nil, err
+	}
+	if ret.Version != actionHistoryVersion {
+		return nil, fmt.Errorf("unsupported version %d", ret.Version)
+	}
+	if ret.Actions == nil {
+		ret.Actions = make(map[string]*actionHistoryEntry)
+	}
+	return ret, nil
+}
+
+func (h *ActionHistory) write(data *actionHistoryData) error {
+	buf, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tempPath := h.filename + ".tmp"
+	if err := ioutil.WriteFile(tempPath, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tempPath, h.filename)
+}
+
+func writeActionRegressions(regressions []*ActionRegression, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "%d actions took at least %.0fx and %s longer than the median of their previous runs:\n\n",
+		len(regressions), actionRegressionFactor, actionRegressionMinDelta)
+	fmt.Fprintf(f, "%10s %10s %5s  %s\n", "Duration", "Median", "Runs", "Output")
+	for _, r := range regressions {
+		fmt.Fprintf(f, "%10s %10s %5d  %s\n", r.Duration.Round(time.Second/10), r.Baseline.Round(time.Second/10), r.Samples, r.Output)
+		if r.Description != "" {
+			fmt.Fprintf(f, "%10s %10s %5s  (%s)\n", "", "", "", r.Description)
+		}
+	}
+
+	return f.Close()
+}
+
+func medianInt64(list []int64) int64 {
+	sorted := append([]int64(nil), list...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
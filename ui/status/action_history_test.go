@@ -0,0 +1,156 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+type messageCollector struct {
+	messages []string
+}
+
+func (m *messageCollector) StartAction(action *Action, counts Counts)       {}
+func (m *messageCollector) FinishAction(result ActionResult, counts Counts) {}
+func (m *messageCollector) Flush()                                          {}
+func (m *messageCollector) Write(p []byte) (int, error)                     { return len(p), nil }
+func (m *messageCollector) Message(level MsgLevel, message string) {
+	m.messages = append(m.messages, message)
+}
+
+// runActionHistoryBuild simulates a build that runs an action for each entry
+// in durations, and returns the messages printed by the report.
+func runActionHistoryBuild(t *testing.T, historyFile, reportFile string, durations map[string]time.Duration) []string {
+	t.Helper()
+
+	h := NewActionHistory(logger.New(ioutil.Discard), historyFile)
+	collector := &messageCollector{}
+	stat := &Status{}
+	stat.AddOutput(h)
+	stat.AddOutput(collector)
+	tool := stat.StartTool()
+
+	for output, duration := range durations {
+		action := &Action{Description: "build " + output, Outputs: []string{output}}
+		h.clock = testClock(time.Unix(0, 0))
+		tool.StartAction(action)
+		h.clock = testClock(time.Unix(0, 0).Add(duration))
+		tool.FinishAction(ActionResult{Action: action})
+	}
+	tool.Finish()
+
+	h.Report(stat.StartTool(), reportFile)
+	stat.Finish()
+	return collector.messages
+}
+
+func TestActionHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "action_history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	historyFile := filepath.Join(dir, ".action_history.json")
+	reportFile := filepath.Join(dir, "build_time_regressions.txt")
+
+	normal := map[string]time.Duration{
+		"out/fast":   10 * time.Second,
+		"out/steady": 20 * time.Second,
+		"out/tiny":   10 * time.Millisecond,
+	}
+	for i := 0; i < actionRegressionMinSamples; i++ {
+		if messages := runActionHistoryBuild(t, historyFile, reportFile, normal); len(messages) != 0 {
+			t.Fatalf("build %d: unexpected messages %q", i, messages)
+		}
+	}
+
+	messages := runActionHistoryBuild(t, historyFile, reportFile, map[string]time.Duration{
+		// Regressed by more than the factor and the minimum delta.
+		"out/fast": 30 * time.Second,
+		// Slower, but not by the factor.
+		"out/steady": 30 * time.Second,
+		// Much slower, but never recorded since it was too fast.
+		"out/tiny": 20 * time.Second,
+		// No history.
+		"out/new": time.Minute,
+	})
+	if len(messages) != 1 || !strings.Contains(messages[0], "1 actions took significantly longer") {
+		t.Errorf("expected a single warning, got %q", messages)
+	}
+
+	report, err := ioutil.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The median is of the runs recorded so far, not of a full history.
+	if !strings.Contains(string(report), "      10s     3  out/fast\n") {
+		t.Errorf("expected out/fast with a median of 3 runs in report:\n%s", report)
+	}
+	for _, s := range []string{"out/steady", "out/tiny", "out/new"} {
+		if strings.Contains(string(report), s) {
+			t.Errorf("unexpected %s in report:\n%s", s, report)
+		}
+	}
+
+	// A build without regressions removes the stale report.
+	if messages := runActionHistoryBuild(t, historyFile, reportFile, normal); len(messages) != 0 {
+		t.Errorf("unexpected messages %q", messages)
+	}
+	if _, err := os.Stat(reportFile); !os.IsNotExist(err) {
+		t.Errorf("expected report to be removed, got %v", err)
+	}
+}
+
+func TestActionHistoryUpdate(t *testing.T) {
+	h := NewActionHistory(logger.New(ioutil.Discard), "")
+	data := &actionHistoryData{
+		Builds: actionHistoryMaxAge,
+		Actions: map[string]*actionHistoryEntry{
+			"out/old":  {DurationsMs: []int64{1000}, LastBuild: 1},
+			"out/full": {DurationsMs: []int64{1, 2, 3, 4, 5}, LastBuild: actionHistoryMaxAge},
+		},
+	}
+	h.finished["out/full"] = &actionHistoryResult{duration: 6 * time.Second}
+
+	h.update(data)
+
+	if _, ok := data.Actions["out/old"]; ok {
+		t.Errorf("expected out/old to be dropped")
+	}
+	full := data.Actions["out/full"]
+	if full == nil {
+		t.Fatal("expected out/full to be kept")
+	}
+	want := []int64{2, 3, 4, 5, 6000}
+	if len(full.DurationsMs) != len(want) {
+		t.Fatalf("expected durations %v, got %v", want, full.DurationsMs)
+	}
+	for i := range want {
+		if full.DurationsMs[i] != want[i] {
+			t.Fatalf("expected durations %v, got %v", want, full.DurationsMs)
+		}
+	}
+	if full.LastBuild != actionHistoryMaxAge+1 {
+		t.Errorf("expected last build %d, got %d", actionHistoryMaxAge+1, full.LastBuild)
+	}
+}
@@ -25,10 +25,13 @@ type BuildError struct {
 	// are not associated with a build action.
 	ErrorMessages []string `protobuf:"bytes,1,rep,name=error_messages,json=errorMessages" json:"error_messages,omitempty"`
 	// List of build action errors.
-	ActionErrors         []*BuildActionError `protobuf:"bytes,2,rep,name=action_errors,json=actionErrors" json:"action_errors,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+	ActionErrors []*BuildActionError `protobuf:"bytes,2,rep,name=action_errors,json=actionErrors" json:"action_errors,omitempty"`
+	// The build action errors grouped by their normalized error signature, in
+	// the order each signature was first seen.
+	ErrorGroups          []*BuildErrorGroup `protobuf:"bytes,3,rep,name=error_groups,json=errorGroups" json:"error_groups,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *BuildError) Reset()         { *m = BuildError{} }
@@ -70,6 +73,13 @@ func (m *BuildError) GetActionErrors() []*BuildActionError {
 	return nil
 }
 
+func (m *BuildError) GetErrorGroups() []*BuildErrorGroup {
+	if m != nil {
+		return m.ErrorGroups
+	}
+	return nil
+}
+
 // Build is composed of a list of build action. There can be a set of build
 // actions that can failed.
 type BuildActionError struct {
@@ -148,28 +158,95 @@ func (m *BuildActionError) GetError() string {
 	return ""
 }
 
+// A group of build action errors that share the same root cause, as
+// determined by the first compiler diagnostic in their output with paths and
+// line numbers removed.
+type BuildErrorGroup struct {
+	// The normalized error signature shared by the group.
+	Signature *string `protobuf:"bytes,1,opt,name=signature" json:"signature,omitempty"`
+	// The number of build action errors with this signature.
+	Count *uint32 `protobuf:"varint,2,opt,name=count" json:"count,omitempty"`
+	// The index in action_errors of the first build action error with this
+	// signature.
+	ExampleIndex         *uint32  `protobuf:"varint,3,opt,name=example_index,json=exampleIndex" json:"example_index,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BuildErrorGroup) Reset()         { *m = BuildErrorGroup{} }
+func (m *BuildErrorGroup) String() string { return proto.CompactTextString(m) }
+func (*BuildErrorGroup) ProtoMessage()    {}
+func (*BuildErrorGroup) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a2e15b05802a5501, []int{2}
+}
+
+func (m *BuildErrorGroup) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BuildErrorGroup.Unmarshal(m, b)
+}
+func (m *BuildErrorGroup) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BuildErrorGroup.Marshal(b, m, deterministic)
+}
+func (m *BuildErrorGroup) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BuildErrorGroup.Merge(m, src)
+}
+func (m *BuildErrorGroup) XXX_Size() int {
+	return xxx_messageInfo_BuildErrorGroup.Size(m)
+}
+func (m *BuildErrorGroup) XXX_DiscardUnknown() {
+	xxx_messageInfo_BuildErrorGroup.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BuildErrorGroup proto.InternalMessageInfo
+
+func (m *BuildErrorGroup) GetSignature() string {
+	if m != nil && m.Signature != nil {
+		return *m.Signature
+	}
+	return ""
+}
+
+func (m *BuildErrorGroup) GetCount() uint32 {
+	if m != nil && m.Count != nil {
+		return *m.Count
+	}
+	return 0
+}
+
+func (m *BuildErrorGroup) GetExampleIndex() uint32 {
+	if m != nil && m.ExampleIndex != nil {
+		return *m.ExampleIndex
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*BuildError)(nil), "soong_build_error.BuildError")
 	proto.RegisterType((*BuildActionError)(nil), "soong_build_error.BuildActionError")
+	proto.RegisterType((*BuildErrorGroup)(nil), "soong_build_error.BuildErrorGroup")
 }
 
 func init() { proto.RegisterFile("build_error.proto", fileDescriptor_a2e15b05802a5501) }
 
 var fileDescriptor_a2e15b05802a5501 = []byte{
-	// 229 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x64, 0x90, 0xc1, 0x4a, 0xc3, 0x40,
-	0x10, 0x86, 0x49, 0x63, 0x95, 0x4c, 0xad, 0xd8, 0x41, 0x74, 0x04, 0x0f, 0xa1, 0x22, 0xe4, 0x94,
-	0x83, 0x6f, 0x60, 0x41, 0xf0, 0xe2, 0x25, 0x47, 0x2f, 0x61, 0xdd, 0xac, 0x65, 0xc1, 0x64, 0xc2,
-	0xce, 0xe6, 0xe8, 0x8b, 0xf8, 0xb4, 0x92, 0x69, 0xa5, 0xa5, 0x39, 0x7e, 0xdf, 0x3f, 0xfb, 0xef,
-	0xce, 0xc2, 0xea, 0x73, 0xf0, 0xdf, 0x4d, 0xed, 0x42, 0xe0, 0x50, 0xf6, 0x81, 0x23, 0xe3, 0x4a,
-	0x98, 0xbb, 0x6d, 0x7d, 0x14, 0xac, 0x7f, 0x00, 0x36, 0x23, 0xbe, 0x8e, 0x84, 0x4f, 0x70, 0xa5,
-	0xba, 0x6e, 0x9d, 0x88, 0xd9, 0x3a, 0xa1, 0x24, 0x4f, 0x8b, 0xac, 0x5a, 0xaa, 0x7d, 0xdf, 0x4b,
-	0x7c, 0x83, 0xa5, 0xb1, 0xd1, 0x73, 0xb7, 0x2b, 0x11, 0x9a, 0xe5, 0x69, 0xb1, 0x78, 0x7e, 0x2c,
-	0x27, 0xfd, 0xa5, 0x96, 0xbf, 0xe8, 0xb0, 0x5e, 0x51, 0x5d, 0x9a, 0x03, 0xc8, 0xfa, 0x37, 0x81,
-	0xeb, 0xd3, 0x11, 0xcc, 0x61, 0xd1, 0x38, 0xb1, 0xc1, 0xf7, 0xa3, 0xa3, 0x24, 0x4f, 0x8a, 0xac,
-	0x3a, 0x56, 0x48, 0x70, 0x61, 0xb9, 0x6d, 0x4d, 0xd7, 0xd0, 0x4c, 0xd3, 0x7f, 0xc4, 0x5b, 0x38,
-	0xe7, 0x21, 0xf6, 0x43, 0xa4, 0x54, 0x83, 0x3d, 0xe1, 0x03, 0x64, 0x26, 0x44, 0xff, 0x65, 0x6c,
-	0x14, 0x3a, 0xd3, 0xa5, 0x0e, 0x02, 0x6f, 0x60, 0xae, 0xcf, 0xa5, 0xb9, 0x1e, 0xda, 0xc1, 0xe6,
-	0xfe, 0xe3, 0x6e, 0xb2, 0x50, 0xad, 0x3f, 0xf9, 0x17, 0x00, 0x00, 0xff, 0xff, 0xb6, 0x18, 0x9e,
-	0x17, 0x5d, 0x01, 0x00, 0x00,
+	// 301 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x91, 0xcd, 0x4a, 0xc3, 0x40,
+	0x14, 0x85, 0x49, 0x63, 0x95, 0xde, 0x36, 0x6a, 0x07, 0xd1, 0x11, 0x5c, 0x84, 0x14, 0x21, 0xab,
+	0x2c, 0x7c, 0x03, 0x0b, 0x45, 0x5d, 0xb8, 0x99, 0xa5, 0x9b, 0x30, 0x26, 0x63, 0x18, 0x69, 0x66,
+	0xc2, 0xfc, 0x40, 0x9f, 0xc5, 0xe7, 0xf1, 0xc1, 0x64, 0x6e, 0xa2, 0x29, 0x2d, 0xee, 0x72, 0xbe,
+	0x3b, 0xf7, 0x9c, 0xc3, 0x0d, 0x2c, 0xdf, 0xbd, 0xdc, 0xd6, 0xa5, 0x30, 0x46, 0x9b, 0xa2, 0x33,
+	0xda, 0x69, 0xb2, 0xb4, 0x5a, 0xab, 0xa6, 0xdc, 0x1b, 0x64, 0xdf, 0x11, 0xc0, 0x3a, 0xe8, 0x4d,
+	0x90, 0xe4, 0x1e, 0xce, 0x91, 0x97, 0xad, 0xb0, 0x96, 0x37, 0xc2, 0xd2, 0x28, 0x8d, 0xf3, 0x19,
+	0x4b, 0x90, 0xbe, 0x0e, 0x90, 0x3c, 0x43, 0xc2, 0x2b, 0x27, 0xb5, 0xea, 0x5d, 0x2c, 0x9d, 0xa4,
+	0x71, 0x3e, 0x7f, 0x58, 0x15, 0x47, 0x01, 0x05, 0x9a, 0x3f, 0xe2, 0x63, 0x8c, 0x60, 0x0b, 0x3e,
+	0x0a, 0x4b, 0x36, 0xb0, 0xe8, 0x03, 0x1b, 0xa3, 0x7d, 0x67, 0x69, 0x8c, 0x46, 0xd9, 0x7f, 0x46,
+	0xb8, 0xf5, 0x14, 0x9e, 0xb2, 0xb9, 0xf8, 0xfb, 0xb6, 0xd9, 0x57, 0x04, 0x97, 0x87, 0x49, 0x24,
+	0x85, 0x79, 0x2d, 0x6c, 0x65, 0x64, 0x17, 0x18, 0x8d, 0xd2, 0x28, 0x9f, 0xb1, 0x7d, 0x44, 0x28,
+	0x9c, 0x55, 0xba, 0x6d, 0xb9, 0xaa, 0xe9, 0x04, 0xa7, 0xbf, 0x92, 0x5c, 0xc3, 0xa9, 0xf6, 0xae,
+	0xf3, 0x8e, 0xc6, 0x38, 0x18, 0x14, 0xb9, 0x83, 0x19, 0x37, 0x4e, 0x7e, 0xf0, 0xca, 0x59, 0x7a,
+	0x82, 0xb7, 0x19, 0x01, 0xb9, 0x82, 0x29, 0xb6, 0xa2, 0x53, 0x5c, 0xea, 0x45, 0xf6, 0x09, 0x17,
+	0x07, 0xe5, 0x83, 0x8d, 0x95, 0x8d, 0xe2, 0xce, 0x1b, 0x31, 0x14, 0x1b, 0x41, 0xb0, 0xa9, 0xb4,
+	0x57, 0x0e, 0x4b, 0x25, 0xac, 0x17, 0x64, 0x05, 0x89, 0xd8, 0xf1, 0xb6, 0xdb, 0x8a, 0x52, 0xaa,
+	0x5a, 0xec, 0xb0, 0x59, 0xc2, 0x16, 0x03, 0x7c, 0x09, 0x6c, 0x7d, 0xfb, 0x76, 0x73, 0x74, 0xba,
+	0x12, 0xff, 0xfe, 0xcf, 0x00, 0xb5, 0xdc, 0xc7, 0x86, 0x11, 0x02, 0x00, 0x00,
 }
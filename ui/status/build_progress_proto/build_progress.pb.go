@@ -34,10 +34,16 @@ type BuildProgress struct {
 	// build and current_actions + finished_actions <= total_actions.
 	CurrentActions *uint64 `protobuf:"varint,3,opt,name=current_actions,json=currentActions" json:"current_actions,omitempty"`
 	// Total number of actions that reported as a failure.
-	FailedActions        *uint64  `protobuf:"varint,4,opt,name=failed_actions,json=failedActions" json:"failed_actions,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	FailedActions *uint64 `protobuf:"varint,4,opt,name=failed_actions,json=failedActions" json:"failed_actions,omitempty"`
+	// The longest chain of dependent actions in the build, in the order they
+	// were run. Only set once the build has finished.
+	CriticalPath []*CriticalPathAction `protobuf:"bytes,5,rep,name=critical_path,json=criticalPath" json:"critical_path,omitempty"`
+	// The wall time of the critical path attributed to the modules that own
+	// the actions on it, sorted by decreasing time.
+	CriticalPathModules  []*CriticalPathModule `protobuf:"bytes,6,rep,name=critical_path_modules,json=criticalPathModules" json:"critical_path_modules,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
 func (m *BuildProgress) Reset()         { *m = BuildProgress{} }
@@ -93,23 +99,175 @@ func (m *BuildProgress) GetFailedActions() uint64 {
 	return 0
 }
 
+func (m *BuildProgress) GetCriticalPath() []*CriticalPathAction {
+	if m != nil {
+		return m.CriticalPath
+	}
+	return nil
+}
+
+func (m *BuildProgress) GetCriticalPathModules() []*CriticalPathModule {
+	if m != nil {
+		return m.CriticalPathModules
+	}
+	return nil
+}
+
+type CriticalPathAction struct {
+	// Description of the action.
+	Description *string `protobuf:"bytes,1,opt,name=description" json:"description,omitempty"`
+	// List of outputs of the action.
+	Outputs []string `protobuf:"bytes,2,rep,name=outputs" json:"outputs,omitempty"`
+	// The Soong or Make module that owns the action, if it could be determined.
+	Module *string `protobuf:"bytes,3,opt,name=module" json:"module,omitempty"`
+	// Wall time of the action in milliseconds.
+	DurationMs           *uint64  `protobuf:"varint,4,opt,name=duration_ms,json=durationMs" json:"duration_ms,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CriticalPathAction) Reset()         { *m = CriticalPathAction{} }
+func (m *CriticalPathAction) String() string { return proto.CompactTextString(m) }
+func (*CriticalPathAction) ProtoMessage()    {}
+func (*CriticalPathAction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a8a463f8e30dab2e, []int{1}
+}
+
+func (m *CriticalPathAction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CriticalPathAction.Unmarshal(m, b)
+}
+func (m *CriticalPathAction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CriticalPathAction.Marshal(b, m, deterministic)
+}
+func (m *CriticalPathAction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CriticalPathAction.Merge(m, src)
+}
+func (m *CriticalPathAction) XXX_Size() int {
+	return xxx_messageInfo_CriticalPathAction.Size(m)
+}
+func (m *CriticalPathAction) XXX_DiscardUnknown() {
+	xxx_messageInfo_CriticalPathAction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CriticalPathAction proto.InternalMessageInfo
+
+func (m *CriticalPathAction) GetDescription() string {
+	if m != nil && m.Description != nil {
+		return *m.Description
+	}
+	return ""
+}
+
+func (m *CriticalPathAction) GetOutputs() []string {
+	if m != nil {
+		return m.Outputs
+	}
+	return nil
+}
+
+func (m *CriticalPathAction) GetModule() string {
+	if m != nil && m.Module != nil {
+		return *m.Module
+	}
+	return ""
+}
+
+func (m *CriticalPathAction) GetDurationMs() uint64 {
+	if m != nil && m.DurationMs != nil {
+		return *m.DurationMs
+	}
+	return 0
+}
+
+type CriticalPathModule struct {
+	// The Soong or Make module, or "<unknown>" for actions that couldn't be
+	// attributed to a module.
+	Module *string `protobuf:"bytes,1,opt,name=module" json:"module,omitempty"`
+	// Total wall time of the module's actions on the critical path in
+	// milliseconds.
+	DurationMs *uint64 `protobuf:"varint,2,opt,name=duration_ms,json=durationMs" json:"duration_ms,omitempty"`
+	// Number of the module's actions on the critical path.
+	Actions              *uint32  `protobuf:"varint,3,opt,name=actions" json:"actions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CriticalPathModule) Reset()         { *m = CriticalPathModule{} }
+func (m *CriticalPathModule) String() string { return proto.CompactTextString(m) }
+func (*CriticalPathModule) ProtoMessage()    {}
+func (*CriticalPathModule) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a8a463f8e30dab2e, []int{2}
+}
+
+func (m *CriticalPathModule) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CriticalPathModule.Unmarshal(m, b)
+}
+func (m *CriticalPathModule) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CriticalPathModule.Marshal(b, m, deterministic)
+}
+func (m *CriticalPathModule) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CriticalPathModule.Merge(m, src)
+}
+func (m *CriticalPathModule) XXX_Size() int {
+	return xxx_messageInfo_CriticalPathModule.Size(m)
+}
+func (m *CriticalPathModule) XXX_DiscardUnknown() {
+	xxx_messageInfo_CriticalPathModule.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CriticalPathModule proto.InternalMessageInfo
+
+func (m *CriticalPathModule) GetModule() string {
+	if m != nil && m.Module != nil {
+		return *m.Module
+	}
+	return ""
+}
+
+func (m *CriticalPathModule) GetDurationMs() uint64 {
+	if m != nil && m.DurationMs != nil {
+		return *m.DurationMs
+	}
+	return 0
+}
+
+func (m *CriticalPathModule) GetActions() uint32 {
+	if m != nil && m.Actions != nil {
+		return *m.Actions
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*BuildProgress)(nil), "soong_build_progress.BuildProgress")
+	proto.RegisterType((*CriticalPathAction)(nil), "soong_build_progress.CriticalPathAction")
+	proto.RegisterType((*CriticalPathModule)(nil), "soong_build_progress.CriticalPathModule")
 }
 
 func init() { proto.RegisterFile("build_progress.proto", fileDescriptor_a8a463f8e30dab2e) }
 
 var fileDescriptor_a8a463f8e30dab2e = []byte{
-	// 165 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x12, 0x49, 0x2a, 0xcd, 0xcc,
-	0x49, 0x89, 0x2f, 0x28, 0xca, 0x4f, 0x2f, 0x4a, 0x2d, 0x2e, 0xd6, 0x2b, 0x28, 0xca, 0x2f, 0xc9,
-	0x17, 0x12, 0x29, 0xce, 0xcf, 0xcf, 0x4b, 0x8f, 0x47, 0x95, 0x53, 0x5a, 0xcf, 0xc8, 0xc5, 0xeb,
-	0x04, 0x12, 0x0a, 0x80, 0x8a, 0x08, 0x29, 0x73, 0xf1, 0x96, 0xe4, 0x97, 0x24, 0xe6, 0xc4, 0x27,
-	0x26, 0x97, 0x64, 0xe6, 0xe7, 0x15, 0x4b, 0x30, 0x2a, 0x30, 0x6a, 0xb0, 0x04, 0xf1, 0x80, 0x05,
-	0x1d, 0x21, 0x62, 0x42, 0x9a, 0x5c, 0x02, 0x69, 0x99, 0x79, 0x99, 0xc5, 0x19, 0xa9, 0x29, 0x70,
-	0x75, 0x4c, 0x60, 0x75, 0xfc, 0x30, 0x71, 0x98, 0x52, 0x75, 0x2e, 0xfe, 0xe4, 0xd2, 0xa2, 0xa2,
-	0xd4, 0xbc, 0x12, 0xb8, 0x4a, 0x66, 0xb0, 0x4a, 0x3e, 0xa8, 0x30, 0x4c, 0xa1, 0x2a, 0x17, 0x5f,
-	0x5a, 0x62, 0x66, 0x0e, 0x92, 0x89, 0x2c, 0x60, 0x75, 0xbc, 0x10, 0x51, 0xa8, 0x32, 0x27, 0x99,
-	0x28, 0x29, 0x6c, 0x3e, 0x89, 0x07, 0xfb, 0x12, 0x10, 0x00, 0x00, 0xff, 0xff, 0x3f, 0x6e, 0xc1,
-	0xef, 0xfc, 0x00, 0x00, 0x00,
+	// 316 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x8c, 0x92, 0xcd, 0x4a, 0xf3, 0x40,
+	0x14, 0x86, 0x49, 0xdb, 0xaf, 0x1f, 0x3d, 0x6d, 0x5a, 0x19, 0xab, 0x04, 0x11, 0x2c, 0x11, 0x31,
+	0x6e, 0xb2, 0xf0, 0x0e, 0xac, 0xeb, 0x40, 0x99, 0xa5, 0x08, 0xc3, 0x38, 0x49, 0x93, 0x81, 0x34,
+	0x13, 0xe6, 0xe7, 0x22, 0xbc, 0x47, 0x2f, 0x46, 0x32, 0xc9, 0x94, 0xc4, 0x16, 0x71, 0x79, 0x9e,
+	0x3c, 0xe7, 0xe5, 0xe4, 0x4d, 0x60, 0xfd, 0x61, 0x78, 0x99, 0x92, 0x5a, 0x8a, 0x5c, 0x66, 0x4a,
+	0xc5, 0xb5, 0x14, 0x5a, 0xa0, 0xb5, 0x12, 0xa2, 0xca, 0xc9, 0xf0, 0x59, 0xf8, 0x35, 0x02, 0x7f,
+	0xdb, 0xa0, 0x5d, 0x47, 0xd0, 0x3d, 0xf8, 0x5a, 0x68, 0x5a, 0x12, 0xca, 0x34, 0x17, 0x95, 0x0a,
+	0xbc, 0x8d, 0x17, 0x4d, 0xf0, 0xc2, 0xc2, 0x97, 0x96, 0xa1, 0x27, 0xb8, 0xd8, 0xf3, 0x8a, 0xab,
+	0x22, 0x4b, 0x8f, 0xde, 0xc8, 0x7a, 0x2b, 0xc7, 0x9d, 0xfa, 0x08, 0x2b, 0x66, 0xa4, 0xcc, 0x2a,
+	0x7d, 0x34, 0xc7, 0xd6, 0x5c, 0x76, 0xd8, 0x89, 0x0f, 0xb0, 0xdc, 0x53, 0x5e, 0xf6, 0x12, 0x27,
+	0xd6, 0xf3, 0x5b, 0xea, 0xb4, 0x04, 0x7c, 0x26, 0xb9, 0xe6, 0x8c, 0x96, 0xa4, 0xa6, 0xba, 0x08,
+	0xfe, 0x6d, 0xc6, 0xd1, 0xfc, 0x39, 0x8a, 0xcf, 0xbd, 0x5f, 0xfc, 0xda, 0xa9, 0x3b, 0xaa, 0x8b,
+	0x36, 0x01, 0x2f, 0x58, 0x8f, 0xa1, 0x77, 0xb8, 0x1a, 0xc4, 0x91, 0x83, 0x48, 0x4d, 0x99, 0xa9,
+	0x60, 0xfa, 0xd7, 0xd8, 0xc4, 0x2e, 0xe0, 0x4b, 0x76, 0xc2, 0x54, 0xf8, 0xe9, 0x01, 0x3a, 0x3d,
+	0x01, 0x6d, 0x60, 0x9e, 0x66, 0x8a, 0x49, 0x5e, 0x37, 0xa3, 0x6d, 0x78, 0x86, 0xfb, 0x08, 0x05,
+	0xf0, 0x5f, 0x18, 0x5d, 0x1b, 0xdd, 0xf4, 0x3a, 0x8e, 0x66, 0xd8, 0x8d, 0xe8, 0x1a, 0xa6, 0xed,
+	0x89, 0xb6, 0xc6, 0x19, 0xee, 0x26, 0x74, 0x07, 0xf3, 0xd4, 0x48, 0xda, 0x6c, 0x93, 0x83, 0xeb,
+	0x0e, 0x1c, 0x4a, 0x54, 0x98, 0x0f, 0x4f, 0x69, 0x4f, 0xec, 0xc5, 0x79, 0xbf, 0xc5, 0x8d, 0x7e,
+	0xc6, 0x35, 0x17, 0xf6, 0xbf, 0xa7, 0x8f, 0xdd, 0xb8, 0xbd, 0x7d, 0xbb, 0x39, 0x57, 0x1a, 0xb1,
+	0xff, 0xe1, 0xf7, 0x00, 0x57, 0xed, 0x5c, 0x34, 0x9e, 0x02, 0x00, 0x00,
 }
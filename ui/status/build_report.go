@@ -0,0 +1,468 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"html/template"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/metrics/metrics_proto"
+	"android/soong/ui/status/build_error_proto"
+	"android/soong/ui/status/build_progress_proto"
+)
+
+const (
+	// The number of slowest actions listed in the build report.
+	buildReportSlowestActions = 20
+
+	// The maximum number of failed actions whose output is included in the
+	// build report, the error groups cover the rest.
+	buildReportMaxErrors = 50
+)
+
+type buildReportAction struct {
+	Description string
+	Output      string
+	Module      string
+	Duration    time.Duration
+	Actions     uint32
+}
+
+type buildReportPhase struct {
+	Name     string
+	Desc     string
+	Duration time.Duration
+	Percent  float64
+}
+
+type buildReportError struct {
+	Description string
+	Error       string
+	Output      string
+}
+
+type buildReportErrorGroup struct {
+	Signature string
+	Count     uint32
+	Example   string
+}
+
+// buildReportData is the data rendered by buildReportTemplate.
+type buildReportData struct {
+	Generated time.Time
+	Success   bool
+
+	Product   string
+	Variant   string
+	Command   string
+	Hostname  string
+	BuildDate time.Time
+	Duration  time.Duration
+
+	TotalActions    uint64
+	FinishedActions uint64
+	FailedActions   uint64
+
+	Phases []buildReportPhase
+
+	CriticalPath        []buildReportAction
+	CriticalPathTime    time.Duration
+	CriticalPathModules []buildReportAction
+
+	SlowestActions []buildReportAction
+
+	ErrorGroups   []buildReportErrorGroup
+	Errors        []buildReportError
+	MoreErrors    int
+	ErrorMessages []string
+
+	SoongModules  uint32
+	SoongVariants uint32
+	SoongMaxHeap  uint64
+}
+
+type buildReport struct {
+	log   logger.Logger
+	clock clock
+
+	filename          string
+	soongMetricsFile  string
+	buildProgressFile string
+	buildErrorFile    string
+
+	start   time.Time
+	running map[*Action]time.Time
+	slowest []buildReportAction
+}
+
+// NewBuildReport returns a StatusOutput that writes a self-contained HTML
+// summary of the build to filename when flushed. It combines the slowest
+// actions it observed with the contents of the soong metrics, build progress
+// (including the critical path) and build error files, so it must be added
+// after the outputs that write those files. Missing files are skipped.
+func NewBuildReport(log logger.Logger, filename, soongMetricsFile, buildProgressFile, buildErrorFile string) StatusOutput {
+	return &buildReport{
+		log:               log,
+		clock:             osClock{},
+		filename:          filename,
+		soongMetricsFile:  soongMetricsFile,
+		buildProgressFile: buildProgressFile,
+		buildErrorFile:    buildErrorFile,
+		running:           make(map[*Action]time.Time),
+	}
+}
+
+func (r *buildReport) StartAction(action *Action, counts Counts) {
+	now := r.clock.Now()
+	if r.start.IsZero() {
+		r.start = now
+	}
+	r.running[action] = now
+}
+
+func (r *buildReport) FinishAction(result ActionResult, counts Counts) {
+	start, ok := r.running[result.Action]
+	if !ok {
+		return
+	}
+	delete(r.running, result.Action)
+
+	action := buildReportAction{
+		Description: result.Description,
+		Duration:    r.clock.Now().Sub(start),
+	}
+	if action.Description == "" {
+		action.Description = result.Command
+	}
+	if len(result.Outputs) > 0 {
+		action.Output = result.Outputs[0]
+	}
+
+	r.slowest = append(r.slowest, action)
+	sort.SliceStable(r.slowest, func(i, j int) bool {
+		return r.slowest[i].Duration > r.slowest[j].Duration
+	})
+	if len(r.slowest) > buildReportSlowestActions {
+		r.slowest = r.slowest[:buildReportSlowestActions]
+	}
+}
+
+func (r *buildReport) Message(level MsgLevel, message string) {}
+
+func (r *buildReport) Flush() {
+	data := r.data()
+
+	f, err := os.Create(r.filename)
+	if err != nil {
+		r.log.Printf("Failed to write file %s: %v\n", r.filename, err)
+		return
+	}
+	defer f.Close()
+
+	if err := buildReportTemplate.Execute(f, data); err != nil {
+		r.log.Printf("Failed to write file %s: %v\n", r.filename, err)
+	}
+}
+
+func (r *buildReport) Write(p []byte) (int, error) {
+	// Discard writes
+	return len(p), nil
+}
+
+// data collects everything shown in the report.
+func (r *buildReport) data() *buildReportData {
+	data := &buildReportData{
+		Generated:      r.clock.Now(),
+		SlowestActions: r.slowest,
+	}
+	if !r.start.IsZero() {
+		data.Duration = data.Generated.Sub(r.start)
+	}
+
+	soongMetrics := &soong_metrics_proto.MetricsBase{}
+	if r.readProto(r.soongMetricsFile, soongMetrics) {
+		data.Product = soongMetrics.GetTargetProduct()
+		data.Variant = strings.ToLower(soongMetrics.GetTargetBuildVariant().String())
+		data.Command = soongMetrics.GetBuildCommand()
+		data.Hostname = soongMetrics.GetHostname()
+		if soongMetrics.BuildDateTimestamp != nil {
+			data.BuildDate = time.Unix(soongMetrics.GetBuildDateTimestamp(), 0)
+		}
+		if total := soongMetrics.GetTotal(); total != nil {
+			data.Duration = time.Duration(total.GetRealTime())
+		}
+		data.Phases = buildReportPhases(soongMetrics)
+		if soong := soongMetrics.GetSoongBuildMetrics(); soong != nil {
+			data.SoongModules = soong.GetModules()
+			data.SoongVariants = soong.GetVariants()
+			data.SoongMaxHeap = soong.GetMaxHeapSize()
+		}
+	}
+
+	buildProgress := &soong_build_progress_proto.BuildProgress{}
+	if r.readProto(r.buildProgressFile, buildProgress) {
+		data.TotalActions = buildProgress.GetTotalActions()
+		data.FinishedActions = buildProgress.GetFinishedActions()
+		data.FailedActions = buildProgress.GetFailedActions()
+		for _, action := range buildProgress.GetCriticalPath() {
+			duration := time.Duration(action.GetDurationMs()) * time.Millisecond
+			data.CriticalPathTime += duration
+			output := ""
+			if len(action.GetOutputs()) > 0 {
+				output = action.GetOutputs()[0]
+			}
+			data.CriticalPath = append(data.CriticalPath, buildReportAction{
+				Description: action.GetDescription(),
+				Output:      output,
+				Module:      action.GetModule(),
+				Duration:    duration,
+			})
+		}
+		for _, module := range buildProgress.GetCriticalPathModules() {
+			data.CriticalPathModules = append(data.CriticalPathModules, buildReportAction{
+				Module:   module.GetModule(),
+				Duration: time.Duration(module.GetDurationMs()) * time.Millisecond,
+				Actions:  module.GetActions(),
+			})
+		}
+	}
+
+	buildError := &soong_build_error_proto.BuildError{}
+	if r.readProto(r.buildErrorFile, buildError) {
+		actionErrors := buildError.GetActionErrors()
+		for _, group := range buildError.GetErrorGroups() {
+			example := ""
+			if i := int(group.GetExampleIndex()); i < len(actionErrors) {
+				example = actionErrors[i].GetDescription()
+			}
+			data.ErrorGroups = append(data.ErrorGroups, buildReportErrorGroup{
+				Signature: group.GetSignature(),
+				Count:     group.GetCount(),
+				Example:   example,
+			})
+		}
+		for i, actionError := range actionErrors {
+			if i >= buildReportMaxErrors {
+				data.MoreErrors = len(actionErrors) - i
+				break
+			}
+			data.Errors = append(data.Errors, buildReportError{
+				Description: actionError.GetDescription(),
+				Error:       actionError.GetError(),
+				Output:      ansiEscapeRe.ReplaceAllString(actionError.GetOutput(), ""),
+			})
+		}
+		data.ErrorMessages = buildError.GetErrorMessages()
+	}
+
+	data.Success = data.FailedActions == 0 && len(data.Errors) == 0 && len(data.ErrorMessages) == 0
+
+	return data
+}
+
+// readProto reads a proto file written earlier in the build, returning false if
+// it doesn't exist or can't be parsed.
+func (r *buildReport) readProto(filename string, pb proto.Message) bool {
+	if filename == "" {
+		return false
+	}
+	buf, err := ioutil.ReadFile(filename)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			r.log.Verbosef("Failed to read %s for the build report: %v", filename, err)
+		}
+		return false
+	}
+	if err := proto.Unmarshal(buf, pb); err != nil {
+		r.log.Verbosef("Failed to parse %s for the build report: %v", filename, err)
+		return false
+	}
+	return true
+}
+
+// buildReportPhases returns the tools run during the build in the order they
+// ran, with the fraction of the longest one used to draw bars.
+func buildReportPhases(m *soong_metrics_proto.MetricsBase) []buildReportPhase {
+	var perfs []*soong_metrics_proto.PerfInfo
+	for _, list := range [][]*soong_metrics_proto.PerfInfo{
+		m.GetSetupTools(), m.GetSoongRuns(), m.GetBazelRuns(), m.GetKatiRuns(), m.GetNinjaRuns(),
+	} {
+		perfs = append(perfs, list...)
+	}
+	sort.SliceStable(perfs, func(i, j int) bool {
+		return perfs[i].GetStartTime() < perfs[j].GetStartTime()
+	})
+
+	var longest time.Duration
+	var ret []buildReportPhase
+	for _, perf := range perfs {
+		duration := time.Duration(perf.GetRealTime())
+		if duration > longest {
+			longest = duration
+		}
+		ret = append(ret, buildReportPhase{
+			Name:     perf.GetName(),
+			Desc:     perf.GetDesc(),
+			Duration: duration,
+		})
+	}
+	for i := range ret {
+		if longest > 0 {
+			ret[i].Percent = 100 * float64(ret[i].Duration) / float64(longest)
+		}
+	}
+	return ret
+}
+
+func formatReportDuration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
+
+var buildReportTemplate = template.Must(template.New("build_report").Funcs(template.FuncMap{
+	"duration": formatReportDuration,
+	"mb": func(bytes uint64) string {
+		return formatKB(bytes / 1024)
+	},
+	"time": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02 15:04:05 MST")
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Build report{{if .Product}}: {{.Product}}-{{.Variant}}{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #202124; }
+h1 .result { padding: 0.1em 0.4em; border-radius: 0.2em; color: white; }
+.success { background: #188038; }
+.failure { background: #d93025; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { text-align: left; padding: 0.2em 0.8em; border-bottom: 1px solid #dadce0; vertical-align: top; }
+th { cursor: pointer; user-select: none; background: #f1f3f4; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.bar { background: #1a73e8; height: 0.8em; min-width: 1px; }
+pre { background: #f8f9fa; padding: 0.5em; overflow-x: auto; max-height: 30em; }
+summary { cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Build report <span class="result {{if .Success}}success">SUCCESS{{else}}failure">FAILED{{end}}</span></h1>
+<table>
+{{if .Product}}<tr><th>Product</th><td>{{.Product}}-{{.Variant}}</td></tr>{{end}}
+{{if .Command}}<tr><th>Command</th><td><code>{{.Command}}</code></td></tr>{{end}}
+{{if .Hostname}}<tr><th>Host</th><td>{{.Hostname}}</td></tr>{{end}}
+{{if not .BuildDate.IsZero}}<tr><th>Started</th><td>{{time .BuildDate}}</td></tr>{{end}}
+<tr><th>Duration</th><td>{{duration .Duration}}</td></tr>
+<tr><th>Actions</th><td>{{.FinishedActions}} of {{.TotalActions}} finished, {{.FailedActions}} failed</td></tr>
+{{if .SoongModules}}<tr><th>Soong</th><td>{{.SoongModules}} modules, {{.SoongVariants}} variants, {{mb .SoongMaxHeap}} max heap</td></tr>{{end}}
+<tr><th>Generated</th><td>{{time .Generated}}</td></tr>
+</table>
+
+{{if .ErrorGroups}}
+<h2>Failures by error signature</h2>
+<table class="sortable">
+<tr><th>Count</th><th>Signature</th><th>Example</th></tr>
+{{range .ErrorGroups}}<tr><td class="num">{{.Count}}</td><td><code>{{.Signature}}</code></td><td>{{.Example}}</td></tr>
+{{end}}</table>
+{{end}}
+
+{{if or .Errors .ErrorMessages}}
+<h2>Errors</h2>
+{{range .ErrorMessages}}<pre>{{.}}</pre>
+{{end}}
+{{range .Errors}}<details><summary>{{.Description}} ({{.Error}})</summary><pre>{{.Output}}</pre></details>
+{{end}}
+{{if .MoreErrors}}<p>{{.MoreErrors}} more failed actions, see error.log.</p>{{end}}
+{{end}}
+
+{{if .Phases}}
+<h2>Phases</h2>
+<table class="sortable">
+<tr><th>Phase</th><th>Description</th><th>Duration</th><th></th></tr>
+{{range .Phases}}<tr><td>{{.Name}}</td><td>{{.Desc}}</td><td class="num" data-value="{{.Duration.Nanoseconds}}">{{duration .Duration}}</td><td style="width: 20em"><div class="bar" style="width: {{printf "%.1f" .Percent}}%"></div></td></tr>
+{{end}}</table>
+{{end}}
+
+{{if .CriticalPath}}
+<h2>Critical path ({{duration .CriticalPathTime}})</h2>
+{{if .CriticalPathModules}}
+<table class="sortable">
+<tr><th>Module</th><th>Actions</th><th>Duration</th></tr>
+{{range .CriticalPathModules}}<tr><td>{{.Module}}</td><td class="num">{{.Actions}}</td><td class="num" data-value="{{.Duration.Nanoseconds}}">{{duration .Duration}}</td></tr>
+{{end}}</table>
+{{end}}
+<details><summary>{{len .CriticalPath}} actions</summary>
+<table class="sortable">
+<tr><th>Action</th><th>Module</th><th>Duration</th></tr>
+{{range .CriticalPath}}<tr><td>{{.Description}}</td><td>{{.Module}}</td><td class="num" data-value="{{.Duration.Nanoseconds}}">{{duration .Duration}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+
+{{if .SlowestActions}}
+<h2>Slowest actions</h2>
+<table class="sortable">
+<tr><th>Action</th><th>Output</th><th>Duration</th></tr>
+{{range .SlowestActions}}<tr><td>{{.Description}}</td><td><code>{{.Output}}</code></td><td class="num" data-value="{{.Duration.Nanoseconds}}">{{duration .Duration}}</td></tr>
+{{end}}</table>
+{{end}}
+
+<script>
+// Sort tables by clicking on their headers.
+document.querySelectorAll("table.sortable").forEach(function(table) {
+  var headers = table.rows[0].cells;
+  for (var i = 0; i < headers.length; i++) {
+    headers[i].addEventListener("click", function(col) {
+      return function() {
+        var rows = Array.prototype.slice.call(table.rows, 1);
+        var desc = table.dataset.sortCol == col && table.dataset.sortDir != "desc";
+        var key = function(row) {
+          var cell = row.cells[col];
+          var v = cell.dataset.value || cell.textContent;
+          return isNaN(v) ? v : Number(v);
+        };
+        rows.sort(function(a, b) {
+          var ka = key(a), kb = key(b);
+          var c = ka < kb ? -1 : ka > kb ? 1 : 0;
+          return desc ? -c : c;
+        });
+        rows.forEach(function(row) { row.parentNode.appendChild(row); });
+        table.dataset.sortCol = col;
+        table.dataset.sortDir = desc ? "desc" : "asc";
+      };
+    }(i));
+  }
+});
+</script>
+</body>
+</html>
+`))
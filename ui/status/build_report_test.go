@@ -0,0 +1,167 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/metrics/metrics_proto"
+	"android/soong/ui/status/build_error_proto"
+	"android/soong/ui/status/build_progress_proto"
+)
+
+func writeTestProto(t *testing.T, filename string, pb proto.Message) {
+	t.Helper()
+	buf, err := proto.Marshal(pb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filename, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildReport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build_report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metricsFile := filepath.Join(dir, "soong_metrics")
+	progressFile := filepath.Join(dir, "build_progress.pb")
+	errorFile := filepath.Join(dir, "build_error")
+	reportFile := filepath.Join(dir, "build_report.html")
+
+	writeTestProto(t, metricsFile, &soong_metrics_proto.MetricsBase{
+		TargetProduct:      proto.String("aosp_arm64"),
+		TargetBuildVariant: soong_metrics_proto.MetricsBase_USERDEBUG.Enum(),
+		SoongRuns: []*soong_metrics_proto.PerfInfo{{
+			Name:      proto.String("soong"),
+			Desc:      proto.String("bootstrap"),
+			StartTime: proto.Uint64(0),
+			RealTime:  proto.Uint64(uint64(30 * time.Second)),
+		}},
+		NinjaRuns: []*soong_metrics_proto.PerfInfo{{
+			Name:      proto.String("ninja"),
+			Desc:      proto.String("ninja"),
+			StartTime: proto.Uint64(uint64(30 * time.Second)),
+			RealTime:  proto.Uint64(uint64(60 * time.Second)),
+		}},
+	})
+	writeTestProto(t, progressFile, &soong_build_progress_proto.BuildProgress{
+		TotalActions:    proto.Uint64(3),
+		FinishedActions: proto.Uint64(3),
+		FailedActions:   proto.Uint64(1),
+		CriticalPath: []*soong_build_progress_proto.CriticalPathAction{{
+			Description: proto.String("link libfoo"),
+			Module:      proto.String("libfoo"),
+			DurationMs:  proto.Uint64(4000),
+		}},
+		CriticalPathModules: []*soong_build_progress_proto.CriticalPathModule{{
+			Module:     proto.String("libfoo"),
+			DurationMs: proto.Uint64(4000),
+			Actions:    proto.Uint32(1),
+		}},
+	})
+	writeTestProto(t, errorFile, &soong_build_error_proto.BuildError{
+		ActionErrors: []*soong_build_error_proto.BuildActionError{{
+			Description: proto.String("compile bar.cpp"),
+			Error:       proto.String("exited with code: 1"),
+			Output:      proto.String("\x1b[1mbar.cpp:1:1: error: <unknown> type\x1b[0m"),
+		}},
+		ErrorGroups: []*soong_build_error_proto.BuildErrorGroup{{
+			Signature:    proto.String("error: <unknown> type"),
+			Count:        proto.Uint32(1),
+			ExampleIndex: proto.Uint32(0),
+		}},
+	})
+
+	report := NewBuildReport(logger.New(ioutil.Discard), reportFile, metricsFile, progressFile, errorFile).(*buildReport)
+	for i := 1; i <= buildReportSlowestActions+5; i++ {
+		action := &Action{Description: fmt.Sprintf("action %d", i), Outputs: []string{fmt.Sprintf("out%d", i)}}
+		report.clock = testClock(time.Unix(0, 0))
+		report.StartAction(action, Counts{})
+		report.clock = testClock(time.Unix(int64(i), 0))
+		report.FinishAction(ActionResult{Action: action}, Counts{})
+	}
+	report.Flush()
+
+	buf, err := ioutil.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(buf)
+
+	for _, want := range []string{
+		"FAILED",
+		"aosp_arm64-userdebug",
+		"Critical path (4s)",
+		"link libfoo",
+		"compile bar.cpp",
+		"error: &lt;unknown&gt; type",
+		"action 25",
+		"1m0s",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected report to contain %q", want)
+		}
+	}
+
+	// Only the slowest actions are listed.
+	if strings.Contains(html, "action 5<") {
+		t.Errorf("expected report not to contain action 5")
+	}
+	// Escape sequences from the action output are removed.
+	if strings.Contains(html, "\x1b") {
+		t.Errorf("expected report not to contain escape sequences")
+	}
+	// The report needs to work offline.
+	for _, external := range []string{"src=", "href=", "@import"} {
+		if strings.Contains(html, external) {
+			t.Errorf("expected report not to reference external assets, found %q", external)
+		}
+	}
+}
+
+func TestBuildReportMissingFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build_report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	reportFile := filepath.Join(dir, "build_report.html")
+	report := NewBuildReport(logger.New(ioutil.Discard), reportFile,
+		filepath.Join(dir, "soong_metrics"), filepath.Join(dir, "build_progress.pb"), filepath.Join(dir, "build_error"))
+	report.Flush()
+
+	buf, err := ioutil.ReadFile(reportFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(buf), "SUCCESS") {
+		t.Errorf("expected a successful report without any inputs, got:\n%s", buf)
+	}
+}
@@ -15,23 +15,45 @@
 package status
 
 import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+
 	"android/soong/ui/logger"
+	"android/soong/ui/status/build_progress_proto"
 )
 
-func NewCriticalPath(log logger.Logger) StatusOutput {
+// NewCriticalPath returns a StatusOutput that tracks the longest chain of
+// dependent actions in the build and logs it to the verbose log when flushed.
+//
+// Each action on the critical path is attributed to the module that owns it,
+// either from the module name in Soong's action descriptions, or from the
+// installed files listed in moduleInfoFile (Make's module-info.json). If
+// buildProgressFile is set, the critical path and the time attributed to each
+// module are added to the build_progress proto in that file.
+func NewCriticalPath(log logger.Logger, moduleInfoFile, buildProgressFile string) StatusOutput {
 	return &criticalPath{
-		log:     log,
-		running: make(map[*Action]time.Time),
-		nodes:   make(map[string]*node),
-		clock:   osClock{},
+		log:               log,
+		running:           make(map[*Action]time.Time),
+		nodes:             make(map[string]*node),
+		clock:             osClock{},
+		moduleInfoFile:    moduleInfoFile,
+		buildProgressFile: buildProgressFile,
 	}
 }
 
 type criticalPath struct {
 	log logger.Logger
 
+	moduleInfoFile    string
+	buildProgressFile string
+
 	nodes   map[string]*node
 	running map[*Action]time.Time
 
@@ -125,6 +147,19 @@ func (cp *criticalPath) Flush() {
 			cp.log.Verbosef("   %2d:%02d %s",
 				seconds/60, seconds%60, criticalPath[i].action.Description)
 		}
+
+		modules := cp.moduleOwners(criticalPath)
+		moduleDurations := criticalPathModules(criticalPath, modules)
+		cp.log.Verbose("critical path by module:")
+		for _, m := range moduleDurations {
+			seconds := int(m.duration.Round(time.Second).Seconds())
+			cp.log.Verbosef("   %2d:%02d %s (%d actions)",
+				seconds/60, seconds%60, m.module, m.actions)
+		}
+
+		if cp.buildProgressFile != "" {
+			cp.writeBuildProgress(criticalPath, modules, moduleDurations)
+		}
 	}
 }
 
@@ -152,3 +187,177 @@ func (cp *criticalPath) criticalPath() []*node {
 
 	return criticalPath
 }
+
+// criticalPathModule is the time on the critical path attributed to a module.
+type criticalPathModule struct {
+	module   string
+	duration time.Duration
+	actions  int
+}
+
+const unknownModule = "<unknown>"
+
+// criticalPathModules sums the duration of the critical path nodes owned by
+// each module, sorted by decreasing duration.
+func criticalPathModules(criticalPath []*node, modules []string) []*criticalPathModule {
+	byModule := make(map[string]*criticalPathModule)
+	var ret []*criticalPathModule
+	// Walk the critical path in build order so that ties are sorted in
+	// build order.
+	for i := len(criticalPath) - 1; i >= 0; i-- {
+		node := criticalPath[i]
+		module := modules[i]
+		if module == "" {
+			module = unknownModule
+		}
+		m := byModule[module]
+		if m == nil {
+			m = &criticalPathModule{module: module}
+			byModule[module] = m
+			ret = append(ret, m)
+		}
+		m.duration += node.duration
+		m.actions++
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].duration > ret[j].duration
+	})
+	return ret
+}
+
+// moduleOwners returns the module that owns each node of the critical path,
+// or an empty string if the owner couldn't be determined.
+func (cp *criticalPath) moduleOwners(criticalPath []*node) []string {
+	var installed map[string]string
+	if cp.moduleInfoFile != "" {
+		var err error
+		installed, err = ReadModuleInfoInstalled(cp.moduleInfoFile)
+		if err != nil && !os.IsNotExist(err) {
+			cp.log.Verbosef("Failed to read %s: %v", cp.moduleInfoFile, err)
+		}
+	}
+
+	ret := make([]string, len(criticalPath))
+	for i, node := range criticalPath {
+		ret[i] = actionModule(node.action, installed)
+	}
+	return ret
+}
+
+// Matches the intermediates directories of Make modules, for example
+// out/target/product/generic/obj/SHARED_LIBRARIES/libfoo_intermediates/.
+var makeIntermediatesRe = regexp.MustCompile(`/obj(?:_[^/]+)?/[A-Z_]+/([^/]+)_intermediates/`)
+
+// Matches the intermediates directories of Soong modules, for example
+// out/soong/.intermediates/external/foo/libfoo/android_arm64_armv8-a_shared/.
+var soongIntermediatesRe = regexp.MustCompile(`/\.intermediates/(.+)/([^/]+)/(?:android|linux_glibc|linux_bionic|linux_musl|darwin|windows)_[^/]*/`)
+
+// actionModule returns the module that owns an action. Soong prefixes the
+// descriptions of all of its actions with the module's "//dir:name" label.
+// Make actions are matched by their outputs, either against the installed
+// files of each module, or against the module's intermediates directory.
+func actionModule(action *Action, installed map[string]string) string {
+	if fields := strings.Fields(action.Description); len(fields) > 0 && strings.HasPrefix(fields[0], "//") {
+		return fields[0]
+	}
+
+	for _, output := range action.Outputs {
+		if module, ok := installed[output]; ok {
+			return module
+		}
+	}
+
+	for _, output := range action.Outputs {
+		if module := intermediatesModule(output); module != "" {
+			return module
+		}
+	}
+
+	return ""
+}
+
+// OutputModule returns the module that owns an output file, using the
+// installed files from ReadModuleInfoInstalled and the intermediates
+// directories of Make and Soong modules. It returns an empty string if the
+// owner isn't known.
+func OutputModule(output string, installed map[string]string) string {
+	if module, ok := installed[output]; ok {
+		return module
+	}
+	return intermediatesModule(output)
+}
+
+func intermediatesModule(output string) string {
+	if match := makeIntermediatesRe.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	if match := soongIntermediatesRe.FindStringSubmatch(output); match != nil {
+		return "//" + match[1] + ":" + match[2]
+	}
+	return ""
+}
+
+// ReadModuleInfoInstalled reads a module-info.json file and returns a map from
+// each installed file to the module that installs it.
+func ReadModuleInfoInstalled(filename string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var moduleInfo map[string]struct {
+		Installed []string `json:"installed"`
+	}
+	if err := json.Unmarshal(data, &moduleInfo); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]string)
+	for module, info := range moduleInfo {
+		for _, installed := range info.Installed {
+			ret[installed] = module
+		}
+	}
+	return ret, nil
+}
+
+// writeBuildProgress adds the critical path to the existing build_progress
+// proto.
+func (cp *criticalPath) writeBuildProgress(criticalPath []*node, modules []string,
+	moduleDurations []*criticalPathModule) {
+
+	progress := &soong_build_progress_proto.BuildProgress{}
+	if data, err := ioutil.ReadFile(cp.buildProgressFile); err == nil {
+		if err := proto.Unmarshal(data, progress); err != nil {
+			cp.log.Verbosef("Failed to parse %s: %v", cp.buildProgressFile, err)
+		}
+	}
+
+	progress.CriticalPath = nil
+	for i := len(criticalPath) - 1; i >= 0; i-- {
+		action := &soong_build_progress_proto.CriticalPathAction{
+			Description: proto.String(criticalPath[i].action.Description),
+			Outputs:     criticalPath[i].action.Outputs,
+			DurationMs:  proto.Uint64(uint64(criticalPath[i].duration.Milliseconds())),
+		}
+		if modules[i] != "" {
+			action.Module = proto.String(modules[i])
+		}
+		progress.CriticalPath = append(progress.CriticalPath, action)
+	}
+
+	progress.CriticalPathModules = nil
+	for _, m := range moduleDurations {
+		progress.CriticalPathModules = append(progress.CriticalPathModules,
+			&soong_build_progress_proto.CriticalPathModule{
+				Module:     proto.String(m.module),
+				DurationMs: proto.Uint64(uint64(m.duration.Milliseconds())),
+				Actions:    proto.Uint32(uint32(m.actions)),
+			})
+	}
+
+	if err := writeToFile(progress, cp.buildProgressFile); err != nil {
+		cp.log.Printf("Failed to write file %s: %v\n", cp.buildProgressFile, err)
+	}
+}
@@ -15,9 +15,18 @@
 package status
 
 import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/status/build_progress_proto"
 )
 
 type testCriticalPath struct {
@@ -137,7 +146,7 @@ func TestCriticalPath(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			cp := &testCriticalPath{
-				criticalPath: NewCriticalPath(nil).(*criticalPath),
+				criticalPath: NewCriticalPath(nil, "", "").(*criticalPath),
 				actions:      make(map[int]*Action),
 			}
 
@@ -164,3 +173,124 @@ func TestCriticalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestActionModule(t *testing.T) {
+	installed := map[string]string{
+		"out/target/product/generic/system/bin/sh": "sh",
+	}
+
+	tests := []struct {
+		name   string
+		action *Action
+		want   string
+	}{
+		{
+			name:   "soong",
+			action: &Action{Description: "//frameworks/base:framework javac [common]", Outputs: []string{"out/soong/x.jar"}},
+			want:   "//frameworks/base:framework",
+		},
+		{
+			name:   "make installed",
+			action: &Action{Description: "Install: out/target/product/generic/system/bin/sh", Outputs: []string{"out/target/product/generic/system/bin/sh"}},
+			want:   "sh",
+		},
+		{
+			name:   "make intermediates",
+			action: &Action{Description: "target  C++: libfoo <= foo.cpp", Outputs: []string{"out/target/product/generic/obj/SHARED_LIBRARIES/libfoo_intermediates/foo.o"}},
+			want:   "libfoo",
+		},
+		{
+			name:   "make 2nd arch intermediates",
+			action: &Action{Outputs: []string{"out/target/product/generic/obj_arm/STATIC_LIBRARIES/libbar_intermediates/bar.o"}},
+			want:   "libbar",
+		},
+		{
+			name:   "soong intermediates",
+			action: &Action{Description: "prebuilt", Outputs: []string{"out/soong/.intermediates/external/foo/libfoo/android_arm64_armv8-a_shared/libfoo.so"}},
+			want:   "//external/foo:libfoo",
+		},
+		{
+			name:   "unknown",
+			action: &Action{Description: "touch out/a", Outputs: []string{"out/a"}},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if g := actionModule(tt.action, installed); g != tt.want {
+				t.Errorf("actionModule() = %q, want %q", g, tt.want)
+			}
+		})
+	}
+}
+
+func TestCriticalPathModules(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "critical_path")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	moduleInfoFile := filepath.Join(tempDir, "module-info.json")
+	buildProgressFile := filepath.Join(tempDir, "build_progress.pb")
+
+	err = ioutil.WriteFile(moduleInfoFile, []byte(`{"sh": {"installed": ["out/system/bin/sh"]}}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cp := &testCriticalPath{
+		criticalPath: NewCriticalPath(logger.New(ioutil.Discard), moduleInfoFile, buildProgressFile).(*criticalPath),
+		actions:      make(map[int]*Action),
+	}
+
+	cp.start(0, 0, []string{"out/a.o"}, nil)
+	cp.actions[0].Description = "//a:a cc"
+	cp.finish(0, 2*time.Second)
+	cp.start(1, 2*time.Second, []string{"out/a.so"}, []string{"out/a.o"})
+	cp.actions[1].Description = "//a:a ld"
+	cp.finish(1, 3*time.Second)
+	cp.start(2, 3*time.Second, []string{"out/sh"}, []string{"out/a.so"})
+	cp.actions[2].Description = "//b:sh ld"
+	cp.finish(2, 6*time.Second)
+	cp.start(3, 6*time.Second, []string{"out/system/bin/sh"}, []string{"out/sh"})
+	cp.finish(3, 7*time.Second)
+
+	cp.Flush()
+
+	data, err := ioutil.ReadFile(buildProgressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	progress := &soong_build_progress_proto.BuildProgress{}
+	if err := proto.Unmarshal(data, progress); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotPath []string
+	for _, action := range progress.GetCriticalPath() {
+		gotPath = append(gotPath, fmt.Sprintf("%s %s %d", action.GetDescription(), action.GetModule(), action.GetDurationMs()))
+	}
+	wantPath := []string{
+		"//a:a cc //a:a 2000",
+		"//a:a ld //a:a 1000",
+		"//b:sh ld //b:sh 3000",
+		"out/system/bin/sh sh 1000",
+	}
+	if !reflect.DeepEqual(gotPath, wantPath) {
+		t.Errorf("critical path = %q, want %q", gotPath, wantPath)
+	}
+
+	var gotModules []string
+	for _, m := range progress.GetCriticalPathModules() {
+		gotModules = append(gotModules, fmt.Sprintf("%s %d %d", m.GetModule(), m.GetDurationMs(), m.GetActions()))
+	}
+	wantModules := []string{
+		"//a:a 3000 2",
+		"//b:sh 3000 1",
+		"sh 1000 1",
+	}
+	if !reflect.DeepEqual(gotModules, wantModules) {
+		t.Errorf("critical path modules = %q, want %q", gotModules, wantModules)
+	}
+}
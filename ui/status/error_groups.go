@@ -0,0 +1,134 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// Matches the ANSI color codes added by clang and soong_javac_wrapper.
+	ansiEscapeRe = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	// Matches the first error diagnostic in a line, either with a location
+	// prefix as printed by clang and javac:
+	//   foo.cpp:12:5: error: use of undeclared identifier 'x'
+	//   Foo.java:40: error: cannot find symbol
+	// or with a tool name as printed by linkers and other tools:
+	//   ld.lld: error: undefined symbol: foo
+	diagnosticRe = regexp.MustCompile(`(?:^|[\s:])((?:fatal )?error):\s*(.*)$`)
+
+	// Matches the symbol line javac prints a few lines after a "cannot find
+	// symbol" error.
+	javacSymbolRe = regexp.MustCompile(`^\s*symbol:\s*(.*)$`)
+
+	// Matches a file location with a line and optional column number.
+	locationRe = regexp.MustCompile(`[\w.+-]*(?:/[\w.+-]+)*\.\w+:\d+(?::\d+)?`)
+
+	whitespaceRe = regexp.MustCompile(`\s+`)
+)
+
+// The number of lines after a javac error searched for its symbol line.
+const javacSymbolLines = 4
+
+// errorSignature returns a normalized signature for a failed action, so that
+// actions failing for the same root cause can be grouped together. It is the
+// first error diagnostic in the output with file locations removed, or the
+// first line of the output if there is no recognizable diagnostic.
+func errorSignature(result ActionResult) string {
+	lines := strings.Split(ansiEscapeRe.ReplaceAllString(result.Output, ""), "\n")
+
+	for i, line := range lines {
+		match := diagnosticRe.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		signature := match[1] + ": " + normalizeErrorMessage(match[2])
+
+		// javac's "cannot find symbol" doesn't mention the symbol on the
+		// same line, which would group unrelated failures together.
+		for j := i + 1; j < len(lines) && j <= i+javacSymbolLines; j++ {
+			if symbol := javacSymbolRe.FindStringSubmatch(lines[j]); symbol != nil {
+				signature += " (symbol: " + normalizeErrorMessage(symbol[1]) + ")"
+				break
+			}
+		}
+
+		return signature
+	}
+
+	for _, line := range lines {
+		if line = normalizeErrorMessage(line); line != "" {
+			return line
+		}
+	}
+
+	if result.Error != nil {
+		return result.Error.Error()
+	}
+	return ""
+}
+
+func normalizeErrorMessage(s string) string {
+	s = locationRe.ReplaceAllString(s, "<location>")
+	s = whitespaceRe.ReplaceAllString(s, " ")
+	return strings.TrimSpace(s)
+}
+
+// errorGroup is a set of failed actions that share an error signature.
+type errorGroup struct {
+	signature string
+	count     int
+
+	// The index of the first failure with this signature in the order the
+	// failures were added, and its result.
+	exampleIndex int
+	example      ActionResult
+}
+
+// errorGroups groups failed actions by their error signature.
+type errorGroups struct {
+	failures    int
+	groups      []*errorGroup
+	bySignature map[string]*errorGroup
+}
+
+func newErrorGroups() *errorGroups {
+	return &errorGroups{
+		bySignature: make(map[string]*errorGroup),
+	}
+}
+
+// add adds a failed action, returning the group it was added to.
+func (e *errorGroups) add(result ActionResult) *errorGroup {
+	signature := errorSignature(result)
+
+	group := e.bySignature[signature]
+	if group == nil {
+		group = &errorGroup{
+			signature:    signature,
+			exampleIndex: e.failures,
+			example:      result,
+		}
+		e.groups = append(e.groups, group)
+		e.bySignature[signature] = group
+	}
+	group.count++
+	e.failures++
+
+	return group
+}
@@ -0,0 +1,199 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/status/build_error_proto"
+)
+
+func TestErrorSignature(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+	}{
+		{
+			name: "clang",
+			output: "In file included from frameworks/foo/foo.cpp:3:\n" +
+				"frameworks/foo/foo.h:12:5: error: use of undeclared identifier 'bar'\n" +
+				"    bar();\n" +
+				"    ^\n" +
+				"1 error generated.\n",
+			want: "error: use of undeclared identifier 'bar'",
+		},
+		{
+			name: "clang color",
+			output: "\x1b[1mframeworks/foo/foo.h:12:5: \x1b[0m\x1b[0;1;31merror: \x1b[0m\x1b[1m" +
+				"use of undeclared identifier 'bar'\x1b[0m\n",
+			want: "error: use of undeclared identifier 'bar'",
+		},
+		{
+			name:   "clang fatal",
+			output: "system/core/a.cpp:1:10: fatal error: 'missing/header.h' file not found\n",
+			want:   "fatal error: 'missing/header.h' file not found",
+		},
+		{
+			name:   "clang location in message",
+			output: "a.cpp:5:6: error: redefinition of 'f' (previous definition is at b/c.h:3:6)\n",
+			want:   "error: redefinition of 'f' (previous definition is at <location>)",
+		},
+		{
+			name: "javac wrapper",
+			output: "\x1b[1mframeworks/base/Foo.java:40: \x1b[31merror:\x1b[0m\x1b[1m cannot find symbol\x1b[0m\n" +
+				"import com.android.Bar;\n" +
+				"\x1b[1m                  \x1b[32m^\x1b[0m\x1b[1m\x1b[0m\n" +
+				"  symbol:   class Bar\n" +
+				"  location: package com.android\n",
+			want: "error: cannot find symbol (symbol: class Bar)",
+		},
+		{
+			name:   "linker",
+			output: "ld.lld: error: undefined symbol: foo()\n>>> referenced by a.cpp:3\n",
+			want:   "error: undefined symbol: foo()",
+		},
+		{
+			name:   "no diagnostic",
+			output: "\n  Segmentation   fault\n",
+			want:   "Segmentation fault",
+		},
+		{
+			name: "no output",
+			err:  errors.New("exited with code: 1"),
+			want: "exited with code: 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorSignature(ActionResult{Action: &Action{}, Output: tt.output, Error: tt.err})
+			if got != tt.want {
+				t.Errorf("want %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func failedResult(output string, outputs ...string) ActionResult {
+	return ActionResult{
+		Action: &Action{Description: "compile " + outputs[0], Outputs: outputs},
+		Output: output,
+		Error:  errors.New("exited with code: 1"),
+	}
+}
+
+var errorGroupResults = []ActionResult{
+	failedResult("a.cpp:1:10: fatal error: 'foo.h' file not found\n", "a.o"),
+	failedResult("Foo.java:3: error: ';' expected\n", "foo.jar"),
+	failedResult("b.cpp:2:10: fatal error: 'foo.h' file not found\n", "b.o"),
+	failedResult("c.cpp:7:10: fatal error: 'foo.h' file not found\n", "c.o"),
+}
+
+func TestProtoErrorLogGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "error_groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "build_error")
+	stat := &Status{}
+	stat.AddOutput(NewProtoErrorLog(logger.New(ioutil.Discard), filename))
+	tool := stat.StartTool()
+	for _, result := range errorGroupResults {
+		tool.StartAction(result.Action)
+		tool.FinishAction(result)
+	}
+	tool.Finish()
+	stat.Finish()
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buildError := &soong_build_error_proto.BuildError{}
+	if err := proto.Unmarshal(data, buildError); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []*soong_build_error_proto.BuildErrorGroup{
+		{
+			Signature:    proto.String("fatal error: 'foo.h' file not found"),
+			Count:        proto.Uint32(3),
+			ExampleIndex: proto.Uint32(0),
+		},
+		{
+			Signature:    proto.String("error: ';' expected"),
+			Count:        proto.Uint32(1),
+			ExampleIndex: proto.Uint32(1),
+		},
+	}
+	if len(buildError.ErrorGroups) != len(want) {
+		t.Fatalf("want %d groups, got %v", len(want), buildError.ErrorGroups)
+	}
+	for i := range want {
+		if !proto.Equal(buildError.ErrorGroups[i], want[i]) {
+			t.Errorf("group %d: want %v, got %v", i, want[i], buildError.ErrorGroups[i])
+		}
+	}
+	if g := len(buildError.ActionErrors); g != len(errorGroupResults) {
+		t.Errorf("want %d action errors, got %d", len(errorGroupResults), g)
+	}
+}
+
+func TestErrorLogGroups(t *testing.T) {
+	dir, err := ioutil.TempDir("", "error_groups")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "error.log")
+	stat := &Status{}
+	stat.AddOutput(NewErrorLog(logger.New(ioutil.Discard), filename))
+	tool := stat.StartTool()
+	for _, result := range errorGroupResults {
+		tool.StartAction(result.Action)
+		tool.FinishAction(result)
+	}
+	tool.Finish()
+	stat.Finish()
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "4 failed actions in 2 groups by error signature:\n" +
+		"\n3x fatal error: 'foo.h' file not found\n" +
+		"  e.g. compile a.o\n" +
+		"  Outputs: a.o\n" +
+		"\n1x error: ';' expected\n" +
+		"  e.g. compile foo.jar\n" +
+		"  Outputs: foo.jar\n"
+	if !strings.HasSuffix(string(data), want) {
+		t.Errorf("expected error log to end with:\n%s\ngot:\n%s", want, data)
+	}
+}
@@ -85,8 +85,9 @@ func (v *verboseLog) Write(p []byte) (int, error) {
 }
 
 type errorLog struct {
-	w     io.WriteCloser
-	empty bool
+	w      io.WriteCloser
+	empty  bool
+	groups *errorGroups
 }
 
 func NewErrorLog(log logger.Logger, filename string) StatusOutput {
@@ -97,8 +98,9 @@ func NewErrorLog(log logger.Logger, filename string) StatusOutput {
 	}
 
 	return &errorLog{
-		w:     f,
-		empty: true,
+		w:      f,
+		empty:  true,
+		groups: newErrorGroups(),
 	}
 }
 
@@ -125,12 +127,33 @@ func (e *errorLog) FinishAction(result ActionResult, counts Counts) {
 		fmt.Fprintf(e.w, "Command: %s\n", result.Command)
 	}
 	fmt.Fprintf(e.w, "Output:\n%s\n", result.Output)
+
+	e.groups.add(result)
 }
 
 func (e *errorLog) Flush() {
+	e.writeGroups()
 	e.w.Close()
 }
 
+// writeGroups writes a summary of the failed actions grouped by their error
+// signature, so that a single root cause breaking many actions is easy to spot.
+func (e *errorLog) writeGroups() {
+	if e.groups.failures == 0 {
+		return
+	}
+
+	fmt.Fprintf(e.w, "\n\n%d failed actions in %d groups by error signature:\n",
+		e.groups.failures, len(e.groups.groups))
+	for _, group := range e.groups.groups {
+		fmt.Fprintf(e.w, "\n%dx %s\n", group.count, group.signature)
+		fmt.Fprintf(e.w, "  e.g. %s\n", group.example.Description)
+		if len(group.example.Outputs) > 0 {
+			fmt.Fprintf(e.w, "  Outputs: %s\n", strings.Join(group.example.Outputs, " "))
+		}
+	}
+}
+
 func (e *errorLog) Message(level MsgLevel, message string) {
 	if level < ErrorLvl {
 		return
@@ -153,6 +176,7 @@ type errorProtoLog struct {
 	errorProto soong_build_error_proto.BuildError
 	filename   string
 	log        logger.Logger
+	groups     *errorGroups
 }
 
 func NewProtoErrorLog(log logger.Logger, filename string) StatusOutput {
@@ -161,6 +185,7 @@ func NewProtoErrorLog(log logger.Logger, filename string) StatusOutput {
 		errorProto: soong_build_error_proto.BuildError{},
 		filename:   filename,
 		log:        log,
+		groups:     newErrorGroups(),
 	}
 }
 
@@ -179,6 +204,16 @@ func (e *errorProtoLog) FinishAction(result ActionResult, counts Counts) {
 		Error:       proto.String(result.Error.Error()),
 	})
 
+	e.groups.add(result)
+	e.errorProto.ErrorGroups = e.errorProto.ErrorGroups[:0]
+	for _, group := range e.groups.groups {
+		e.errorProto.ErrorGroups = append(e.errorProto.ErrorGroups, &soong_build_error_proto.BuildErrorGroup{
+			Signature:    proto.String(group.signature),
+			Count:        proto.Uint32(uint32(group.count)),
+			ExampleIndex: proto.Uint32(uint32(group.exampleIndex)),
+		})
+	}
+
 	err := writeToFile(&e.errorProto, e.filename)
 	if err != nil {
 		e.log.Printf("Failed to write file %s: %v\n", e.filename, err)
@@ -0,0 +1,295 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"android/soong/ui/logger"
+)
+
+// The number of entries listed in each section of the resource usage report.
+const resourceUsageTopN = 20
+
+// resourceUsage is the accumulated ActionResultStats of a group of actions.
+type resourceUsage struct {
+	Name    string `json:"name"`
+	Actions int    `json:"actions"`
+
+	UserTimeMs   uint64 `json:"user_time_ms"`
+	SystemTimeMs uint64 `json:"system_time_ms"`
+
+	// MaxRssKB is the largest max RSS of any single action in the group.
+	MaxRssKB uint64 `json:"max_rss_kb"`
+
+	MinorPageFaults            uint64 `json:"minor_page_faults"`
+	MajorPageFaults            uint64 `json:"major_page_faults"`
+	IOInputKB                  uint64 `json:"io_input_kb"`
+	IOOutputKB                 uint64 `json:"io_output_kb"`
+	VoluntaryContextSwitches   uint64 `json:"voluntary_context_switches"`
+	InvoluntaryContextSwitches uint64 `json:"involuntary_context_switches"`
+}
+
+func (r *resourceUsage) add(stats ActionResultStats) {
+	r.Actions++
+	r.UserTimeMs += uint64(stats.UserTime)
+	r.SystemTimeMs += uint64(stats.SystemTime)
+	if stats.MaxRssKB > r.MaxRssKB {
+		r.MaxRssKB = stats.MaxRssKB
+	}
+	r.MinorPageFaults += stats.MinorPageFaults
+	r.MajorPageFaults += stats.MajorPageFaults
+	r.IOInputKB += stats.IOInputKB
+	r.IOOutputKB += stats.IOOutputKB
+	r.VoluntaryContextSwitches += stats.VoluntaryContextSwitches
+	r.InvoluntaryContextSwitches += stats.InvoluntaryContextSwitches
+}
+
+func (r *resourceUsage) cpuMs() uint64 { return r.UserTimeMs + r.SystemTimeMs }
+func (r *resourceUsage) ioKB() uint64  { return r.IOInputKB + r.IOOutputKB }
+
+// resourceUsageReport is the machine-readable form of the report.
+type resourceUsageReport struct {
+	Total       resourceUsage    `json:"total"`
+	ByRule      []*resourceUsage `json:"by_rule"`
+	ByOutputDir []*resourceUsage `json:"by_output_dir"`
+
+	// The actions that individually used the most CPU, memory and IO.
+	TopActionsByCpu []*resourceUsage `json:"top_actions_by_cpu"`
+	TopActionsByRss []*resourceUsage `json:"top_actions_by_rss"`
+	TopActionsByIO  []*resourceUsage `json:"top_actions_by_io"`
+}
+
+type resourceUsageLog struct {
+	log                logger.Logger
+	textFile, jsonFile string
+
+	total       resourceUsage
+	byRule      map[string]*resourceUsage
+	byOutputDir map[string]*resourceUsage
+	actions     []*resourceUsage
+}
+
+// NewResourceUsageLog returns a StatusOutput that accumulates the
+// ActionResultStats reported for each action by rule and by output directory.
+// When flushed, it writes a human-readable report of the largest consumers of
+// CPU, memory and IO to textFile, and the full data as JSON to jsonFile.
+func NewResourceUsageLog(log logger.Logger, textFile, jsonFile string) StatusOutput {
+	return &resourceUsageLog{
+		log:         log,
+		textFile:    textFile,
+		jsonFile:    jsonFile,
+		total:       resourceUsage{Name: "total"},
+		byRule:      make(map[string]*resourceUsage),
+		byOutputDir: make(map[string]*resourceUsage),
+	}
+}
+
+func (r *resourceUsageLog) StartAction(action *Action, counts Counts) {}
+
+func (r *resourceUsageLog) FinishAction(result ActionResult, counts Counts) {
+	if result.Stats == (ActionResultStats{}) {
+		// Actions that weren't run as a process (or when the stats
+		// aren't available) carry no information.
+		return
+	}
+
+	r.total.add(result.Stats)
+	addResourceUsage(r.byRule, actionRule(result.Action), result.Stats)
+	addResourceUsage(r.byOutputDir, actionOutputDir(result.Action), result.Stats)
+
+	name := result.Description
+	if len(result.Outputs) > 0 {
+		name = result.Outputs[0]
+	}
+	action := &resourceUsage{Name: name}
+	action.add(result.Stats)
+	r.actions = append(r.actions, action)
+}
+
+func (r *resourceUsageLog) Flush() {
+	if r.total.Actions == 0 {
+		return
+	}
+
+	report := r.report()
+
+	if err := writeResourceUsageText(report, r.textFile); err != nil {
+		r.log.Printf("Failed to write file %s: %v\n", r.textFile, err)
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		err = ioutil.WriteFile(r.jsonFile, data, 0644)
+	}
+	if err != nil {
+		r.log.Printf("Failed to write file %s: %v\n", r.jsonFile, err)
+	}
+}
+
+func (r *resourceUsageLog) Message(level MsgLevel, message string) {}
+
+func (r *resourceUsageLog) Write(p []byte) (int, error) {
+	// Discard writes
+	return len(p), nil
+}
+
+func (r *resourceUsageLog) report() *resourceUsageReport {
+	return &resourceUsageReport{
+		Total:           r.total,
+		ByRule:          sortedResourceUsage(r.byRule, moreCpu),
+		ByOutputDir:     sortedResourceUsage(r.byOutputDir, moreCpu),
+		TopActionsByCpu: topResourceUsage(r.actions, moreCpu),
+		TopActionsByRss: topResourceUsage(r.actions, moreRss),
+		TopActionsByIO:  topResourceUsage(r.actions, moreIO),
+	}
+}
+
+func moreCpu(a, b *resourceUsage) bool { return a.cpuMs() > b.cpuMs() }
+func moreRss(a, b *resourceUsage) bool { return a.MaxRssKB > b.MaxRssKB }
+func moreIO(a, b *resourceUsage) bool  { return a.ioKB() > b.ioKB() }
+
+func addResourceUsage(m map[string]*resourceUsage, name string, stats ActionResultStats) {
+	usage := m[name]
+	if usage == nil {
+		usage = &resourceUsage{Name: name}
+		m[name] = usage
+	}
+	usage.add(stats)
+}
+
+// sortedResourceUsage returns all of the entries in m, sorted by less and
+// then by name.
+func sortedResourceUsage(m map[string]*resourceUsage, less func(a, b *resourceUsage) bool) []*resourceUsage {
+	ret := make([]*resourceUsage, 0, len(m))
+	for _, usage := range m {
+		ret = append(ret, usage)
+	}
+	sortResourceUsage(ret, less)
+	return ret
+}
+
+// topResourceUsage returns the first resourceUsageTopN entries of list when
+// sorted by less.
+func topResourceUsage(list []*resourceUsage, less func(a, b *resourceUsage) bool) []*resourceUsage {
+	ret := append([]*resourceUsage(nil), list...)
+	sortResourceUsage(ret, less)
+	if len(ret) > resourceUsageTopN {
+		ret = ret[:resourceUsageTopN]
+	}
+	return ret
+}
+
+func sortResourceUsage(list []*resourceUsage, less func(a, b *resourceUsage) bool) {
+	sort.SliceStable(list, func(i, j int) bool {
+		if less(list[i], list[j]) {
+			return true
+		} else if less(list[j], list[i]) {
+			return false
+		}
+		return list[i].Name < list[j].Name
+	})
+}
+
+// actionRule returns a short name for the kind of work an action performs,
+// derived from its description. Soong descriptions are of the form
+// "//dir:module rule ...", so the rule is the word following the module.
+// Make descriptions are usually of the form "target C++: ...", so the rule is
+// the text before the colon.
+func actionRule(action *Action) string {
+	desc := action.Description
+	if desc == "" {
+		desc = action.Command
+	}
+	fields := strings.Fields(desc)
+	if len(fields) == 0 {
+		return "<unknown>"
+	}
+
+	if strings.HasPrefix(fields[0], "//") {
+		if len(fields) > 1 {
+			return fields[1]
+		}
+		return "<unknown>"
+	}
+
+	for i, field := range fields {
+		if i >= 3 {
+			break
+		}
+		if strings.HasSuffix(field, ":") {
+			return strings.TrimSuffix(strings.Join(fields[:i+1], " "), ":")
+		}
+	}
+
+	return fields[0]
+}
+
+// actionOutputDir returns the directory containing the first output of an
+// action.
+func actionOutputDir(action *Action) string {
+	if len(action.Outputs) == 0 {
+		return "<none>"
+	}
+	return filepath.Dir(action.Outputs[0])
+}
+
+func writeResourceUsageText(report *resourceUsageReport, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fmt.Fprintf(f, "%d actions used %s CPU, up to %s RSS and %s IO\n",
+		report.Total.Actions, formatCpuMs(report.Total.cpuMs()),
+		formatKB(report.Total.MaxRssKB), formatKB(report.Total.ioKB()))
+
+	writeResourceUsageSection(f, "rules by CPU time", topResourceUsage(report.ByRule, moreCpu))
+	writeResourceUsageSection(f, "rules by max RSS", topResourceUsage(report.ByRule, moreRss))
+	writeResourceUsageSection(f, "rules by IO", topResourceUsage(report.ByRule, moreIO))
+	writeResourceUsageSection(f, "output directories by CPU time", topResourceUsage(report.ByOutputDir, moreCpu))
+	writeResourceUsageSection(f, "output directories by max RSS", topResourceUsage(report.ByOutputDir, moreRss))
+	writeResourceUsageSection(f, "output directories by IO", topResourceUsage(report.ByOutputDir, moreIO))
+	writeResourceUsageSection(f, "actions by CPU time", report.TopActionsByCpu)
+	writeResourceUsageSection(f, "actions by max RSS", report.TopActionsByRss)
+	writeResourceUsageSection(f, "actions by IO", report.TopActionsByIO)
+
+	return f.Close()
+}
+
+func writeResourceUsageSection(w io.Writer, title string, list []*resourceUsage) {
+	fmt.Fprintf(w, "\nTop %d %s:\n", len(list), title)
+	fmt.Fprintf(w, "  %10s %10s %10s %8s  %s\n", "CPU", "Max RSS", "IO", "Actions", "Name")
+	for _, usage := range list {
+		fmt.Fprintf(w, "  %10s %10s %10s %8d  %s\n", formatCpuMs(usage.cpuMs()),
+			formatKB(usage.MaxRssKB), formatKB(usage.ioKB()), usage.Actions, usage.Name)
+	}
+}
+
+func formatCpuMs(ms uint64) string {
+	return fmt.Sprintf("%.1fs", float64(ms)/1000)
+}
+
+func formatKB(kb uint64) string {
+	return fmt.Sprintf("%.1fMB", float64(kb)/1024)
+}
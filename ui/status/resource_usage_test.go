@@ -0,0 +1,127 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"android/soong/ui/logger"
+)
+
+func TestActionRule(t *testing.T) {
+	tests := []struct {
+		action *Action
+		want   string
+	}{
+		{&Action{Description: "//frameworks/base:framework javac [common]"}, "javac"},
+		{&Action{Description: "//external/foo:libfoo"}, "<unknown>"},
+		{&Action{Description: "target  C++: libfoo <= foo.cpp"}, "target C++"},
+		{&Action{Description: "Install: out/target/product/foo/system/bin/sh"}, "Install"},
+		{&Action{Description: "touch a b c d"}, "touch"},
+		{&Action{Command: "cp a b"}, "cp"},
+		{&Action{}, "<unknown>"},
+	}
+
+	for _, tt := range tests {
+		if g := actionRule(tt.action); g != tt.want {
+			t.Errorf("actionRule(%+v): want %q, got %q", tt.action, tt.want, g)
+		}
+	}
+}
+
+func TestResourceUsageLog(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "resource_usage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	textFile := filepath.Join(tempDir, "resource_usage.txt")
+	jsonFile := filepath.Join(tempDir, "resource_usage.json")
+
+	stat := &Status{}
+	stat.AddOutput(NewResourceUsageLog(logger.New(ioutil.Discard), textFile, jsonFile))
+	tool := stat.StartTool()
+
+	finish := func(desc, output string, stats ActionResultStats) {
+		action := &Action{Description: desc, Outputs: []string{output}}
+		tool.StartAction(action)
+		tool.FinishAction(ActionResult{Action: action, Stats: stats})
+	}
+
+	finish("//a:a javac", "out/a/a.jar", ActionResultStats{UserTime: 1000, MaxRssKB: 100, IOOutputKB: 10})
+	finish("//b:b javac", "out/b/b.jar", ActionResultStats{UserTime: 3000, SystemTime: 1000, MaxRssKB: 50})
+	finish("//a:a r8", "out/a/a.dex", ActionResultStats{UserTime: 2000, MaxRssKB: 400, IOInputKB: 5})
+	// Actions without stats are ignored.
+	finish("//a:a touch", "out/a/a.stamp", ActionResultStats{})
+
+	tool.Finish()
+	stat.Finish()
+
+	data, err := ioutil.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report resourceUsageReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, w := report.Total.Actions, 3; g != w {
+		t.Errorf("expected %d actions, got %d", w, g)
+	}
+	if g, w := report.Total.MaxRssKB, uint64(400); g != w {
+		t.Errorf("expected max rss %d, got %d", w, g)
+	}
+
+	var rules []string
+	for _, usage := range report.ByRule {
+		rules = append(rules, usage.Name)
+	}
+	if g, w := strings.Join(rules, " "), "javac r8"; g != w {
+		t.Errorf("expected rules %q, got %q", w, g)
+	}
+	if g, w := report.ByRule[0].cpuMs(), uint64(5000); g != w {
+		t.Errorf("expected javac to use %dms, got %dms", w, g)
+	}
+
+	var dirs []string
+	for _, usage := range report.ByOutputDir {
+		dirs = append(dirs, usage.Name)
+	}
+	if g, w := strings.Join(dirs, " "), "out/b out/a"; g != w {
+		t.Errorf("expected output dirs %q, got %q", w, g)
+	}
+
+	if g, w := report.TopActionsByRss[0].Name, "out/a/a.dex"; g != w {
+		t.Errorf("expected top action by rss %q, got %q", w, g)
+	}
+	if g, w := report.TopActionsByIO[0].Name, "out/a/a.jar"; g != w {
+		t.Errorf("expected top action by io %q, got %q", w, g)
+	}
+
+	text, err := ioutil.ReadFile(textFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(text), "3 actions used 7.0s CPU") {
+		t.Errorf("unexpected text report:\n%s", text)
+	}
+}
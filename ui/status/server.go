@@ -0,0 +1,363 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"android/soong/ui/logger"
+)
+
+const (
+	// The number of finished actions and messages kept around for new
+	// clients of the status server.
+	serverHistorySize = 100
+
+	// The number of events buffered for each streaming client before
+	// events start getting dropped for that client.
+	serverEventBufferSize = 1024
+
+	// The maximum time to wait for streaming clients to drain when the
+	// build finishes.
+	serverShutdownTimeout = time.Second
+)
+
+// serverAction is the JSON representation of an Action served by the status
+// server.
+type serverAction struct {
+	Description string    `json:"description,omitempty"`
+	Command     string    `json:"command,omitempty"`
+	Outputs     []string  `json:"outputs,omitempty"`
+	StartTime   time.Time `json:"start_time"`
+}
+
+// serverResult is the JSON representation of an ActionResult served by the
+// status server.
+type serverResult struct {
+	serverAction
+	Output     string            `json:"output,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Stats      ActionResultStats `json:"stats"`
+}
+
+type serverMessage struct {
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// serverSnapshot is the current state of the build, returned from /status and
+// sent as the first event to every /events client.
+type serverSnapshot struct {
+	Counts   Counts           `json:"counts"`
+	Running  []*serverAction  `json:"running"`
+	Results  []*serverResult  `json:"results"`
+	Messages []*serverMessage `json:"messages"`
+	Done     bool             `json:"done"`
+}
+
+// serverEvent is a single event streamed to /events clients.
+type serverEvent struct {
+	name string
+	data interface{}
+}
+
+type serverEventData struct {
+	Counts  *Counts        `json:"counts,omitempty"`
+	Action  *serverAction  `json:"action,omitempty"`
+	Result  *serverResult  `json:"result,omitempty"`
+	Message *serverMessage `json:"message,omitempty"`
+}
+
+type statusServer struct {
+	log      logger.Logger
+	listener net.Listener
+	server   *http.Server
+
+	// Protects everything below, since the http handlers run on their
+	// own goroutines rather than under the Status lock.
+	lock sync.Mutex
+
+	counts   Counts
+	running  map[*Action]*serverAction
+	results  []*serverResult
+	messages []*serverMessage
+	done     bool
+
+	clients map[chan serverEvent]bool
+}
+
+// NewStatusServer returns a StatusOutput that serves the current build status
+// over HTTP on addr. If addr is only a port, the server listens on localhost.
+//
+// GET /status returns a JSON snapshot of the current counts, the running
+// actions, and the most recent results and messages. GET /events streams the
+// same snapshot followed by every start, finish and message event as
+// Server-Sent Events.
+func NewStatusServer(log logger.Logger, addr string) StatusOutput {
+	if !strings.Contains(addr, ":") {
+		addr = "localhost:" + addr
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Println("Failed to start status server:", err)
+		return nil
+	}
+
+	s := &statusServer{
+		log:      log,
+		listener: listener,
+		running:  make(map[*Action]*serverAction),
+		clients:  make(map[chan serverEvent]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", s.serveStatus)
+	mux.HandleFunc("/events", s.serveEvents)
+	s.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Println("Status server failed:", err)
+		}
+	}()
+
+	log.Verbosef("Serving build status on http://%s/status", listener.Addr())
+
+	return s
+}
+
+func (s *statusServer) StartAction(action *Action, counts Counts) {
+	a := newServerAction(action, time.Now())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.counts = counts
+	s.running[action] = a
+	s.broadcast("start", &serverEventData{Counts: &counts, Action: a})
+}
+
+func (s *statusServer) FinishAction(result ActionResult, counts Counts) {
+	end := time.Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	a, ok := s.running[result.Action]
+	if !ok {
+		a = newServerAction(result.Action, end)
+	}
+	delete(s.running, result.Action)
+
+	r := &serverResult{
+		serverAction: *a,
+		Output:       result.Output,
+		DurationMs:   end.Sub(a.StartTime).Milliseconds(),
+		Stats:        result.Stats,
+	}
+	if result.Error != nil {
+		r.Error = result.Error.Error()
+	}
+
+	s.counts = counts
+	s.results = appendServerResult(s.results, r)
+	s.broadcast("finish", &serverEventData{Counts: &counts, Result: r})
+}
+
+func (s *statusServer) Message(level MsgLevel, message string) {
+	if level < StatusLvl {
+		return
+	}
+
+	m := &serverMessage{
+		Level:   level.String(),
+		Message: message,
+		Time:    time.Now(),
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.messages = appendServerMessage(s.messages, m)
+	s.broadcast("message", &serverEventData{Message: m})
+}
+
+func (s *statusServer) Flush() {
+	s.lock.Lock()
+	counts := s.counts
+	s.done = true
+	s.broadcast("done", &serverEventData{Counts: &counts})
+	for c := range s.clients {
+		close(c)
+	}
+	s.clients = nil
+	s.lock.Unlock()
+
+	// Give the streaming clients a moment to receive the final events
+	// before closing their connections.
+	ctx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		s.server.Close()
+	}
+}
+
+func (s *statusServer) Write(p []byte) (int, error) {
+	s.Message(PrintLvl, string(p))
+	return len(p), nil
+}
+
+func newServerAction(action *Action, start time.Time) *serverAction {
+	return &serverAction{
+		Description: action.Description,
+		Command:     action.Command,
+		Outputs:     action.Outputs,
+		StartTime:   start,
+	}
+}
+
+// appendServerResult appends to a list of results, dropping the oldest entries
+// once there are more than serverHistorySize.
+func appendServerResult(list []*serverResult, r *serverResult) []*serverResult {
+	list = append(list, r)
+	if len(list) > serverHistorySize {
+		list = list[len(list)-serverHistorySize:]
+	}
+	return list
+}
+
+// appendServerMessage appends to a list of messages, dropping the oldest
+// entries once there are more than serverHistorySize.
+func appendServerMessage(list []*serverMessage, m *serverMessage) []*serverMessage {
+	list = append(list, m)
+	if len(list) > serverHistorySize {
+		list = list[len(list)-serverHistorySize:]
+	}
+	return list
+}
+
+// broadcast sends an event to every streaming client. Clients that aren't
+// keeping up lose events rather than blocking the build. Must be called with
+// s.lock held.
+func (s *statusServer) broadcast(name string, data *serverEventData) {
+	for c := range s.clients {
+		select {
+		case c <- serverEvent{name: name, data: data}:
+		default:
+		}
+	}
+}
+
+// snapshot returns the current state of the build. Must be called with s.lock
+// held.
+func (s *statusServer) snapshot() *serverSnapshot {
+	ret := &serverSnapshot{
+		Counts:   s.counts,
+		Running:  make([]*serverAction, 0, len(s.running)),
+		Results:  append([]*serverResult{}, s.results...),
+		Messages: append([]*serverMessage{}, s.messages...),
+		Done:     s.done,
+	}
+	for _, a := range s.running {
+		ret.Running = append(ret.Running, a)
+	}
+	sort.Slice(ret.Running, func(i, j int) bool {
+		return ret.Running[i].StartTime.Before(ret.Running[j].StartTime)
+	})
+	return ret
+}
+
+func (s *statusServer) serveStatus(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	snapshot := s.snapshot()
+	s.lock.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(snapshot); err != nil {
+		s.log.Verboseln("Failed to write status:", err)
+	}
+}
+
+func (s *statusServer) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	// Take the snapshot and subscribe under the same lock so that no events
+	// are lost between the two.
+	s.lock.Lock()
+	snapshot := s.snapshot()
+	var events chan serverEvent
+	if !s.done {
+		events = make(chan serverEvent, serverEventBufferSize)
+		s.clients[events] = true
+	}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		delete(s.clients, events)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if err := writeServerEvent(w, serverEvent{name: "snapshot", data: snapshot}); err != nil {
+		return
+	}
+	flusher.Flush()
+
+	if events == nil {
+		return
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeServerEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeServerEvent(w http.ResponseWriter, event serverEvent) error {
+	data, err := json.Marshal(event.data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.name, data)
+	return err
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"android/soong/ui/logger"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readSSEEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			return ev
+		case strings.HasPrefix(line, "event: "):
+			ev.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStatusServer(t *testing.T) {
+	output := NewStatusServer(logger.New(ioutil.Discard), "127.0.0.1:0")
+	if output == nil {
+		t.Fatal("failed to start status server")
+	}
+	server := output.(*statusServer)
+	url := "http://" + server.listener.Addr().String()
+
+	stat := &Status{}
+	stat.AddOutput(output)
+	tool := stat.StartTool()
+	tool.SetTotalActions(2)
+
+	action1 := &Action{Description: "action1", Outputs: []string{"out1"}}
+	tool.StartAction(action1)
+
+	resp, err := http.Get(url + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+
+	ev := readSSEEvent(t, events)
+	if ev.name != "snapshot" {
+		t.Fatalf("expected snapshot event, got %q", ev.name)
+	}
+	var snapshot serverSnapshot
+	if err := json.Unmarshal([]byte(ev.data), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if g, w := snapshot.Counts, (Counts{TotalActions: 2, RunningActions: 1, StartedActions: 1}); g != w {
+		t.Errorf("expected counts %+v, got %+v", w, g)
+	}
+	if len(snapshot.Running) != 1 || snapshot.Running[0].Description != "action1" {
+		t.Errorf("expected action1 to be running, got %+v", snapshot.Running)
+	}
+
+	tool.FinishAction(ActionResult{Action: action1, Output: "output1", Error: errors.New("failed")})
+	tool.Print("hello")
+
+	ev = readSSEEvent(t, events)
+	if ev.name != "finish" {
+		t.Fatalf("expected finish event, got %q", ev.name)
+	}
+	var finish serverEventData
+	if err := json.Unmarshal([]byte(ev.data), &finish); err != nil {
+		t.Fatal(err)
+	}
+	if finish.Result == nil || finish.Result.Description != "action1" ||
+		finish.Result.Output != "output1" || finish.Result.Error != "failed" ||
+		!reflect.DeepEqual(finish.Result.Outputs, []string{"out1"}) {
+		t.Errorf("unexpected finish event %s", ev.data)
+	}
+	if g, w := *finish.Counts, (Counts{TotalActions: 2, FinishedActions: 1, StartedActions: 1}); g != w {
+		t.Errorf("expected counts %+v, got %+v", w, g)
+	}
+
+	ev = readSSEEvent(t, events)
+	if ev.name != "message" || !strings.Contains(ev.data, `"level":"print"`) {
+		t.Errorf("unexpected message event %+v", ev)
+	}
+
+	statusResp, err := http.Get(url + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer statusResp.Body.Close()
+	snapshot = serverSnapshot{}
+	if err := json.NewDecoder(statusResp.Body).Decode(&snapshot); err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Running) != 0 {
+		t.Errorf("expected no running actions, got %+v", snapshot.Running)
+	}
+	if len(snapshot.Results) != 1 || snapshot.Results[0].Description != "action1" {
+		t.Errorf("expected action1 result, got %+v", snapshot.Results)
+	}
+	if len(snapshot.Messages) != 1 || snapshot.Messages[0].Message != "hello" {
+		t.Errorf("expected hello message, got %+v", snapshot.Messages)
+	}
+
+	tool.Finish()
+	stat.Finish()
+
+	ev = readSSEEvent(t, events)
+	if ev.name != "done" {
+		t.Errorf("expected done event, got %q", ev.name)
+	}
+}
+
+func TestStatusServerHistory(t *testing.T) {
+	var results []*serverResult
+	for i := 0; i < serverHistorySize+10; i++ {
+		results = appendServerResult(results, &serverResult{DurationMs: int64(i)})
+	}
+	if len(results) != serverHistorySize {
+		t.Fatalf("expected %d results, got %d", serverHistorySize, len(results))
+	}
+	if results[0].DurationMs != 10 {
+		t.Errorf("expected oldest results to be dropped, first is %d", results[0].DurationMs)
+	}
+}
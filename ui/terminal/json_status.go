@@ -0,0 +1,183 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+// jsonEvent is a single line of output from a jsonStatusOutput.
+type jsonEvent struct {
+	// Type is one of "start", "finish" or "message".
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+
+	// Progress is the NINJA_STATUS formatted progress, and Counts the raw
+	// counts it was formatted from. Only set for start and finish events.
+	Progress string         `json:"progress,omitempty"`
+	Counts   *status.Counts `json:"counts,omitempty"`
+
+	Description string   `json:"description,omitempty"`
+	Command     string   `json:"command,omitempty"`
+	Outputs     []string `json:"outputs,omitempty"`
+
+	// Only set for finish events.
+	DurationMs *int64 `json:"duration_ms,omitempty"`
+	Success    *bool  `json:"success,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Output     string `json:"output,omitempty"`
+
+	// Only set for message events.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type jsonStatusOutput struct {
+	formatter formatter
+
+	// Protects everything below, since Write may be called by the logger
+	// outside of the Status lock.
+	lock    sync.Mutex
+	encoder *json.Encoder
+	running map[*status.Action]time.Time
+	now     func() time.Time
+}
+
+// NewJSONStatusOutput returns a StatusOutput that writes one JSON object per
+// line to w for every action started or finished and every message, for use by
+// tools that wrap the build.
+//
+// statusFormat and quietBuild are interpreted the same way as by
+// NewStatusOutput.
+func NewJSONStatusOutput(w io.Writer, statusFormat string, quietBuild bool) status.StatusOutput {
+	return &jsonStatusOutput{
+		formatter: newFormatter(statusFormat, quietBuild),
+		encoder:   json.NewEncoder(w),
+		running:   make(map[*status.Action]time.Time),
+		now:       time.Now,
+	}
+}
+
+// JSONStatusWriter returns the writer selected by dest for use with
+// NewJSONStatusOutput. dest is either "stdout" or "stderr", referring to the
+// streams in stdio, or the number of an already open file descriptor.
+func JSONStatusWriter(stdio StdioInterface, dest string) (io.Writer, error) {
+	switch dest {
+	case "stdout":
+		return stdio.Stdout(), nil
+	case "stderr":
+		return stdio.Stderr(), nil
+	}
+
+	fd, err := strconv.ParseUint(dest, 10, 31)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON status destination %q, expected stdout, stderr or a file descriptor", dest)
+	}
+	f := os.NewFile(uintptr(fd), "fd"+dest)
+	if _, err := f.Stat(); err != nil {
+		return nil, fmt.Errorf("invalid JSON status file descriptor %s: %w", dest, err)
+	}
+	return f, nil
+}
+
+func (s *jsonStatusOutput) StartAction(action *status.Action, counts status.Counts) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	s.running[action] = now
+
+	event := s.actionEvent("start", now, action, counts)
+	s.write(event)
+}
+
+func (s *jsonStatusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := s.now()
+	event := s.actionEvent("finish", now, result.Action, counts)
+
+	var duration int64
+	if start, ok := s.running[result.Action]; ok {
+		duration = now.Sub(start).Milliseconds()
+		delete(s.running, result.Action)
+	}
+	event.DurationMs = &duration
+
+	success := result.Error == nil
+	event.Success = &success
+	if result.Error != nil {
+		event.Error = result.Error.Error()
+	}
+	event.Output = string(stripAnsiEscapes([]byte(s.formatter.result(result))))
+
+	s.write(event)
+}
+
+func (s *jsonStatusOutput) Message(level status.MsgLevel, message string) {
+	if level < status.StatusLvl {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.write(&jsonEvent{
+		Type:    "message",
+		Time:    s.now(),
+		Level:   level.String(),
+		Message: s.formatter.message(level, message),
+	})
+}
+
+func (s *jsonStatusOutput) Flush() {}
+
+func (s *jsonStatusOutput) Write(p []byte) (int, error) {
+	s.Message(status.PrintLvl, strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+func (s *jsonStatusOutput) actionEvent(typ string, now time.Time, action *status.Action, counts status.Counts) *jsonEvent {
+	description := action.Description
+	if description == "" {
+		description = action.Command
+	}
+
+	return &jsonEvent{
+		Type:        typ,
+		Time:        now,
+		Progress:    strings.TrimSpace(s.formatter.progress(counts)),
+		Counts:      &counts,
+		Description: description,
+		Command:     action.Command,
+		Outputs:     action.Outputs,
+	}
+}
+
+// write encodes a single event. Must be called with s.lock held.
+func (s *jsonStatusOutput) write(event *jsonEvent) {
+	// Errors writing status are ignored, the same as the other outputs.
+	s.encoder.Encode(event)
+}
@@ -0,0 +1,251 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+// jsonTestEvent is the subset of jsonEvent that is stable between runs.
+type jsonTestEvent struct {
+	Type        string
+	Progress    string
+	Description string
+	Outputs     []string
+	Success     *bool
+	Error       string
+	Output      string
+	Level       string
+	Message     string
+}
+
+func decodeJSONEvents(t *testing.T, buf *bytes.Buffer) []jsonTestEvent {
+	t.Helper()
+
+	var ret []jsonTestEvent
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event jsonEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("failed to decode %q: %s", scanner.Text(), err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("missing time in %q", scanner.Text())
+		}
+		ret = append(ret, jsonTestEvent{
+			Type:        event.Type,
+			Progress:    event.Progress,
+			Description: event.Description,
+			Outputs:     event.Outputs,
+			Success:     event.Success,
+			Error:       event.Error,
+			Output:      event.Output,
+			Level:       event.Level,
+			Message:     event.Message,
+		})
+	}
+	return ret
+}
+
+func TestJSONStatusOutput(t *testing.T) {
+	succeeded, failed := true, false
+
+	tests := []struct {
+		name  string
+		calls func(stat status.StatusOutput)
+		want  []jsonTestEvent
+	}{
+		{
+			name:  "two actions",
+			calls: twoActions,
+			want: []jsonTestEvent{
+				{Type: "start", Progress: "[  0% 0/2]", Description: "action1"},
+				{Type: "finish", Progress: "[ 50% 1/2]", Description: "action1", Success: &succeeded},
+				{Type: "start", Progress: "[ 50% 1/2]", Description: "action2"},
+				{Type: "finish", Progress: "[100% 2/2]", Description: "action2", Success: &succeeded},
+			},
+		},
+		{
+			name:  "action with error",
+			calls: actionsWithError,
+			want: []jsonTestEvent{
+				{Type: "start", Progress: "[  0% 0/3]", Description: "action1"},
+				{Type: "finish", Progress: "[ 33% 1/3]", Description: "action1", Success: &succeeded},
+				{Type: "start", Progress: "[ 33% 1/3]", Description: "action2", Outputs: []string{"f1", "f2"}},
+				{Type: "finish", Progress: "[ 66% 2/3]", Description: "action2", Outputs: []string{"f1", "f2"},
+					Success: &failed, Error: "error1", Output: "FAILED: f1 f2\ntouch f1 f2\nerror1\nerror2\n"},
+				{Type: "start", Progress: "[ 66% 2/3]", Description: "action3"},
+				{Type: "finish", Progress: "[100% 3/3]", Description: "action3", Success: &succeeded},
+			},
+		},
+		{
+			name:  "action with empty description",
+			calls: actionWithEmptyDescription,
+			want: []jsonTestEvent{
+				{Type: "start", Progress: "[  0% 0/1]", Description: "command1"},
+				{Type: "finish", Progress: "[100% 1/1]", Description: "command1", Success: &succeeded},
+			},
+		},
+		{
+			name:  "messages",
+			calls: actionsWithMessages,
+			want: []jsonTestEvent{
+				{Type: "start", Progress: "[  0% 0/2]", Description: "action1"},
+				{Type: "finish", Progress: "[ 50% 1/2]", Description: "action1", Success: &succeeded},
+				{Type: "message", Level: "status", Message: "status"},
+				{Type: "message", Level: "print", Message: "print"},
+				{Type: "message", Level: "error", Message: "FAILED: error"},
+				{Type: "start", Progress: "[ 50% 1/2]", Description: "action2"},
+				{Type: "finish", Progress: "[100% 2/2]", Description: "action2", Success: &succeeded},
+			},
+		},
+		{
+			name:  "action with output with ansi codes",
+			calls: actionWithOuptutWithAnsiCodes,
+			want: []jsonTestEvent{
+				{Type: "start", Progress: "[  0% 0/1]", Description: "action1"},
+				{Type: "finish", Progress: "[100% 1/1]", Description: "action1", Success: &succeeded, Output: "color\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout := &bytes.Buffer{}
+			stdio := NewCustomStdio(nil, stdout, ioutil.Discard)
+
+			w, err := JSONStatusWriter(stdio, "stdout")
+			if err != nil {
+				t.Fatal(err)
+			}
+			stat := NewJSONStatusOutput(w, "", false)
+			tt.calls(stat)
+			stat.Flush()
+
+			if g, w := decodeJSONEvents(t, stdout), tt.want; !reflect.DeepEqual(g, w) {
+				t.Errorf("want:\n%+v\ngot:\n%+v", w, g)
+			}
+		})
+	}
+}
+
+func TestJSONStatusOutputDuration(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stat := NewJSONStatusOutput(buf, "", false)
+	jsonStat := stat.(*jsonStatusOutput)
+
+	start := time.Unix(0, 0)
+	jsonStat.now = func() time.Time { return start }
+	runner := newRunner(stat, 1)
+	runner.startAction(action1)
+	jsonStat.now = func() time.Time { return start.Add(1500 * time.Millisecond) }
+	runner.finishAction(result1)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 events, got %q", lines)
+	}
+	var event jsonEvent
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.DurationMs == nil || *event.DurationMs != 1500 {
+		t.Errorf("expected duration 1500ms, got %v", event.DurationMs)
+	}
+	if g, w := *event.Counts, (status.Counts{TotalActions: 1, StartedActions: 1, FinishedActions: 1}); g != w {
+		t.Errorf("expected counts %+v, got %+v", w, g)
+	}
+}
+
+func TestJSONStatusOutputWrite(t *testing.T) {
+	buf := &bytes.Buffer{}
+	stat := NewJSONStatusOutput(buf, "", false)
+	fmt.Fprintln(stat, "log line")
+	stat.Message(status.VerboseLvl, "verbose")
+
+	want := []jsonTestEvent{{Type: "message", Level: "print", Message: "log line"}}
+	if g := decodeJSONEvents(t, buf); !reflect.DeepEqual(g, want) {
+		t.Errorf("want:\n%+v\ngot:\n%+v", want, g)
+	}
+}
+
+func TestJSONStatusWriter(t *testing.T) {
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	stdio := NewCustomStdio(nil, stdout, stderr)
+
+	t.Run("stdout", func(t *testing.T) {
+		w, err := JSONStatusWriter(stdio, "stdout")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w != stdout {
+			t.Errorf("expected stdout, got %v", w)
+		}
+	})
+
+	t.Run("stderr", func(t *testing.T) {
+		w, err := JSONStatusWriter(stdio, "stderr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w != stderr {
+			t.Errorf("expected stderr, got %v", w)
+		}
+	})
+
+	t.Run("fd", func(t *testing.T) {
+		r, pw, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		defer pw.Close()
+
+		w, err := JSONStatusWriter(stdio, fmt.Sprint(pw.Fd()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat := NewJSONStatusOutput(w, "", false)
+		stat.Message(status.PrintLvl, "hello")
+
+		line, err := bufio.NewReader(r).ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(line, `"message":"hello"`) {
+			t.Errorf("unexpected event %q", line)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, dest := range []string{"", "stdin", "-1", "99999"} {
+			if _, err := JSONStatusWriter(stdio, dest); err == nil {
+				t.Errorf("expected error for %q", dest)
+			}
+		}
+	})
+}
@@ -48,6 +48,9 @@ type smartStatusOutput struct {
 	requestedTableHeight  int
 	termWidth, termHeight int
 
+	// Collapses repeated warnings if enabled by CollapseWarnings.
+	warnings *warningCollapser
+
 	runningActions  []actionTableEntry
 	ticker          *time.Ticker
 	done            chan bool
@@ -160,6 +163,10 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 		}
 	}
 
+	if s.warnings != nil && output != "" {
+		output = s.warnings.collapse(progress, result, output)
+	}
+
 	if output != "" {
 		s.statusLine(progress)
 		s.requestLine()
@@ -167,6 +174,13 @@ func (s *smartStatusOutput) FinishAction(result status.ActionResult, counts stat
 	} else {
 		s.statusLine(progress)
 	}
+
+	if s.warnings != nil {
+		if summary := s.warnings.summary(false); summary != "" {
+			s.requestLine()
+			s.print(summary)
+		}
+	}
 }
 
 func (s *smartStatusOutput) Flush() {
@@ -184,6 +198,14 @@ func (s *smartStatusOutput) Flush() {
 
 	s.requestLine()
 
+	if s.warnings != nil {
+		if summary := s.warnings.summary(true); summary != "" {
+			s.print(summary)
+		}
+		s.warnings.close()
+		s.warnings = nil
+	}
+
 	s.runningActions = nil
 
 	if s.tableMode {
@@ -0,0 +1,172 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+// The minimum time between two summaries of the collapsed warnings.
+const warningSummaryInterval = 30 * time.Second
+
+// The maximum number of distinct warnings listed in a summary.
+const warningSummaryMaxLines = 5
+
+// warningLineRe matches the lines that start a warning, like
+// "foo.cpp:1:2: warning: ..." or javac's "Note: Some input files use ...".
+var warningLineRe = regexp.MustCompile(`(?i)(?:^|[\s:])warning:|^Note: `)
+
+type collapsedWarning struct {
+	line string
+
+	// The number of occurrences not printed since the last summary.
+	pending int
+}
+
+// warningCollapser removes warnings that were already printed from the output
+// of successful actions, and writes the full output of every action to a
+// warnings file.
+type warningCollapser struct {
+	filename string
+	file     *os.File
+	writer   *bufio.Writer
+	now      func() time.Time
+
+	warnings    map[string]*collapsedWarning
+	lastSummary time.Time
+}
+
+// CollapseWarnings makes a smart terminal status output collapse identical
+// warnings printed by different actions. Each warning is printed once, later
+// occurrences are counted and reported periodically, and the full output of
+// every action is written to warningsFile. It does nothing for other status
+// outputs, which are usually logged in full.
+func CollapseWarnings(output status.StatusOutput, warningsFile string) error {
+	s, ok := output.(*smartStatusOutput)
+	if !ok {
+		return nil
+	}
+
+	f, err := os.Create(warningsFile)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.warnings = &warningCollapser{
+		filename:    warningsFile,
+		file:        f,
+		writer:      bufio.NewWriter(f),
+		now:         time.Now,
+		warnings:    make(map[string]*collapsedWarning),
+		lastSummary: time.Now(),
+	}
+
+	return nil
+}
+
+// collapse writes the output of an action to the warnings file, and returns
+// it with the warnings that were already printed removed. Failed actions are
+// never collapsed.
+func (w *warningCollapser) collapse(description string, result status.ActionResult, output string) string {
+	fmt.Fprintln(w.writer, description)
+	w.writer.Write(stripAnsiEscapes([]byte(output)))
+
+	if result.Error != nil {
+		return output
+	}
+
+	// Split the output into blocks that start with a warning line, so that
+	// the context printed after a warning is dropped with it.
+	var ret strings.Builder
+	skip := false
+	for _, line := range strings.SplitAfter(output, "\n") {
+		if line == "" {
+			continue
+		}
+		key := strings.TrimSpace(string(stripAnsiEscapes([]byte(line))))
+		if warningLineRe.MatchString(key) {
+			if warning, ok := w.warnings[key]; ok {
+				warning.pending++
+				skip = true
+			} else {
+				w.warnings[key] = &collapsedWarning{line: key}
+				skip = false
+			}
+		}
+		if !skip {
+			ret.WriteString(line)
+		}
+	}
+
+	return ret.String()
+}
+
+// summary returns the number of occurrences of each warning collapsed since
+// the last summary. Unless final is set, it returns an empty string if the
+// last summary was printed recently.
+func (w *warningCollapser) summary(final bool) string {
+	now := w.now()
+	if !final && now.Sub(w.lastSummary) < warningSummaryInterval {
+		return ""
+	}
+
+	var pending []*collapsedWarning
+	for _, warning := range w.warnings {
+		if warning.pending > 0 {
+			pending = append(pending, warning)
+		}
+	}
+	if len(pending) == 0 {
+		return ""
+	}
+	w.lastSummary = now
+
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].pending != pending[j].pending {
+			return pending[i].pending > pending[j].pending
+		}
+		return pending[i].line < pending[j].line
+	})
+
+	var ret strings.Builder
+	for i, warning := range pending {
+		if i < warningSummaryMaxLines {
+			fmt.Fprintf(&ret, "%d more occurrences of: %s\n", warning.pending, warning.line)
+		}
+		warning.pending = 0
+	}
+	if len(pending) > warningSummaryMaxLines {
+		fmt.Fprintf(&ret, "%d more repeated warnings\n", len(pending)-warningSummaryMaxLines)
+	}
+	fmt.Fprintf(&ret, "See %s for the full output\n", w.filename)
+
+	return ret.String()
+}
+
+func (w *warningCollapser) close() {
+	w.writer.Flush()
+	w.file.Close()
+}
@@ -0,0 +1,142 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"android/soong/ui/status"
+)
+
+const deprecationNotes = "Note: Some input files use or override a deprecated API.\n" +
+	"Note: Recompile with -Xlint:deprecation for details.\n"
+
+func TestCollapseWarnings(t *testing.T) {
+	os.Setenv(tableHeightEnVar, "")
+
+	dir, err := ioutil.TempDir("", "warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	warningsFile := filepath.Join(dir, "warnings.txt")
+
+	smart := &fakeSmartTerminal{termWidth: 40}
+	stat := NewStatusOutput(smart, "", false, false)
+	if err := CollapseWarnings(stat, warningsFile); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(0, 0)
+	warnings := stat.(*smartStatusOutput).warnings
+	warnings.now = func() time.Time { return now }
+	warnings.lastSummary = now
+
+	runner := newRunner(stat, 5)
+
+	runner.startAction(action1)
+	runner.finishAction(status.ActionResult{Action: action1, Output: deprecationNotes})
+
+	// Identical warnings are dropped along with the lines that follow them.
+	runner.startAction(action2)
+	runner.finishAction(status.ActionResult{Action: action2, Output: "\x1b[1m" + deprecationNotes + "\x1b[0m" +
+		"Foo.java:1: warning: unchecked\n  context\n"})
+
+	// Failed actions are never collapsed.
+	action3WithError := &status.Action{Description: "action3", Outputs: []string{"f3"}}
+	runner.startAction(action3WithError)
+	runner.finishAction(status.ActionResult{Action: action3WithError, Output: deprecationNotes, Error: fmt.Errorf("error")})
+
+	// A summary is printed once enough time has passed.
+	now = now.Add(warningSummaryInterval)
+	action4 := &status.Action{Description: "action4"}
+	runner.startAction(action4)
+	runner.finishAction(status.ActionResult{Action: action4, Output: "Foo.java:1: warning: unchecked\n  context\n"})
+
+	// The remaining occurrences are reported when the build finishes.
+	action5 := &status.Action{Description: "action5"}
+	runner.startAction(action5)
+	runner.finishAction(status.ActionResult{Action: action5, Output: deprecationNotes})
+
+	stat.Flush()
+
+	w := "\r\x1b[1m[  0% 0/5] action1\x1b[0m\x1b[K\r\x1b[1m[ 20% 1/5] action1\x1b[0m\x1b[K\n" +
+		deprecationNotes +
+		"\r\x1b[1m[ 20% 1/5] action2\x1b[0m\x1b[K\r\x1b[1m[ 40% 2/5] action2\x1b[0m\x1b[K\n" +
+		"\x1b[0mFoo.java:1: warning: unchecked\n  context\n" +
+		"\r\x1b[1m[ 40% 2/5] action3\x1b[0m\x1b[K\r\x1b[1m[ 60% 3/5] action3\x1b[0m\x1b[K\n" +
+		"FAILED: f3\n" + deprecationNotes +
+		"\r\x1b[1m[ 60% 3/5] action4\x1b[0m\x1b[K\r\x1b[1m[ 80% 4/5] action4\x1b[0m\x1b[K\n" +
+		"1 more occurrences of: Foo.java:1: warning: unchecked\n" +
+		"1 more occurrences of: Note: Recompile with -Xlint:deprecation for details.\n" +
+		"1 more occurrences of: Note: Some input files use or override a deprecated API.\n" +
+		"See " + warningsFile + " for the full output\n" +
+		"\r\x1b[1m[ 80% 4/5] action5\x1b[0m\x1b[K\r\x1b[1m[100% 5/5] action5\x1b[0m\x1b[K\n" +
+		"1 more occurrences of: Note: Recompile with -Xlint:deprecation for details.\n" +
+		"1 more occurrences of: Note: Some input files use or override a deprecated API.\n" +
+		"See " + warningsFile + " for the full output\n"
+
+	if g := smart.String(); g != w {
+		t.Errorf("want:\n%q\ngot:\n%q", w, g)
+	}
+
+	// The warnings file has the full output of every action without escape
+	// sequences.
+	buf, err := ioutil.ReadFile(warningsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, w := bytes.Count(buf, []byte("Note: Some input files")), 4; g != w {
+		t.Errorf("want %d occurrences in the warnings file, got %d:\n%s", w, g, buf)
+	}
+	if bytes.Contains(buf, []byte("\x1b")) {
+		t.Errorf("want no escape sequences in the warnings file, got:\n%q", buf)
+	}
+}
+
+func TestCollapseWarningsSimpleOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warnings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	warningsFile := filepath.Join(dir, "warnings.txt")
+
+	simple := &bytes.Buffer{}
+	stat := NewStatusOutput(simple, "", false, false)
+	if err := CollapseWarnings(stat, warningsFile); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := newRunner(stat, 2)
+	runner.startAction(action1)
+	runner.finishAction(status.ActionResult{Action: action1, Output: deprecationNotes})
+	runner.startAction(action2)
+	runner.finishAction(status.ActionResult{Action: action2, Output: deprecationNotes})
+	stat.Flush()
+
+	w := "[ 50% 1/2] action1\n" + deprecationNotes + "[100% 2/2] action2\n" + deprecationNotes
+	if g := simple.String(); g != w {
+		t.Errorf("want:\n%q\ngot:\n%q", w, g)
+	}
+	if _, err := os.Stat(warningsFile); !os.IsNotExist(err) {
+		t.Errorf("want no warnings file for the simple output, got %v", err)
+	}
+}
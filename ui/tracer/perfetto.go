@@ -0,0 +1,274 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/tracer/perfetto_proto"
+)
+
+const (
+	// All packets are written by a single writer, on a single sequence.
+	perfettoSequenceId = 1
+
+	// The field number of Trace.packet.
+	perfettoPacketField = 1
+
+	// Counter tracks get uuids above every possible process and thread
+	// track uuid.
+	perfettoCounterUuidBase = 1 << 62
+)
+
+// The names of the processes used in viewerEvents, which the JSON format
+// leaves unnamed.
+var perfettoProcessNames = map[uint64]string{
+	0: "soong_ui",
+	1: "actions",
+}
+
+// perfettoWriter converts viewerEvents into a Perfetto trace, a stream of
+// TracePacket protos. Each pid and tid becomes a track nested under its
+// process, and each counter gets its own counter track.
+type perfettoWriter struct {
+	w *bufio.Writer
+
+	first    bool
+	tracks   map[uint64]bool
+	counters map[string]uint64
+}
+
+func newPerfettoWriter(w io.Writer) *perfettoWriter {
+	return &perfettoWriter{
+		w:        bufio.NewWriter(w),
+		first:    true,
+		tracks:   make(map[uint64]bool),
+		counters: make(map[string]uint64),
+	}
+}
+
+func perfettoProcessUuid(pid uint64) uint64 {
+	return pid + 1
+}
+
+func perfettoThreadUuid(pid, tid uint64) uint64 {
+	return (pid+1)<<32 | (tid + 1)
+}
+
+// writeEvent writes the packets corresponding to a single viewerEvent.
+func (p *perfettoWriter) writeEvent(event *viewerEvent) error {
+	// viewerEvent timestamps are in microseconds, Perfetto's are in
+	// nanoseconds.
+	ts := event.Time * 1000
+
+	switch event.Phase {
+	case "M":
+		if event.Name != "thread_name" {
+			return nil
+		}
+		name := ""
+		if arg, ok := event.Arg.(*nameArg); ok {
+			name = arg.Name
+		}
+		return p.defineThread(event.Pid, event.Tid, name)
+	case "B":
+		return p.writeSlice(perfetto_proto.TrackEvent_TYPE_SLICE_BEGIN, ts, event)
+	case "E":
+		return p.writeSlice(perfetto_proto.TrackEvent_TYPE_SLICE_END, ts, event)
+	case "X":
+		if err := p.writeSlice(perfetto_proto.TrackEvent_TYPE_SLICE_BEGIN, ts, event); err != nil {
+			return err
+		}
+		end := *event
+		end.Name = ""
+		end.Arg = nil
+		return p.writeSlice(perfetto_proto.TrackEvent_TYPE_SLICE_END, ts+event.Dur*1000, &end)
+	case "C":
+		return p.writeCounter(ts, event)
+	default:
+		return fmt.Errorf("unsupported event phase %q", event.Phase)
+	}
+}
+
+func (p *perfettoWriter) writeSlice(typ perfetto_proto.TrackEvent_Type, ts uint64, event *viewerEvent) error {
+	if !p.tracks[perfettoThreadUuid(event.Pid, event.Tid)] {
+		if err := p.defineThread(event.Pid, event.Tid, fmt.Sprint(event.Tid)); err != nil {
+			return err
+		}
+	}
+
+	trackEvent := &perfetto_proto.TrackEvent{
+		Type:             typ.Enum(),
+		TrackUuid:        proto.Uint64(perfettoThreadUuid(event.Pid, event.Tid)),
+		DebugAnnotations: perfettoDebugAnnotations(event.Arg),
+	}
+	if event.Name != "" {
+		trackEvent.Name = proto.String(event.Name)
+	}
+
+	return p.writePacket(&perfetto_proto.TracePacket{
+		Timestamp:  proto.Uint64(ts),
+		TrackEvent: trackEvent,
+	})
+}
+
+func (p *perfettoWriter) writeCounter(ts uint64, event *viewerEvent) error {
+	arg, ok := event.Arg.(*counterArg)
+	if !ok {
+		return fmt.Errorf("unexpected counter argument %#v", event.Arg)
+	}
+
+	uuid, ok := p.counters[event.Name]
+	if !ok {
+		uuid = perfettoCounterUuidBase + uint64(len(p.counters))
+		p.counters[event.Name] = uuid
+		if err := p.defineProcess(event.Pid); err != nil {
+			return err
+		}
+		err := p.writePacket(&perfetto_proto.TracePacket{
+			TrackDescriptor: &perfetto_proto.TrackDescriptor{
+				Uuid:       proto.Uint64(uuid),
+				ParentUuid: proto.Uint64(perfettoProcessUuid(event.Pid)),
+				Name:       proto.String(event.Name),
+				Counter:    &perfetto_proto.CounterDescriptor{},
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.writePacket(&perfetto_proto.TracePacket{
+		Timestamp: proto.Uint64(ts),
+		TrackEvent: &perfetto_proto.TrackEvent{
+			Type:         perfetto_proto.TrackEvent_TYPE_COUNTER.Enum(),
+			TrackUuid:    proto.Uint64(uuid),
+			CounterValue: proto.Int64(arg.Value),
+		},
+	})
+}
+
+// defineProcess writes the track descriptor for a process the first time it is
+// used.
+func (p *perfettoWriter) defineProcess(pid uint64) error {
+	uuid := perfettoProcessUuid(pid)
+	if p.tracks[uuid] {
+		return nil
+	}
+	p.tracks[uuid] = true
+
+	name, ok := perfettoProcessNames[pid]
+	if !ok {
+		name = fmt.Sprintf("pid %d", pid)
+	}
+
+	return p.writePacket(&perfetto_proto.TracePacket{
+		TrackDescriptor: &perfetto_proto.TrackDescriptor{
+			Uuid: proto.Uint64(uuid),
+			Name: proto.String(name),
+		},
+	})
+}
+
+// defineThread writes the track descriptor for a thread. It may be called more
+// than once for the same thread to rename it.
+func (p *perfettoWriter) defineThread(pid, tid uint64, name string) error {
+	if err := p.defineProcess(pid); err != nil {
+		return err
+	}
+
+	uuid := perfettoThreadUuid(pid, tid)
+	p.tracks[uuid] = true
+
+	return p.writePacket(&perfetto_proto.TracePacket{
+		TrackDescriptor: &perfetto_proto.TrackDescriptor{
+			Uuid:       proto.Uint64(uuid),
+			ParentUuid: proto.Uint64(perfettoProcessUuid(pid)),
+			Name:       proto.String(name),
+		},
+	})
+}
+
+// writePacket writes a single packet, framed as an element of Trace.packet so
+// that the concatenation of all packets is a valid Trace proto.
+func (p *perfettoWriter) writePacket(packet *perfetto_proto.TracePacket) error {
+	packet.TrustedPacketSequenceId = proto.Uint32(perfettoSequenceId)
+	if p.first {
+		packet.SequenceFlags = proto.Uint32(uint32(perfetto_proto.TracePacket_SEQ_INCREMENTAL_STATE_CLEARED))
+		p.first = false
+	}
+
+	data, err := proto.Marshal(packet)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.w.Write(proto.EncodeVarint(perfettoPacketField<<3 | proto.WireBytes)); err != nil {
+		return err
+	}
+	if _, err := p.w.Write(proto.EncodeVarint(uint64(len(data)))); err != nil {
+		return err
+	}
+	_, err = p.w.Write(data)
+	return err
+}
+
+func (p *perfettoWriter) flush() error {
+	return p.w.Flush()
+}
+
+// perfettoDebugAnnotations converts the arguments of a viewerEvent into debug
+// annotations, using the same names as the JSON format.
+func perfettoDebugAnnotations(arg interface{}) []*perfetto_proto.DebugAnnotation {
+	if arg == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(arg)
+	if err != nil {
+		return nil
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var ret []*perfetto_proto.DebugAnnotation
+	for _, name := range names {
+		annotation := &perfetto_proto.DebugAnnotation{Name: proto.String(name)}
+		switch v := args[name].(type) {
+		case float64:
+			annotation.IntValue = proto.Int64(int64(v))
+		case string:
+			annotation.StringValue = proto.String(v)
+		default:
+			annotation.StringValue = proto.String(fmt.Sprint(v))
+		}
+		ret = append(ret, annotation)
+	}
+	return ret
+}
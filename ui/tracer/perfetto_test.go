@@ -0,0 +1,131 @@
+// Copyright 2021 Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tracer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	"android/soong/ui/logger"
+	"android/soong/ui/status"
+	"android/soong/ui/tracer/perfetto_proto"
+)
+
+func TestPerfettoTrace(t *testing.T) {
+	dir, err := ioutil.TempDir("", "perfetto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	microfactoryLog := filepath.Join(dir, ".soong_ui.trace")
+	err = ioutil.WriteFile(microfactoryLog, []byte("1000 B compile\n3000 E compile\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := New(logger.New(ioutil.Discard))
+
+	// Events from before the outputs are set are replayed.
+	trace.Begin("setup", MainThread)
+	trace.End(MainThread)
+
+	perfettoFile := filepath.Join(dir, "build.perfetto-trace")
+	trace.SetPerfettoOutput(perfettoFile)
+	trace.SetOutput(filepath.Join(dir, "build.trace"))
+
+	trace.Complete("complete", trace.NewThread("worker"), 5000000, 6000000)
+	trace.ImportMicrofactoryLog(microfactoryLog)
+
+	stat := trace.StatusTracer()
+	action := &status.Action{Description: "action", Outputs: []string{"out"}}
+	stat.StartAction(action, status.Counts{TotalActions: 1, RunningActions: 1, StartedActions: 1})
+	stat.FinishAction(status.ActionResult{
+		Action: action,
+		Stats:  status.ActionResultStats{MaxRssKB: 42},
+	}, status.Counts{TotalActions: 1, StartedActions: 1, FinishedActions: 1})
+
+	trace.Close()
+
+	data, err := ioutil.ReadFile(perfettoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	perfettoTrace := &perfetto_proto.Trace{}
+	if err := proto.Unmarshal(data, perfettoTrace); err != nil {
+		t.Fatal(err)
+	}
+
+	tracks := map[uint64]string{}
+	slices := map[string]uint64{}
+	counters := map[uint64][]int64{}
+	begins := 0
+	ends := 0
+	for i, packet := range perfettoTrace.Packet {
+		if packet.GetTrustedPacketSequenceId() != perfettoSequenceId {
+			t.Errorf("packet %d: wrong sequence id %d", i, packet.GetTrustedPacketSequenceId())
+		}
+		if desc := packet.TrackDescriptor; desc != nil {
+			tracks[desc.GetUuid()] = desc.GetName()
+		}
+		if event := packet.TrackEvent; event != nil {
+			if _, ok := tracks[event.GetTrackUuid()]; !ok {
+				t.Errorf("packet %d: event on undefined track %d", i, event.GetTrackUuid())
+			}
+			switch event.GetType() {
+			case perfetto_proto.TrackEvent_TYPE_SLICE_BEGIN:
+				begins++
+				slices[event.GetName()] = event.GetTrackUuid()
+			case perfetto_proto.TrackEvent_TYPE_SLICE_END:
+				ends++
+			case perfetto_proto.TrackEvent_TYPE_COUNTER:
+				counters[event.GetTrackUuid()] = append(counters[event.GetTrackUuid()], event.GetCounterValue())
+			}
+		}
+	}
+
+	if perfettoTrace.Packet[0].GetSequenceFlags() == 0 {
+		t.Errorf("expected the first packet to clear the incremental state")
+	}
+
+	if begins != ends {
+		t.Errorf("expected matching slice begins and ends, got %d and %d", begins, ends)
+	}
+	for _, name := range []string{"setup", "complete", "compile", "out"} {
+		if _, ok := slices[name]; !ok {
+			t.Errorf("missing slice %q, got %v", name, slices)
+		}
+	}
+	if g, w := tracks[slices["setup"]], "main"; g != w {
+		t.Errorf("expected setup on track %q, got %q", w, g)
+	}
+	if g, w := tracks[slices["complete"]], "worker"; g != w {
+		t.Errorf("expected complete on track %q, got %q", w, g)
+	}
+
+	var running []int64
+	for uuid, values := range counters {
+		if tracks[uuid] == "running actions" {
+			running = values
+		}
+	}
+	if len(running) != 2 || running[0] != 1 || running[1] != 0 {
+		t.Errorf("expected running actions counter [1 0], got %v", running)
+	}
+}
@@ -15,10 +15,16 @@
 package tracer
 
 import (
-	"android/soong/ui/status"
 	"time"
+
+	"android/soong/finder/fs"
+	"android/soong/ui/metrics/proc"
+	"android/soong/ui/status"
 )
 
+// The minimum time between samples of the system memory usage.
+const memorySampleInterval = time.Second
+
 func (t *tracerImpl) StatusTracer() status.StatusOutput {
 	return &statusOutput{
 		tracer: t,
@@ -37,6 +43,9 @@ type statusOutput struct {
 
 	cpus    []bool
 	running map[*status.Action]actionStatus
+
+	lastMemorySample time.Time
+	noMemorySamples  bool
 }
 
 func (s *statusOutput) StartAction(action *status.Action, counts status.Counts) {
@@ -54,10 +63,13 @@ func (s *statusOutput) StartAction(action *status.Action, counts status.Counts)
 		s.cpus = append(s.cpus, true)
 	}
 
+	now := time.Now()
 	s.running[action] = actionStatus{
 		cpu:   cpu,
-		start: time.Now(),
+		start: now,
 	}
+
+	s.updateCounters(counts, now)
 }
 
 func (s *statusOutput) FinishAction(result status.ActionResult, counts status.Counts) {
@@ -92,6 +104,27 @@ func (s *statusOutput) FinishAction(result status.ActionResult, counts status.Co
 			InvoluntaryContextSwitches: result.Stats.InvoluntaryContextSwitches,
 		},
 	})
+
+	s.updateCounters(counts, time.Now())
+}
+
+// updateCounters writes the number of running actions, and the system memory
+// usage if it hasn't been sampled recently.
+func (s *statusOutput) updateCounters(counts status.Counts, now time.Time) {
+	s.tracer.Counter("running actions", int64(counts.RunningActions))
+
+	if s.noMemorySamples || now.Sub(s.lastMemorySample) < memorySampleInterval {
+		return
+	}
+	s.lastMemorySample = now
+
+	used, err := systemMemoryUsed()
+	if err != nil {
+		// Not available on this platform, don't try again.
+		s.noMemorySamples = true
+		return
+	}
+	s.tracer.Counter("memory used (bytes)", used)
 }
 
 type statsArg struct {
@@ -113,3 +146,13 @@ func (s *statusOutput) Write(p []byte) (int, error) {
 	// Discard writes
 	return len(p), nil
 }
+
+// systemMemoryUsed returns the memory in use by the system in bytes, as
+// MemTotal - MemAvailable from /proc/meminfo.
+func systemMemoryUsed() (int64, error) {
+	m, err := proc.NewMemInfo(fs.OsFs)
+	if err != nil {
+		return 0, err
+	}
+	return int64(m.MemTotal) - int64(m.MemAvailable), nil
+}
@@ -17,6 +17,10 @@
 //
 // It implements the JSON Array Format defined here:
 // https://docs.google.com/document/d/1CvAClvFfyA5R-PhYUmn5OOQtYMH4h6I0nSsKchNAySU/edit
+//
+// The same events can also be written in the Perfetto protobuf trace format,
+// which is much smaller and faster to load for large builds:
+// https://perfetto.dev/docs/reference/trace-packet-proto
 package tracer
 
 import (
@@ -45,6 +49,7 @@ type Tracer interface {
 	Begin(name string, thread Thread)
 	End(thread Thread)
 	Complete(name string, thread Thread, begin, end uint64)
+	Counter(name string, value int64)
 
 	ImportMicrofactoryLog(filename string)
 
@@ -63,6 +68,13 @@ type tracerImpl struct {
 
 	firstEvent bool
 	nextTid    uint64
+
+	// The events written while buffering, so that they can be replayed
+	// into the Perfetto output.
+	pending []*viewerEvent
+
+	perfettoFile *os.File
+	perfetto     *perfettoWriter
 }
 
 var _ Tracer = &tracerImpl{}
@@ -83,6 +95,10 @@ type nameArg struct {
 	Name string `json:"name"`
 }
 
+type counterArg struct {
+	Value int64 `json:"value"`
+}
+
 type nopCloser struct{ io.Writer }
 
 func (nopCloser) Close() error { return nil }
@@ -145,6 +161,7 @@ func (t *tracerImpl) SetOutput(filename string) {
 	// underlying file.
 	t.file = f
 	t.w = gzip.NewWriter(f)
+	t.pending = nil
 
 	// Write out everything that happened since the start
 	if _, err := io.Copy(t.w, &t.buf); err != nil {
@@ -153,13 +170,49 @@ func (t *tracerImpl) SetOutput(filename string) {
 	t.buf = bytes.Buffer{}
 }
 
-// Close closes the output file. Any future events will be buffered until the
+// SetPerfettoOutput creates a Perfetto trace file (rotating old files), which
+// receives the same events as the JSON trace. It must be called before
+// SetOutput to include the events from before that call.
+func (t *tracerImpl) SetPerfettoOutput(filename string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.closePerfetto()
+
+	f, err := logger.CreateFileWithRotation(filename, 5)
+	if err != nil {
+		t.log.Println("Failed to create perfetto trace file:", err)
+		return
+	}
+	t.perfettoFile = f
+	t.perfetto = newPerfettoWriter(f)
+
+	for _, event := range t.pending {
+		t.writePerfettoEventLocked(event)
+	}
+}
+
+func (t *tracerImpl) closePerfetto() {
+	if t.perfetto != nil {
+		if err := t.perfetto.flush(); err != nil {
+			t.log.Println("Error flushing perfetto trace:", err)
+		}
+		if err := t.perfettoFile.Close(); err != nil {
+			t.log.Println("Error closing perfetto trace file:", err)
+		}
+		t.perfetto = nil
+		t.perfettoFile = nil
+	}
+}
+
+// Close closes the output files. Any future events will be buffered until the
 // next call to SetOutput.
 func (t *tracerImpl) Close() {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
 	t.close()
+	t.closePerfetto()
 }
 
 func (t *tracerImpl) writeEvent(event *viewerEvent) {
@@ -186,6 +239,21 @@ func (t *tracerImpl) writeEventLocked(event *viewerEvent) {
 	if _, err = t.w.Write(bytes); err != nil {
 		t.log.Println("Trace write error:", err)
 	}
+
+	if t.file == nil {
+		t.pending = append(t.pending, event)
+	}
+	t.writePerfettoEventLocked(event)
+}
+
+func (t *tracerImpl) writePerfettoEventLocked(event *viewerEvent) {
+	if t.perfetto == nil {
+		return
+	}
+
+	if err := t.perfetto.writeEvent(event); err != nil {
+		t.log.Println("Perfetto trace write error:", err)
+	}
 }
 
 func (t *tracerImpl) defineThread(thread Thread, name string) {
@@ -247,3 +315,18 @@ func (t *tracerImpl) Complete(name string, thread Thread, begin, end uint64) {
 		Tid:   uint64(thread),
 	})
 }
+
+// Counter writes a Counter Event, setting the value of the named counter from
+// now until the next Counter Event with the same name.
+func (t *tracerImpl) Counter(name string, value int64) {
+	t.writeEvent(&viewerEvent{
+		Name:  name,
+		Phase: "C",
+		Time:  uint64(time.Now().UnixNano()) / 1000,
+		Pid:   0,
+		Tid:   0,
+		Arg: &counterArg{
+			Value: value,
+		},
+	})
+}